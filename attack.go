@@ -1,8 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/json"
 	"errors"
 	"flag"
 	"fmt"
@@ -17,15 +19,20 @@ import (
 
 	"github.com/ernestrc/vegeta/internal/resolver"
 	vegeta "github.com/ernestrc/vegeta/lib"
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
 func attackCmd() command {
 	fs := flag.NewFlagSet("vegeta attack", flag.ExitOnError)
 	opts := &attackOpts{
-		headers: headers{http.Header{}},
-		laddr:   localAddr{&vegeta.DefaultLocalAddr},
-		rate:    vegeta.Rate{Freq: 50, Per: time.Second},
-		maxBody: vegeta.DefaultMaxBody,
+		headers:   headers{http.Header{}},
+		laddr:     localAddr{[]net.IPAddr{vegeta.DefaultLocalAddr}},
+		rate:      vegeta.Rate{Freq: 50, Per: time.Second},
+		maxBody:   vegeta.DefaultMaxBody,
+		vars:      kvFlag{},
+		capture:   kvFlag{},
+		logFields: kvFlag{},
+		jwtClaims: kvFlag{},
 	}
 	fs.StringVar(&opts.name, "name", "", "Attack name")
 	fs.StringVar(&opts.targetsf, "targets", "stdin", "Targets file")
@@ -38,19 +45,69 @@ func attackCmd() command {
 	fs.Var(&opts.rootCerts, "root-certs", "TLS root certificate files (comma separated list)")
 	fs.BoolVar(&opts.http2, "http2", true, "Send HTTP/2 requests when supported by the server")
 	fs.BoolVar(&opts.h2c, "h2c", false, "Send HTTP/2 requests without TLS encryption")
+	fs.BoolVar(&opts.http3, "http3", false, "Send HTTP/3 requests over QUIC")
 	fs.BoolVar(&opts.insecure, "insecure", false, "Ignore invalid server TLS certificates")
 	fs.BoolVar(&opts.lazy, "lazy", false, "Read targets lazily")
+	fs.Var(&opts.harHosts, "har-hosts", "Hosts of the HAR requests to include (comma separated list, *.domain matches subdomains)")
+	fs.Var(&opts.harTypes, "har-types", "Response content types of the HAR requests to include (comma separated list, type/* matches subtypes)")
+	fs.StringVar(&opts.logFormat, "log-format", vegeta.CombinedLogFormat,
+		fmt.Sprintf("Access log format of the log targets format [%s]", strings.Join(vegeta.LogFormats, ", ")))
+	fs.Var(opts.logFields, "log-fields", "JSON access log field mapping as key=field with key one of time, method, url, host or header.<name>")
+	fs.StringVar(&opts.logTimeLayout, "log-time-layout", time.RFC3339Nano, "Layout of the time field of JSON access logs")
+	fs.StringVar(&opts.logBaseURL, "log-base-url", "", "Base URL of the request URIs of access logs")
+	fs.IntVar(&opts.logMaxBad, "log-max-bad", 0, "Number of bad access log lines skipped before the attack fails [0 = no limit, -1 = none]")
+	fs.BoolVar(&opts.timing, "timing", false, "Replay HAR or access log requests with their recorded timing instead of -rate")
+	fs.Float64Var(&opts.speed, "speed", 1, "Speed factor of -timing replays, e.g. 2 replays twice as fast")
+	fs.StringVar(&opts.mix, "mix", mixRoundRobin, fmt.Sprintf("Targets mix [%s]", strings.Join(mixes, ", ")))
+	fs.Int64Var(&opts.seed, "seed", 0, "Random seed of the weighted targets mix [0 = current time]")
+	fs.BoolVar(&opts.template, "template", false, "Render {{var}} placeholders in targets")
+	fs.Var(opts.vars, "var", "Template variable as name=value (implies -template)")
+	fs.StringVar(&opts.feederf, "feeder", "", "Template variables CSV or JSON lines file (implies -template)")
+	fs.Var(opts.capture, "capture", "Template variable captured from responses as name=jsonpath:<path> or name=regex:<pattern> (implies -template)")
 	fs.DurationVar(&opts.duration, "duration", 0, "Duration of the test [0 = forever]")
 	fs.DurationVar(&opts.timeout, "timeout", vegeta.DefaultTimeout, "Requests timeout")
 	fs.Uint64Var(&opts.workers, "workers", vegeta.DefaultWorkers, "Initial number of workers")
 	fs.Uint64Var(&opts.maxWorkers, "max-workers", vegeta.DefaultMaxWorkers, "Maximum number of workers")
 	fs.IntVar(&opts.connections, "connections", vegeta.DefaultConnections, "Max open idle connections per target host")
+	fs.Uint64Var(&opts.conns.Requests, "conn-requests", 0, "Number of requests each worker sends over its connections before opening new ones [0 = no limit]")
+	fs.DurationVar(&opts.conns.Lifetime, "conn-lifetime", 0, "How long each worker reuses its connections before opening new ones [0 = no limit]")
+	fs.IntVar(&opts.conns.MaxOpen, "max-conns", 0, "Maximum number of open connections [0 = no limit]")
+	fs.Var(&rateFlag{&opts.connRate}, "conn-rate", "Number of connections opened per time unit [0 = infinity]")
 	fs.IntVar(&opts.redirects, "redirects", vegeta.DefaultRedirects, "Number of redirects to follow. -1 will not follow but marks as success")
+	fs.IntVar(&opts.retries.Max, "retries", 0, "Maximum number of retries of failed requests")
+	fs.Var(&opts.retryOn, "retry-on", "Conditions of retried requests as status codes, classes like 5xx or timeout, dns, refused, reset, tls and error (comma separated list) (default 502,503,reset)")
+	fs.DurationVar(&opts.retries.Backoff, "retry-backoff", vegeta.DefaultRetryBackoff, "Base delay of the exponential backoff between retries")
+	fs.DurationVar(&opts.retries.MaxBackoff, "retry-max-backoff", vegeta.DefaultRetryMaxBackoff, "Maximum delay between retries")
 	fs.Var(&maxBodyFlag{&opts.maxBody}, "max-body", "Maximum number of bytes to capture from response bodies. [-1 = no limit]")
 	fs.Var(&rateFlag{&opts.rate}, "rate", "Number of requests per time unit [0 = infinity]")
+	fs.Var(&usersFlag{&opts.users}, "users", "Virtual users of a closed-model attack instead of -rate, as users[@ramp duration] stages (comma separated list)")
+	fs.Var(&opts.think, "think", "Think time of virtual users as <duration>, uniform:<min>-<max> or exponential:<mean>")
 	fs.Var(&opts.headers, "header", "Request header")
-	fs.Var(&opts.laddr, "laddr", "Local IP address")
+	fs.StringVar(&opts.basicAuth, "basic-auth", "", "Basic authentication credentials as user:password")
+	fs.StringVar(&opts.oauth2.TokenURL, "oauth2-token-url", "", "OAuth2 token endpoint of the client credentials grant")
+	fs.StringVar(&opts.oauth2.ClientID, "oauth2-client-id", "", "OAuth2 client id")
+	fs.StringVar(&opts.oauth2.ClientSecret, "oauth2-client-secret", "", "OAuth2 client secret")
+	fs.Var((*csl)(&opts.oauth2.Scopes), "oauth2-scopes", "OAuth2 scopes (comma separated list)")
+	fs.StringVar(&opts.jwtKeyf, "jwt-key", "", "JWT signing key file, an HMAC secret or a PEM encoded RSA private key")
+	fs.StringVar(&opts.jwtAlg, "jwt-alg", vegeta.HS256, fmt.Sprintf("JWT signing algorithm [%s, %s]", vegeta.HS256, vegeta.RS256))
+	fs.Var(opts.jwtClaims, "jwt-claims", "JWT claim as name=value, with JSON values decoded")
+	fs.DurationVar(&opts.jwtTTL, "jwt-ttl", time.Hour, "JWT lifetime [0 = no expiry]")
+	fs.StringVar(&opts.sigV4, "sigv4", "", "AWS SigV4 signing region and service as region/service, with credentials from the AWS_* environment variables")
+	fs.Var(&opts.assertions, "assert", "Response assertion as status:<codes>, body-contains:<text>, body-regex:<pattern>, jsonpath:<path>=<value>, header:<name> or latency:<duration>")
+	fs.Var(&opts.laddr, "laddr", "Local IP addresses, host names or CIDR ranges (comma separated list)")
+	fs.StringVar(&opts.laddrMode, "laddr-mode", laddrRoundRobin, fmt.Sprintf("Assignment of multiple local addresses to connections [%s]", strings.Join(laddrModes, ", ")))
+	fs.Var(&portRangeFlag{&opts.lports}, "lport-range", "Local port range of connections as first-last [empty = ephemeral ports]")
+	fs.StringVar(&opts.cookies, "cookies", cookiesNone, fmt.Sprintf("Cookie jars keeping response cookies [%s]", strings.Join(cookieModes, ", ")))
+	fs.StringVar(&opts.cookieFile, "cookie-file", "", "Netscape cookie file seeding the cookie jars (implies -cookies=worker if none)")
 	fs.BoolVar(&opts.keepalive, "keepalive", true, "Use persistent connections")
+	fs.BoolVar(&opts.trace, "trace", false, "Record the DNS, connect, TLS, first byte and transfer timings of requests")
+	fs.BoolVar(&opts.metadata, "metadata", false, "Record the protocol of responses and the remote and local addresses of connections")
+	fs.Var(&opts.respHeaders, "response-headers", "Response headers to record (comma separated list, * records all)")
+	fs.StringVar(&opts.grpcDescs, "grpc-descriptors", "", "Protobuf descriptor set file of the methods of gRPC targets [empty = server reflection]")
+	fs.DurationVar(&opts.ws.Hold, "ws-hold", 0, "How long WebSocket connections are held open, sending their messages over and over [0 = send them once]")
+	fs.DurationVar(&opts.ws.Interval, "ws-interval", 0, "Delay between sending WebSocket messages [0 = once the previous reply arrives]")
+	fs.BoolVar(&opts.stream, "stream", false, "Read response bodies as streams of events, recording their first byte, first event and event gap timings")
+	fs.DurationVar(&opts.streamHold, "stream-hold", 0, "How long streamed responses are held open before closing them [0 = until their end]")
 	fs.StringVar(&opts.unixSocket, "unix-socket", "", "Connect over a unix socket. This overrides the host address in target URLs")
 	systemSpecificFlags(fs, opts)
 
@@ -60,6 +117,32 @@ func attackCmd() command {
 	}}
 }
 
+// Supported targets mixes.
+const (
+	mixRoundRobin = "round-robin"
+	mixWeighted   = "weighted"
+	mixSequential = "sequential"
+)
+
+var mixes = []string{mixRoundRobin, mixWeighted, mixSequential}
+
+// Supported cookie jar modes.
+const (
+	cookiesNone   = "none"
+	cookiesShared = "shared"
+	cookiesWorker = "worker"
+)
+
+var cookieModes = []string{cookiesNone, cookiesShared, cookiesWorker}
+
+// Supported local address modes.
+const (
+	laddrRoundRobin = "round-robin"
+	laddrWorker     = "worker"
+)
+
+var laddrModes = []string{laddrRoundRobin, laddrWorker}
+
 var (
 	errZeroRate = errors.New("rate frequency and time unit must be bigger than zero")
 	errBadCert  = errors.New("bad certificate")
@@ -67,40 +150,133 @@ var (
 
 // attackOpts aggregates the attack function command options
 type attackOpts struct {
-	name        string
-	targetsf    string
-	format      string
-	outputf     string
-	bodyf       string
-	certf       string
-	keyf        string
-	rootCerts   csl
-	http2       bool
-	h2c         bool
-	insecure    bool
-	lazy        bool
-	duration    time.Duration
-	timeout     time.Duration
-	rate        vegeta.Rate
-	workers     uint64
-	maxWorkers  uint64
-	connections int
-	redirects   int
-	maxBody     int64
-	headers     headers
-	laddr       localAddr
-	keepalive   bool
-	resolvers   csl
-	unixSocket  string
+	name          string
+	targetsf      string
+	format        string
+	outputf       string
+	bodyf         string
+	certf         string
+	keyf          string
+	rootCerts     csl
+	http2         bool
+	h2c           bool
+	http3         bool
+	insecure      bool
+	lazy          bool
+	harHosts      csl
+	harTypes      csl
+	logFormat     string
+	logFields     kvFlag
+	logTimeLayout string
+	logBaseURL    string
+	logMaxBad     int
+	timing        bool
+	speed         float64
+	mix           string
+	seed          int64
+	template      bool
+	vars          kvFlag
+	feederf       string
+	capture       kvFlag
+	duration      time.Duration
+	timeout       time.Duration
+	rate          vegeta.Rate
+	users         vegeta.UserSchedule
+	think         thinkFlag
+	workers       uint64
+	maxWorkers    uint64
+	connections   int
+	conns         vegeta.ConnectionPolicy
+	connRate      vegeta.Rate
+	redirects     int
+	retries       vegeta.RetryPolicy
+	retryOn       csl
+	maxBody       int64
+	headers       headers
+	assertions    listFlag
+	cookies       string
+	cookieFile    string
+	basicAuth     string
+	oauth2        vegeta.OAuth2Config
+	jwtKeyf       string
+	jwtAlg        string
+	jwtClaims     kvFlag
+	jwtTTL        time.Duration
+	sigV4         string
+	trace         bool
+	metadata      bool
+	respHeaders   csl
+	grpcDescs     string
+	ws            vegeta.WebSocketConfig
+	stream        bool
+	streamHold    time.Duration
+	laddr         localAddr
+	laddrMode     string
+	lports        vegeta.PortRange
+	keepalive     bool
+	resolvers     csl
+	unixSocket    string
 }
 
 // attack validates the attack arguments, sets up the
 // required resources, launches the attack and writes the results
 func attack(opts *attackOpts) (err error) {
-	if opts.maxWorkers == vegeta.DefaultMaxWorkers && opts.rate.Freq == 0 {
+	if len(opts.users) > 0 && opts.timing {
+		return fmt.Errorf("-users and -timing can't be used together")
+	}
+
+	if opts.http3 && (opts.h2c || opts.unixSocket != "") {
+		return fmt.Errorf("-http3 can't be used with -h2c or -unix-socket")
+	}
+
+	if len(opts.laddr.addrs) == 0 {
+		return fmt.Errorf("-laddr requires at least one address")
+	}
+
+	laddrs, err := localAddrs(opts)
+	if err != nil {
+		return err
+	}
+
+	if (len(opts.laddr.addrs) > 1 || opts.lports != (vegeta.PortRange{})) && (opts.h2c || opts.http3 || opts.unixSocket != "") {
+		return fmt.Errorf("multiple -laddr addresses and -lport-range can't be used with -h2c, -http3 or -unix-socket")
+	}
+
+	if (opts.h2c || opts.http3) && (opts.conns != (vegeta.ConnectionPolicy{}) || opts.connRate.Freq > 0) {
+		return fmt.Errorf("-conn-requests, -conn-lifetime, -max-conns and -conn-rate can't be used with -h2c or -http3")
+	}
+
+	if opts.maxWorkers == vegeta.DefaultMaxWorkers && opts.rate.Freq == 0 && len(opts.users) == 0 {
 		return fmt.Errorf("-rate=0 requires setting -max-workers")
 	}
 
+	assertions := make([]vegeta.Assertion, 0, len(opts.assertions))
+	for _, expr := range opts.assertions {
+		a, err := vegeta.ParseAssertion(expr)
+		if err != nil {
+			return err
+		}
+		assertions = append(assertions, a)
+	}
+
+	for _, cond := range opts.retryOn {
+		on, err := vegeta.ParseRetryCondition(cond)
+		if err != nil {
+			return err
+		}
+		opts.retries.On = append(opts.retries.On, on)
+	}
+
+	auth, err := authenticator(opts)
+	if err != nil {
+		return err
+	}
+
+	cookies, err := cookieJars(opts)
+	if err != nil {
+		return err
+	}
+
 	if len(opts.resolvers) > 0 {
 		res, err := resolver.NewResolver(opts.resolvers)
 		if err != nil {
@@ -130,27 +306,89 @@ func attack(opts *attackOpts) (err error) {
 	}
 
 	var (
-		tr  vegeta.TargeterProvider
-		src = files[opts.targetsf]
-		hdr = opts.headers.Header
+		tr     vegeta.TargeterProvider
+		pacer  vegeta.Pacer = opts.rate
+		src                 = files[opts.targetsf]
+		hdr                 = opts.headers.Header
+		logged *vegeta.Replay
 	)
 
+	if opts.timing && opts.format != vegeta.HARTargetFormat && opts.format != vegeta.AccessLogTargetFormat {
+		return fmt.Errorf("-timing requires -format=%s or -format=%s", vegeta.HARTargetFormat, vegeta.AccessLogTargetFormat)
+	}
+
 	switch opts.format {
 	case vegeta.JSONTargetFormat:
 		tr = vegeta.NewJSONTargeter(src, body, hdr)
 	case vegeta.HTTPTargetFormat:
 		tr = vegeta.NewHTTPTargeter(src, body, hdr)
+	case vegeta.CurlTargetFormat:
+		tr = vegeta.NewCurlTargeter(src, body, hdr)
+	case vegeta.ScenarioTargetFormat:
+		if tr, err = vegeta.NewScenarioTargeter(src, body, hdr); err != nil {
+			return err
+		}
+	case vegeta.HARTargetFormat:
+		replay, err := vegeta.NewHARTargeter(src, body, hdr, vegeta.HAROptions{
+			Hosts:        opts.harHosts,
+			ContentTypes: opts.harTypes,
+		})
+		if err != nil {
+			return err
+		}
+		if opts.timing {
+			pacer = replay.Pacer(opts.speed)
+		}
+		tr = replay
+	case vegeta.AccessLogTargetFormat:
+		if logged, err = accessLogTargeter(src, body, hdr, opts); err != nil {
+			return err
+		}
+		if opts.timing {
+			pacer = logged.Pacer(opts.speed)
+		}
+		tr = logged
 	default:
 		return fmt.Errorf("format %q isn't one of [%s]",
 			opts.format, strings.Join(vegeta.TargetFormats, ", "))
 	}
 
-	if !opts.lazy {
+	switch {
+	case opts.mix != mixRoundRobin && opts.mix != mixWeighted && opts.mix != mixSequential:
+		return fmt.Errorf("mix %q isn't one of [%s]", opts.mix, strings.Join(mixes, ", "))
+	case (opts.lazy || opts.timing || opts.format == vegeta.AccessLogTargetFormat) && opts.mix == mixWeighted:
+		return fmt.Errorf("-mix=%s requires reading targets eagerly", mixWeighted)
+	case opts.lazy, opts.format == vegeta.ScenarioTargetFormat, opts.format == vegeta.AccessLogTargetFormat, opts.timing:
+		// Scenario targeters are stateful, so they can't be read eagerly.
+		// Access logs are streamed since they can be huge.
+		// Lazily read and timed targets are always sequential.
+	default:
 		targets, err := vegeta.ReadAllTargets(tr)
 		if err != nil {
 			return err
 		}
-		tr = vegeta.NewStaticTargeter(targets...)
+
+		switch opts.mix {
+		case mixWeighted:
+			seed := opts.seed
+			if seed == 0 {
+				seed = time.Now().UnixNano()
+			}
+			tr = vegeta.NewWeightedTargeter(seed, targets...)
+		case mixSequential:
+			tr = vegeta.NewSequenceTargeter(targets...)
+		default:
+			tr = vegeta.NewStaticTargeter(targets...)
+		}
+	}
+
+	if opts.template || len(opts.vars) > 0 || opts.feederf != "" || len(opts.capture) > 0 {
+		// Scenario targeters render their own templates.
+		if opts.format != vegeta.ScenarioTargetFormat {
+			if tr, err = templateTargeter(tr, opts); err != nil {
+				return err
+			}
+		}
 	}
 
 	out, err := file(opts.outputf, true)
@@ -164,22 +402,63 @@ func attack(opts *attackOpts) (err error) {
 		return err
 	}
 
+	var descs *protoregistry.Files
+	if opts.grpcDescs != "" {
+		f, err := os.Open(opts.grpcDescs)
+		if err != nil {
+			return fmt.Errorf("error opening %s: %s", opts.grpcDescs, err)
+		}
+		defer f.Close()
+
+		if descs, err = vegeta.ReadProtoDescriptorSet(f); err != nil {
+			return fmt.Errorf("error reading %s: %s", opts.grpcDescs, err)
+		}
+	}
+
+	if opts.connRate.Freq > 0 {
+		opts.conns.Pacer = opts.connRate
+	}
+
 	atk := vegeta.NewAttacker(
 		vegeta.Redirects(opts.redirects),
 		vegeta.Timeout(opts.timeout),
-		vegeta.LocalAddr(*opts.laddr.IPAddr),
+		vegeta.LocalAddr(opts.laddr.addrs[0]),
 		vegeta.TLSConfig(tlsc),
 		vegeta.Workers(opts.workers),
 		vegeta.MaxWorkers(opts.maxWorkers),
 		vegeta.KeepAlive(opts.keepalive),
+		laddrs,
 		vegeta.Connections(opts.connections),
 		vegeta.HTTP2(opts.http2),
 		vegeta.H2C(opts.h2c),
+		vegeta.HTTP3(opts.http3),
 		vegeta.MaxBody(opts.maxBody),
 		vegeta.UnixSocket(opts.unixSocket),
+		vegeta.NewConnections(opts.conns),
+		vegeta.Assertions(assertions...),
+		vegeta.Auth(auth),
+		vegeta.Retries(opts.retries),
+		vegeta.Trace(opts.trace),
+		vegeta.Metadata(opts.metadata),
+		vegeta.ResponseHeaders(opts.respHeaders...),
+		vegeta.GRPCDescriptors(descs),
+		vegeta.WebSocket(opts.ws),
+		vegeta.Stream(opts.stream),
+		vegeta.StreamHold(opts.streamHold),
+		cookies,
 	)
 
-	res := atk.Attack(tr, opts.rate, opts.duration, opts.name)
+	if err = atk.Err(); err != nil {
+		return err
+	}
+
+	var res <-chan *vegeta.Result
+	if len(opts.users) > 0 {
+		res = atk.AttackUsers(tr, opts.users, opts.think.think, opts.duration, opts.name)
+	} else {
+		res = atk.Attack(tr, pacer, opts.duration, opts.name)
+	}
+
 	enc := vegeta.NewEncoder(out)
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt)
@@ -191,7 +470,7 @@ func attack(opts *attackOpts) (err error) {
 			return nil
 		case r, ok := <-res:
 			if !ok {
-				return nil
+				return replayed(logged)
 			}
 			if err = enc.Encode(r); err != nil {
 				return err
@@ -200,6 +479,203 @@ func attack(opts *attackOpts) (err error) {
 	}
 }
 
+// templateTargeter wraps the given TargeterProvider with the templating
+// configured in opts.
+func templateTargeter(tr vegeta.TargeterProvider, opts *attackOpts) (vegeta.TargeterProvider, error) {
+	t := vegeta.Templating{Vars: opts.vars, Captures: opts.capture}
+	if opts.feederf != "" {
+		f, err := os.Open(opts.feederf)
+		if err != nil {
+			return nil, fmt.Errorf("error opening %s: %s", opts.feederf, err)
+		}
+		defer f.Close()
+
+		if strings.HasSuffix(opts.feederf, ".csv") {
+			t.Feeder, err = vegeta.ReadCSVFeeder(f)
+		} else {
+			t.Feeder, err = vegeta.ReadJSONFeeder(f)
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %s", opts.feederf, err)
+		}
+	}
+	return vegeta.NewTemplateTargeter(tr, t)
+}
+
+// accessLogTargeter returns a Replay of the access log read from src with
+// the options configured in opts.
+func accessLogTargeter(src io.Reader, body []byte, hdr http.Header, opts *attackOpts) (*vegeta.Replay, error) {
+	o := vegeta.AccessLogOptions{
+		Format:      opts.logFormat,
+		BaseURL:     opts.logBaseURL,
+		TimeLayout:  opts.logTimeLayout,
+		MaxBadLines: opts.logMaxBad,
+	}
+
+	for k, field := range opts.logFields {
+		switch {
+		case k == "time":
+			o.Fields.Time = field
+		case k == "method":
+			o.Fields.Method = field
+		case k == "url":
+			o.Fields.URL = field
+		case k == "host":
+			o.Fields.Host = field
+		case strings.HasPrefix(k, "header."):
+			if o.Fields.Header == nil {
+				o.Fields.Header = map[string]string{}
+			}
+			o.Fields.Header[strings.TrimPrefix(k, "header.")] = field
+		default:
+			return nil, fmt.Errorf("bad -log-fields key: %s", k)
+		}
+	}
+
+	return vegeta.NewAccessLogTargeter(src, body, hdr, o)
+}
+
+// replayed returns the error which ended the given access log Replay early,
+// if any, and reports the bad lines it skipped.
+func replayed(r *vegeta.Replay) error {
+	if r == nil {
+		return nil
+	} else if err := r.Err(); err != nil {
+		return err
+	}
+
+	if n := r.Skipped(); n > 0 {
+		fmt.Fprintf(os.Stderr, "vegeta: skipped %d bad access log lines\n", n)
+	}
+	return nil
+}
+
+// authenticator builds the vegeta.Authenticator of the given options,
+// which is nil if none is set.
+func authenticator(opts *attackOpts) (vegeta.Authenticator, error) {
+	var auths []vegeta.Authenticator
+
+	if opts.basicAuth != "" {
+		ps := strings.SplitN(opts.basicAuth, ":", 2)
+		if len(ps) != 2 {
+			return nil, fmt.Errorf("-basic-auth %q doesn't match the \"user:password\" format", opts.basicAuth)
+		}
+		auths = append(auths, vegeta.BasicAuth(ps[0], ps[1]))
+	}
+
+	if opts.oauth2.TokenURL != "" {
+		au, err := vegeta.NewOAuth2Authenticator(opts.oauth2)
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, au)
+	}
+
+	if opts.jwtKeyf != "" {
+		key, err := ioutil.ReadFile(opts.jwtKeyf)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %s", opts.jwtKeyf, err)
+		}
+
+		claims := make(map[string]interface{}, len(opts.jwtClaims))
+		for name, v := range opts.jwtClaims {
+			var claim interface{}
+			if err := json.Unmarshal([]byte(v), &claim); err != nil {
+				claim = v
+			}
+			claims[name] = claim
+		}
+
+		au, err := vegeta.NewJWTAuthenticator(vegeta.JWTConfig{
+			Algorithm: opts.jwtAlg,
+			Key:       bytes.TrimSpace(key),
+			Claims:    claims,
+			TTL:       opts.jwtTTL,
+		})
+		if err != nil {
+			return nil, err
+		}
+		auths = append(auths, au)
+	}
+
+	if opts.sigV4 != "" {
+		ps := strings.SplitN(opts.sigV4, "/", 2)
+		if len(ps) != 2 || ps[0] == "" || ps[1] == "" {
+			return nil, fmt.Errorf("-sigv4 %q doesn't match the \"region/service\" format", opts.sigV4)
+		}
+
+		c := vegeta.SigV4Config{
+			Region:          ps[0],
+			Service:         ps[1],
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+
+		if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+			return nil, fmt.Errorf("-sigv4 requires the AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY environment variables")
+		}
+		auths = append(auths, vegeta.NewSigV4Authenticator(c))
+	}
+
+	switch len(auths) {
+	case 0:
+		return nil, nil
+	case 1:
+		return auths[0], nil
+	default:
+		return nil, fmt.Errorf("only one of -basic-auth, -oauth2-token-url, -jwt-key and -sigv4 can be set")
+	}
+}
+
+// cookieJars returns the vegeta.Cookies option of the given options.
+func cookieJars(opts *attackOpts) (func(*vegeta.Attacker), error) {
+	var seed []vegeta.CookieSeed
+	if opts.cookieFile != "" {
+		f, err := os.Open(opts.cookieFile)
+		if err != nil {
+			return nil, fmt.Errorf("error opening %s: %s", opts.cookieFile, err)
+		}
+		defer f.Close()
+
+		if seed, err = vegeta.ReadCookieFile(f); err != nil {
+			return nil, fmt.Errorf("error reading %s: %s", opts.cookieFile, err)
+		}
+	}
+
+	switch opts.cookies {
+	case cookiesNone:
+		if opts.cookieFile != "" {
+			return vegeta.Cookies(vegeta.WorkerCookies, seed), nil
+		}
+		return vegeta.Cookies(vegeta.NoCookies, nil), nil
+	case cookiesShared:
+		return vegeta.Cookies(vegeta.SharedCookies, seed), nil
+	case cookiesWorker:
+		return vegeta.Cookies(vegeta.WorkerCookies, seed), nil
+	default:
+		return nil, fmt.Errorf("cookies %q isn't one of [%s]", opts.cookies, strings.Join(cookieModes, ", "))
+	}
+}
+
+// localAddrs returns the vegeta.LocalAddrs option of the given options.
+func localAddrs(opts *attackOpts) (func(*vegeta.Attacker), error) {
+	var addrs []net.IPAddr
+	if len(opts.laddr.addrs) > 1 {
+		addrs = opts.laddr.addrs
+	}
+
+	switch opts.laddrMode {
+	case laddrRoundRobin:
+		return vegeta.LocalAddrs(addrs, vegeta.RoundRobinAddrs, opts.lports), nil
+	case laddrWorker:
+		return vegeta.LocalAddrs(addrs, vegeta.WorkerAddrs, opts.lports), nil
+	default:
+		return nil, fmt.Errorf("laddr-mode %q isn't one of [%s]", opts.laddrMode, strings.Join(laddrModes, ", "))
+	}
+}
+
 // tlsConfig builds a *tls.Config from the given options.
 func tlsConfig(insecure bool, certf, keyf string, rootCerts []string) (*tls.Config, error) {
 	var err error
@@ -26,3 +26,25 @@ func TestHeadersSet(t *testing.T) {
 		}
 	}
 }
+
+func TestLocalAddrSet(t *testing.T) {
+	for _, tt := range []struct {
+		in, want string
+		err      bool
+	}{
+		{"127.0.0.1", "127.0.0.1", false},
+		{"10.0.0.0/30,10.0.1.7", "10.0.0.1,10.0.0.2,10.0.1.7", false},
+		{"10.0.0.254/31", "10.0.0.254,10.0.0.255", false},
+		{"10.0.0.5/32", "10.0.0.5", false},
+		{"fd00::/127", "fd00::,fd00::1", false},
+		{"10.0.0.0/8", "", true},
+		{"10.0.0.0/33", "", true},
+	} {
+		var l localAddr
+		if err := l.Set(tt.in); (err != nil) != tt.err {
+			t.Errorf("%q: got error %v, want error %v", tt.in, err, tt.err)
+		} else if got := l.String(); got != tt.want {
+			t.Errorf("%q: got %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -35,6 +35,29 @@ The CSV encoder doesn't write a header. The columns written by it are:
   7. Base64 encoded response body
   8. Attack name
   9. Sequence number of request
+  10. Tag of the target
+  11. Intended Unix timestamp in nanoseconds since epoch, if known
+  12. DNS lookup duration in nanoseconds, if traced
+  13. TCP connect duration in nanoseconds, if traced
+  14. TLS handshake duration in nanoseconds, if traced
+  15. Time to first byte in nanoseconds, if traced
+  16. Response body transfer duration in nanoseconds, if traced
+  17. Whether the connection was reused (true | false), if traced
+  18. Method of the target
+  19. URL of the target
+  20. Protocol of the response, if recorded
+  21. Remote address of the response, if recorded
+  22. Base64 encoded response headers in wire format, if recorded
+  23. Number of retries, if retried
+  24. JSON array of the errors of the retried attempts, if retried
+  25. Number of the WebSocket message within its connection, if any
+  26. Time to the first byte of a streamed response in nanoseconds, if streamed
+  27. Time to the first event of a streamed response in nanoseconds, if streamed
+  28. Number of events of a streamed response, if streamed
+  29. JSON array of the gaps between events in nanoseconds, sampled down to
+      100 of them, if streamed
+  30. Handshake of the connection opened by the request in nanoseconds, if recorded
+  31. Local address of the connection of the request, if recorded
 
 Arguments:
   <file>  A file with vegeta attack results encoded with one of
@@ -6,6 +6,7 @@ import (
 	"math"
 	"net"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -45,12 +46,133 @@ func (h headers) Set(value string) error {
 	return nil
 }
 
-// localAddr implements the Flag interface for parsing net.IPAddr
-type localAddr struct{ *net.IPAddr }
+// maxLocalAddrs is the maximum number of addresses a localAddr expands to.
+const maxLocalAddrs = 1 << 16
 
-func (ip *localAddr) Set(value string) (err error) {
-	ip.IPAddr, err = net.ResolveIPAddr("ip", value)
-	return
+// localAddr implements the Flag interface for parsing a comma separated list
+// of local IP addresses, host names and CIDR ranges into net.IPAddrs. CIDR
+// ranges stand for the addresses of their hosts.
+type localAddr struct{ addrs []net.IPAddr }
+
+func (l *localAddr) Set(value string) error {
+	var addrs []net.IPAddr
+	for _, v := range strings.Split(value, ",") {
+		v = strings.TrimSpace(v)
+		if !strings.Contains(v, "/") {
+			ip, err := net.ResolveIPAddr("ip", v)
+			if err != nil {
+				return err
+			}
+			addrs = append(addrs, *ip)
+			continue
+		}
+
+		ip, ipnet, err := net.ParseCIDR(v)
+		if err != nil {
+			return err
+		}
+
+		ones, bits := ipnet.Mask.Size()
+		if bits-ones > 16 || len(addrs)+1<<uint(bits-ones) > maxLocalAddrs {
+			return fmt.Errorf("%q has more than %d addresses", value, maxLocalAddrs)
+		}
+
+		var block []net.IPAddr
+		for ip = ip.Mask(ipnet.Mask); ipnet.Contains(ip); ip = nextIP(ip) {
+			block = append(block, net.IPAddr{IP: ip})
+		}
+
+		// The network and broadcast addresses of IPv4 ranges can't be
+		// bound to, unlike both of those of /31 ranges.
+		if bits == 32 && bits-ones > 1 {
+			block = block[1 : len(block)-1]
+		}
+		addrs = append(addrs, block...)
+	}
+
+	l.addrs = addrs
+	return nil
+}
+
+func (l *localAddr) String() string {
+	if l == nil {
+		return ""
+	}
+
+	addrs := make([]string, len(l.addrs))
+	for i := range l.addrs {
+		addrs[i] = l.addrs[i].String()
+	}
+	return strings.Join(addrs, ",")
+}
+
+// nextIP returns the IP address following the given one, which wraps around
+// to zero past the last one.
+func nextIP(ip net.IP) net.IP {
+	next := make(net.IP, len(ip))
+	copy(next, ip)
+	for i := len(next) - 1; i >= 0; i-- {
+		if next[i]++; next[i] != 0 {
+			break
+		}
+	}
+	return next
+}
+
+// portRangeFlag implements the flag.Value interface for a range of local
+// ports as first-last.
+type portRangeFlag struct{ r *vegeta.PortRange }
+
+func (f *portRangeFlag) Set(v string) error {
+	ps := strings.SplitN(v, "-", 2)
+	if len(ps) != 2 {
+		return fmt.Errorf("%q doesn't match the \"first-last\" format", v)
+	}
+
+	first, err := strconv.ParseUint(ps[0], 10, 16)
+	if err != nil {
+		return err
+	}
+
+	last, err := strconv.ParseUint(ps[1], 10, 16)
+	if err != nil {
+		return err
+	}
+
+	if first == 0 || first > last {
+		return fmt.Errorf("%q isn't a range of ports between 1 and 65535", v)
+	}
+
+	*f.r = vegeta.PortRange{First: uint16(first), Last: uint16(last)}
+	return nil
+}
+
+func (f *portRangeFlag) String() string {
+	if f.r == nil || *f.r == (vegeta.PortRange{}) {
+		return ""
+	}
+	return fmt.Sprintf("%d-%d", f.r.First, f.r.Last)
+}
+
+// kvFlag implements the flag.Value interface for repeated name=value pairs.
+type kvFlag map[string]string
+
+func (f kvFlag) Set(v string) error {
+	ps := strings.SplitN(v, "=", 2)
+	if len(ps) != 2 || ps[0] == "" {
+		return fmt.Errorf("%q doesn't match the \"name=value\" format", v)
+	}
+	f[ps[0]] = ps[1]
+	return nil
+}
+
+func (f kvFlag) String() string {
+	pairs := make([]string, 0, len(f))
+	for k, v := range f {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
 }
 
 // csl implements the flag.Value interface for comma separated lists
@@ -63,6 +185,16 @@ func (l *csl) Set(v string) error {
 
 func (l csl) String() string { return strings.Join(l, ",") }
 
+// listFlag implements the flag.Value interface for repeated values.
+type listFlag []string
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func (l listFlag) String() string { return strings.Join(l, ", ") }
+
 type rateFlag struct{ *vegeta.Rate }
 
 func (f *rateFlag) Set(v string) (err error) {
@@ -103,6 +235,104 @@ func (f *rateFlag) String() string {
 	return fmt.Sprintf("%d/%s", f.Freq, f.Per)
 }
 
+// usersFlag implements the flag.Value interface for user schedules of
+// comma separated stages, each a number of users optionally followed by
+// @duration to ramp up or down to them over that duration.
+type usersFlag struct{ s *vegeta.UserSchedule }
+
+func (f *usersFlag) Set(v string) error {
+	var s vegeta.UserSchedule
+	for _, stage := range strings.Split(v, ",") {
+		ps := strings.SplitN(strings.TrimSpace(stage), "@", 2)
+
+		var (
+			st  vegeta.UserStage
+			err error
+		)
+
+		if st.Users, err = strconv.ParseUint(ps[0], 10, 64); err != nil {
+			return fmt.Errorf("-users stage %q doesn't match the \"users[@duration]\" format", stage)
+		}
+
+		if len(ps) == 2 {
+			if st.Duration, err = time.ParseDuration(ps[1]); err != nil || st.Duration < 0 {
+				return fmt.Errorf("-users stage %q doesn't match the \"users[@duration]\" format", stage)
+			}
+		}
+
+		s = append(s, st)
+	}
+
+	*f.s = s
+	return nil
+}
+
+func (f *usersFlag) String() string {
+	if f.s == nil {
+		return ""
+	}
+
+	stages := make([]string, 0, len(*f.s))
+	for _, st := range *f.s {
+		stage := strconv.FormatUint(st.Users, 10)
+		if st.Duration > 0 {
+			stage += "@" + st.Duration.String()
+		}
+		stages = append(stages, stage)
+	}
+
+	return strings.Join(stages, ",")
+}
+
+// thinkFlag implements the flag.Value interface for think time
+// distributions as constant:<duration>, uniform:<min>-<max> or
+// exponential:<mean>. A bare duration is constant.
+type thinkFlag struct {
+	expr  string
+	think vegeta.ThinkTime
+}
+
+func (f *thinkFlag) Set(v string) (err error) {
+	kind, arg := "constant", v
+	if ps := strings.SplitN(v, ":", 2); len(ps) == 2 {
+		kind, arg = ps[0], ps[1]
+	}
+
+	bad := fmt.Errorf("-think %q doesn't match one of the constant:<duration>, uniform:<min>-<max> or exponential:<mean> formats", v)
+
+	switch kind {
+	case "constant", "exponential":
+		d, err := time.ParseDuration(arg)
+		if err != nil || d < 0 {
+			return bad
+		}
+		if f.think = vegeta.ConstantThinkTime(d); kind == "exponential" {
+			f.think = vegeta.ExponentialThinkTime(d)
+		}
+	case "uniform":
+		ps := strings.SplitN(arg, "-", 2)
+		if len(ps) != 2 {
+			return bad
+		}
+		min, err := time.ParseDuration(ps[0])
+		if err != nil || min < 0 {
+			return bad
+		}
+		max, err := time.ParseDuration(ps[1])
+		if err != nil || max < min {
+			return bad
+		}
+		f.think = vegeta.UniformThinkTime(min, max)
+	default:
+		return bad
+	}
+
+	f.expr = v
+	return nil
+}
+
+func (f *thinkFlag) String() string { return f.expr }
+
 type maxBodyFlag struct{ n *int64 }
 
 func (f *maxBodyFlag) Set(v string) (err error) {
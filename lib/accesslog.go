@@ -0,0 +1,246 @@
+package vegeta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Supported access log formats.
+const (
+	// CombinedLogFormat is the NCSA combined log format used by default by
+	// nginx and Apache, which is also able to parse the common log format.
+	CombinedLogFormat = "combined"
+	// JSONLogFormat is the format of logs with one JSON object per line.
+	JSONLogFormat = "json"
+)
+
+// LogFormats contains the canonical list of the valid access log formats.
+var LogFormats = []string{CombinedLogFormat, JSONLogFormat}
+
+// AccessLogOptions configures how NewAccessLogTargeter parses access logs.
+type AccessLogOptions struct {
+	// Format is one of LogFormats. It defaults to CombinedLogFormat.
+	Format string
+	// BaseURL is the URL the logged request URIs are relative to, e.g.
+	// https://goku:9090. It's required unless logged URLs are absolute or,
+	// in JSON logs, the host field is set. It takes precedence over the
+	// host field.
+	BaseURL string
+	// Fields maps the fields of JSON logs.
+	Fields LogFields
+	// TimeLayout is the layout of the time field of JSON logs as understood
+	// by time.Parse. It defaults to time.RFC3339Nano. Numeric times are
+	// always read as seconds since the Unix epoch.
+	TimeLayout string
+	// MaxBadLines is the number of bad lines, which can't be turned into
+	// Targets, that are skipped before the replay fails. Zero skips all of
+	// them and a negative number fails on the first one.
+	MaxBadLines int
+}
+
+// LogFields maps the fields of JSON logs to the request fields they hold.
+// Nested fields are separated by dots, like request.method.
+type LogFields struct {
+	Time   string // defaults to time
+	Method string // defaults to method
+	URL    string // defaults to url
+	Host   string // defaults to host
+	// Header maps header names to the fields holding their values.
+	Header map[string]string
+}
+
+// combinedLog matches lines in the combined or common log formats:
+//
+//    127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://www.example.com/start.html" "Mozilla/4.08"
+var combinedLog = regexp.MustCompile(`^\S+ \S+ .*?\[([^\]]+)\] "(\S+) (\S+)[^"]*" \S+ \S+(?: "((?:[^"\\]|\\.)*)" "((?:[^"\\]|\\.)*)")?`)
+
+const combinedLogTime = "02/Jan/2006:15:04:05 -0700"
+
+// NewAccessLogTargeter returns a Replay of the requests logged in the access
+// log read from the given io.Reader, in the order they were logged. The log
+// is read lazily, so it can be arbitrarily large. Use the Replay's Pacer to
+// hit the requests with the time intervals they were logged with.
+//
+// Combined logs keep the Referer and User-Agent headers of requests. JSON logs
+// keep the headers mapped in the options. Bad lines are skipped, up to the
+// MaxBadLines of the options, and counted by the Replay's Skipped method.
+//
+// body will be set as the Target's body.
+// hdr will be merged with each Target's headers.
+func NewAccessLogTargeter(src io.Reader, body []byte, hdr http.Header, opts AccessLogOptions) (*Replay, error) {
+	var parse func(line []byte, tgt *Target) (time.Time, error)
+	switch opts.Format {
+	case "", CombinedLogFormat:
+		parse = parseCombinedLog
+	case JSONLogFormat:
+		fields, layout := opts.Fields, opts.TimeLayout
+		if layout == "" {
+			layout = time.RFC3339Nano
+		}
+		useHost := opts.BaseURL == ""
+		parse = func(line []byte, tgt *Target) (time.Time, error) {
+			return parseJSONLog(line, tgt, fields, layout, useHost)
+		}
+	default:
+		return nil, fmt.Errorf("bad access log format: %s", opts.Format)
+	}
+
+	var base *url.URL
+	if opts.BaseURL != "" {
+		var err error
+		if base, err = url.Parse(opts.BaseURL); err != nil || !base.IsAbs() {
+			return nil, fmt.Errorf("bad access log base URL: %s", opts.BaseURL)
+		}
+	}
+
+	var (
+		sc    = bufio.NewScanner(src)
+		n     int
+		bad   int
+		began time.Time
+	)
+
+	skip := func(err error) (time.Duration, error) {
+		switch bad++; {
+		case opts.MaxBadLines < 0:
+			return 0, err
+		case opts.MaxBadLines > 0 && bad > opts.MaxBadLines:
+			return 0, fmt.Errorf("more than %d bad access log lines: %s", opts.MaxBadLines, err)
+		default:
+			return 0, skipError{err}
+		}
+	}
+
+	sc.Buffer(nil, 1<<20)
+
+	return NewReplay(func(tgt *Target) (time.Duration, error) {
+		var line []byte
+		for len(line) == 0 {
+			if !sc.Scan() {
+				if err := sc.Err(); err != nil {
+					return 0, err
+				}
+				return 0, ErrNoTargets
+			}
+			n++
+			line = bytes.TrimSpace(sc.Bytes())
+		}
+
+		*tgt = Target{Body: body, Header: http.Header{}}
+		for k, vs := range hdr {
+			tgt.Header[k] = append(tgt.Header[k], vs...)
+		}
+
+		ts, err := parse(line, tgt)
+		if err != nil {
+			return skip(fmt.Errorf("bad access log line %d: %s", n, err))
+		}
+
+		u, err := url.Parse(tgt.URL)
+		if err != nil {
+			return skip(fmt.Errorf("bad access log line %d: bad URL: %s", n, tgt.URL))
+		} else if !u.IsAbs() {
+			if base == nil {
+				return skip(fmt.Errorf("bad access log line %d: relative URL %s requires a base URL", n, tgt.URL))
+			}
+			tgt.URL = strings.TrimSuffix(base.String(), "/") + "/" + strings.TrimPrefix(u.String(), "/")
+		}
+
+		if began.IsZero() {
+			began = ts
+		}
+
+		if ts.IsZero() {
+			return 0, nil
+		}
+
+		return ts.Sub(began), nil
+	}), nil
+}
+
+func parseCombinedLog(line []byte, tgt *Target) (time.Time, error) {
+	m := combinedLog.FindSubmatch(line)
+	if m == nil {
+		return time.Time{}, fmt.Errorf("doesn't match the %s log format", CombinedLogFormat)
+	}
+
+	ts, err := time.Parse(combinedLogTime, string(m[1]))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	tgt.Method, tgt.URL = string(m[2]), string(m[3])
+	for i, name := range []string{"Referer", "User-Agent"} {
+		if v := string(m[4+i]); v != "" && v != "-" {
+			tgt.Header[name] = append(tgt.Header[name], strings.Replace(v, `\"`, `"`, -1))
+		}
+	}
+
+	return ts, nil
+}
+
+// parseJSONLog parses a JSON log line into tgt. useHost sets whether the
+// host field is used to make relative URLs absolute.
+func parseJSONLog(line []byte, tgt *Target, fields LogFields, layout string, useHost bool) (time.Time, error) {
+	var entry map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&entry); err != nil {
+		return time.Time{}, err
+	}
+
+	field := func(name, def string) string {
+		if name == "" {
+			name = def
+		}
+
+		var v interface{} = entry
+		for _, key := range strings.Split(name, ".") {
+			obj, _ := v.(map[string]interface{})
+			v = obj[key]
+		}
+
+		switch s := v.(type) {
+		case string:
+			return s
+		case json.Number:
+			return s.String()
+		default:
+			return ""
+		}
+	}
+
+	if tgt.Method = field(fields.Method, "method"); tgt.Method == "" {
+		return time.Time{}, ErrNoMethod
+	} else if tgt.URL = field(fields.URL, "url"); tgt.URL == "" {
+		return time.Time{}, ErrNoURL
+	}
+
+	if host := field(fields.Host, "host"); useHost && host != "" && !strings.Contains(tgt.URL, "://") {
+		tgt.URL = "http://" + host + "/" + strings.TrimPrefix(tgt.URL, "/")
+	}
+
+	for name, f := range fields.Header {
+		if v := field(f, ""); v != "" {
+			tgt.Header[name] = append(tgt.Header[name], v)
+		}
+	}
+
+	ts := field(fields.Time, "time")
+	if ts == "" {
+		return time.Time{}, nil
+	} else if secs, err := strconv.ParseFloat(ts, 64); err == nil {
+		return time.Unix(0, int64(secs*float64(time.Second))), nil
+	}
+
+	return time.Parse(layout, ts)
+}
@@ -0,0 +1,151 @@
+package vegeta
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAccessLogTargeter(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name    string
+		log     string
+		opts    AccessLogOptions
+		targets []Target
+		offsets []time.Duration
+		skipped uint64
+		err     error
+	}{
+		{
+			name: "combined",
+			log: `127.0.0.1 - frank [10/Oct/2000:13:55:36 -0700] "GET /apache_pb.gif HTTP/1.0" 200 2326 "http://goku/start.html" "Mozilla/4.08 \"vegeta\""
+
+10.0.0.1 - - [10/Oct/2000:13:55:38 -0700] "POST /login?next=%2F HTTP/1.1" 302 0 "-" "-"
+10.0.0.2 - - [10/Oct/2000:20:55:39 +0000] "DELETE http://vegeta/power HTTP/1.1" 204 0
+`,
+			opts: AccessLogOptions{BaseURL: "https://goku:9090/"},
+			targets: []Target{
+				{
+					Method: "GET",
+					URL:    "https://goku:9090/apache_pb.gif",
+					Header: http.Header{
+						"X-Attack":   []string{"vegeta"},
+						"Referer":    []string{"http://goku/start.html"},
+						"User-Agent": []string{`Mozilla/4.08 "vegeta"`},
+					},
+				},
+				{Method: "POST", URL: "https://goku:9090/login?next=%2F", Header: http.Header{"X-Attack": []string{"vegeta"}}},
+				{Method: "DELETE", URL: "http://vegeta/power", Header: http.Header{"X-Attack": []string{"vegeta"}}},
+			},
+			offsets: []time.Duration{0, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			name: "json",
+			log: `{"ts": 1500000000.5, "req": {"verb": "GET", "uri": "/a"}, "domain": "goku", "ua": "vegeta"}
+{"ts": 1500000001, "req": {"verb": "PUT", "uri": "http://vegeta/b"}}
+`,
+			opts: AccessLogOptions{
+				Format: JSONLogFormat,
+				Fields: LogFields{
+					Time:   "ts",
+					Method: "req.verb",
+					URL:    "req.uri",
+					Host:   "domain",
+					Header: map[string]string{"User-Agent": "ua"},
+				},
+			},
+			targets: []Target{
+				{Method: "GET", URL: "http://goku/a", Header: http.Header{"X-Attack": []string{"vegeta"}, "User-Agent": []string{"vegeta"}}},
+				{Method: "PUT", URL: "http://vegeta/b", Header: http.Header{"X-Attack": []string{"vegeta"}}},
+			},
+			offsets: []time.Duration{0, 500 * time.Millisecond},
+		},
+		{
+			name: "json time layout",
+			log:  `{"time": "2018-01-01 00:00:00", "method": "GET", "url": "/a", "host": "goku"}` + "\n" + `{"time": "2018-01-01 00:01:00", "method": "GET", "url": "/b"}`,
+			opts: AccessLogOptions{Format: JSONLogFormat, BaseURL: "http://vegeta", TimeLayout: "2006-01-02 15:04:05"},
+			targets: []Target{
+				{Method: "GET", URL: "http://vegeta/a", Header: http.Header{"X-Attack": []string{"vegeta"}}},
+				{Method: "GET", URL: "http://vegeta/b", Header: http.Header{"X-Attack": []string{"vegeta"}}},
+			},
+			offsets: []time.Duration{0, time.Minute},
+		},
+		{
+			name: "relative url",
+			log:  `127.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET /a HTTP/1.0" 200 2326`,
+			opts: AccessLogOptions{MaxBadLines: -1},
+			err:  fmt.Errorf("bad access log line 1: relative URL /a requires a base URL"),
+		},
+		{
+			name: "bad lines",
+			log: `10.0.0.1 - - [10/Oct/2000:13:55:36 -0700] "GET http://goku/a HTTP/1.1" 200 0
+garbage
+10.0.0.1 - - [10/Oct/2000:13:55:37 -0700] "-" 400 0 "-" "-"
+10.0.0.1 - - [10/Oct/2000:13:55:38 -0700] "GET /b HTTP/1.1" 200 0
+10.0.0.1 - - [10/Oct/2000:13:55:39 -0700] "GET http://goku/c HTTP/1.1" 200 0
+`,
+			targets: []Target{
+				{Method: "GET", URL: "http://goku/a", Header: http.Header{"X-Attack": []string{"vegeta"}}},
+				{Method: "GET", URL: "http://goku/c", Header: http.Header{"X-Attack": []string{"vegeta"}}},
+			},
+			offsets: []time.Duration{0, 3 * time.Second},
+			skipped: 3,
+		},
+		{
+			name:    "too many bad lines",
+			log:     "garbage\n\ngarbage\n",
+			opts:    AccessLogOptions{MaxBadLines: 1},
+			skipped: 1,
+			err:     fmt.Errorf("more than 1 bad access log lines: bad access log line 3: doesn't match the combined log format"),
+		},
+		{
+			name: "json missing method",
+			log:  `{"url": "http://goku"}`,
+			opts: AccessLogOptions{Format: JSONLogFormat, MaxBadLines: -1},
+			err:  fmt.Errorf("bad access log line 1: %s", ErrNoMethod),
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			hdr := http.Header{"X-Attack": []string{"vegeta"}}
+			r, err := NewAccessLogTargeter(strings.NewReader(tc.log), nil, hdr, tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for i, want := range tc.targets {
+				var got Target
+				if err := r.next(&got); err != nil {
+					t.Fatalf("#%d: %s", i, err)
+				} else if !got.Equal(&want) {
+					t.Errorf("#%d: got: %#v, want: %#v", i, got, want)
+				} else if r.offset != tc.offsets[i] {
+					t.Errorf("#%d: got offset %s, want %s", i, r.offset, tc.offsets[i])
+				}
+			}
+
+			want := tc.err
+			if want == nil {
+				want = ErrNoTargets
+			}
+
+			if err := r.next(&Target{}); fmt.Sprint(err) != fmt.Sprint(want) {
+				t.Errorf("got error %v, want %v", err, want)
+			} else if fmt.Sprint(r.Err()) != fmt.Sprint(tc.err) {
+				t.Errorf("got replay error %v, want %v", r.Err(), tc.err)
+			} else if r.Skipped() != tc.skipped {
+				t.Errorf("got %d skipped lines, want %d", r.Skipped(), tc.skipped)
+			}
+		})
+	}
+
+	if _, err := NewAccessLogTargeter(nil, nil, nil, AccessLogOptions{Format: "xml"}); err == nil {
+		t.Error("got nil error for bad format")
+	}
+}
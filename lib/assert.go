@@ -0,0 +1,153 @@
+package vegeta
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// An Assertion checks the responses of hits, whose Results are marked as
+// failed when it isn't met.
+type Assertion struct {
+	expr  string
+	check func(r *assertee) error
+}
+
+// assertee holds the parts of a hit's response that assertions check.
+type assertee struct {
+	code    uint16
+	header  http.Header
+	body    []byte
+	latency time.Duration
+}
+
+// ParseAssertion parses an assertion expression, which is one of:
+//
+//    status:<code>,...        the status code is one of the given
+//    body-contains:<text>     the body contains the given text
+//    body-regex:<pattern>     the body matches the given regular expression
+//    jsonpath:<path>=<value>  the JSON body value at the path equals the given
+//    header:<name>            the given header is present
+//    latency:<duration>       the latency doesn't exceed the given duration
+//
+// Body assertions check the body as limited by MaxBody.
+func ParseAssertion(expr string) (Assertion, error) {
+	a := Assertion{expr: expr}
+
+	tokens := strings.SplitN(expr, ":", 2)
+	if len(tokens) < 2 {
+		return a, fmt.Errorf("bad assertion: %s", expr)
+	}
+
+	switch kind, arg := tokens[0], tokens[1]; kind {
+	case "status":
+		codes := map[uint16]bool{}
+		for _, c := range strings.Split(arg, ",") {
+			code, err := strconv.ParseUint(strings.TrimSpace(c), 10, 16)
+			if err != nil {
+				return a, fmt.Errorf("bad assertion: %s", expr)
+			}
+			codes[uint16(code)] = true
+		}
+		a.check = func(r *assertee) error {
+			if !codes[r.code] {
+				return fmt.Errorf("status %d not in %s", r.code, arg)
+			}
+			return nil
+		}
+	case "body-contains":
+		text := []byte(arg)
+		a.check = func(r *assertee) error {
+			if !bytes.Contains(r.body, text) {
+				return fmt.Errorf("body doesn't contain %q", arg)
+			}
+			return nil
+		}
+	case "body-regex":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return a, fmt.Errorf("bad assertion: %s", err)
+		}
+		a.check = func(r *assertee) error {
+			if !re.Match(r.body) {
+				return fmt.Errorf("body doesn't match %q", arg)
+			}
+			return nil
+		}
+	case "jsonpath":
+		kv := strings.SplitN(arg, "=", 2)
+		if len(kv) != 2 {
+			return a, fmt.Errorf("bad assertion: %s", expr)
+		}
+		p, err := parseJSONPath(kv[0])
+		if err != nil {
+			return a, fmt.Errorf("bad assertion: %s", err)
+		}
+		want := kv[1]
+		a.check = func(r *assertee) error {
+			if got, err := p.extract(r.body); err != nil {
+				return err
+			} else if got != want {
+				return fmt.Errorf("%s isn't %q", kv[0], want)
+			}
+			return nil
+		}
+	case "header":
+		name := strings.TrimSpace(arg)
+		if name == "" {
+			return a, fmt.Errorf("bad assertion: %s", expr)
+		}
+		a.check = func(r *assertee) error {
+			if !hasHeader(r.header, name) {
+				return fmt.Errorf("header %s missing", name)
+			}
+			return nil
+		}
+	case "latency":
+		max, err := time.ParseDuration(arg)
+		if err != nil || max <= 0 {
+			return a, fmt.Errorf("bad assertion: %s", expr)
+		}
+		a.check = func(r *assertee) error {
+			if r.latency > max {
+				return fmt.Errorf("latency over %s", max)
+			}
+			return nil
+		}
+	default:
+		return a, fmt.Errorf("bad assertion kind: %s", kind)
+	}
+
+	return a, nil
+}
+
+// MustParseAssertion is like ParseAssertion but panics if the
+// expression can't be parsed.
+func MustParseAssertion(expr string) Assertion {
+	a, err := ParseAssertion(expr)
+	if err != nil {
+		panic(err)
+	}
+	return a
+}
+
+// String returns the expression of the Assertion.
+func (a Assertion) String() string { return a.expr }
+
+// assert returns an error describing the failure of the first of the given
+// assertions which isn't met by r. Failure messages don't include bodies,
+// extracted values or latencies, which vary across hits, so that they can be
+// grouped in reports. They only include the status codes of responses,
+// which take few distinct values.
+func assert(r *assertee, as []Assertion) error {
+	for _, a := range as {
+		if err := a.check(r); err != nil {
+			return fmt.Errorf("assertion failed: %s", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package vegeta
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestAssertions(t *testing.T) {
+	t.Parallel()
+
+	r := &assertee{
+		code:    200,
+		header:  http.Header{"X-Request-Id": []string{"1"}},
+		body:    []byte(`{"status": "error", "items": [{"id": 7}]}`),
+		latency: 50 * time.Millisecond,
+	}
+
+	for _, tc := range []struct {
+		expr string
+		err  string
+	}{
+		{"status:200,201", ""},
+		{"status:201", "status 200 not in 201"},
+		{"body-contains:items", ""},
+		{"body-contains:goku", `body doesn't contain "goku"`},
+		{`body-regex:"id":\s*\d+`, ""},
+		{"body-regex:^ok$", `body doesn't match "^ok$"`},
+		{"jsonpath:$.items[0].id=7", ""},
+		{"jsonpath:$.status=ok", `$.status isn't "ok"`},
+		{"jsonpath:$.error=ok", `jsonpath "$.error": "error" not found`},
+		{"header:x-request-id", ""},
+		{"header:Location", "header Location missing"},
+		{"latency:100ms", ""},
+		{"latency:10ms", "latency over 10ms"},
+	} {
+		a, err := ParseAssertion(tc.expr)
+		if err != nil {
+			t.Fatalf("%s: %s", tc.expr, err)
+		}
+
+		want := ""
+		if tc.err != "" {
+			want = "assertion failed: " + tc.err
+		}
+
+		if got := fmt.Sprint(assert(r, []Assertion{a})); got != want && (want != "" || got != "<nil>") {
+			t.Errorf("%s: got error %q, want %q", tc.expr, got, want)
+		}
+	}
+
+	for _, expr := range []string{
+		"status",
+		"status:2xx",
+		"body-regex:(",
+		"jsonpath:$.status",
+		"jsonpath:status=ok",
+		"header:",
+		"latency:fast",
+		"size:10",
+	} {
+		if _, err := ParseAssertion(expr); err == nil {
+			t.Errorf("%s: got nil error", expr)
+		}
+	}
+}
@@ -9,11 +9,14 @@ import (
 	"math"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"net/url"
 	"sync"
 	"time"
 
+	"github.com/quic-go/quic-go"
 	"golang.org/x/net/http2"
+	"google.golang.org/protobuf/reflect/protoregistry"
 )
 
 // Attacker is an attack executor which wraps an http.Client
@@ -21,10 +24,35 @@ type Attacker struct {
 	dialer     *net.Dialer
 	client     http.Client
 	stopch     chan struct{}
+	stopOnce   sync.Once
 	workers    uint64
 	maxWorkers uint64
 	maxBody    int64
 	redirects  int
+	auth       Authenticator
+	cookies    CookieMode
+	cookieSeed []CookieSeed
+	assertions []Assertion
+	parsed     sync.Map // Target assertion expressions to their Assertions
+	trace      bool
+	metadata   bool
+	retries    RetryPolicy
+	headers    []string // canonical names of the response headers to record
+	tls        *tls.Config
+	grpcFiles  *protoregistry.Files
+	grpcmu     sync.Mutex
+	grpcConns  map[string]*grpcConn // gRPC connections by scheme and host
+	ws         WebSocketConfig
+	stream     bool
+	streamHold time.Duration
+	http3      bool
+	quicmu     sync.Mutex
+	quic       *quic.Transport // QUIC transport of HTTP/3 connections
+	conns      *ConnectionPolicy
+	gate       *connGate   // caps and paces dials with a ConnectionPolicy
+	laddrs     *localAddrs // local addresses and ports of connections
+	unixSocket string
+	err        error // error of the options, which fails attacks
 	seqmu      sync.Mutex
 	seq        uint64
 	began      time.Time
@@ -78,7 +106,7 @@ func NewAttacker(opts ...func(*Attacker)) *Attacker {
 		Timeout: DefaultTimeout,
 		Transport: &http.Transport{
 			Proxy:               http.ProxyFromEnvironment,
-			Dial:                a.dialer.Dial,
+			DialContext:         a.dialer.DialContext,
 			TLSClientConfig:     DefaultTLSConfig,
 			MaxIdleConnsPerHost: DefaultConnections,
 		},
@@ -88,9 +116,23 @@ func NewAttacker(opts ...func(*Attacker)) *Attacker {
 		opt(a)
 	}
 
+	// Options which wrap the transport set by others are wired once they
+	// were all applied, so that they can be given in any order.
+	for _, wire := range []func() error{a.wireHTTP3, a.wireLocalAddrs, a.wireConns} {
+		if a.err = wire(); a.err != nil {
+			break
+		}
+	}
+
 	return a
 }
 
+// Err returns the error of the options of the Attacker which couldn't be
+// applied, if any, in which case its attacks fail their first hit and stop.
+func (a *Attacker) Err() error {
+	return a.err
+}
+
 // Workers returns a functional option which sets the initial number of workers
 // an Attacker uses to hit its targets. More workers may be spawned dynamically
 // to sustain the requested rate in the face of slow responses and errors.
@@ -118,19 +160,38 @@ func Connections(n int) func(*Attacker) {
 func Redirects(n int) func(*Attacker) {
 	return func(a *Attacker) {
 		a.redirects = n
-		a.client.CheckRedirect = func(_ *http.Request, via []*http.Request) error {
-			switch {
-			case n == NoFollow:
-				return http.ErrUseLastResponse
-			case n < len(via):
-				return fmt.Errorf("stopped after %d redirects", n)
-			default:
-				return nil
-			}
+		a.client.CheckRedirect = checkRedirect(n)
+	}
+}
+
+// checkRedirect returns an http.Client CheckRedirect func which follows
+// at most n redirects.
+func checkRedirect(n int) func(*http.Request, []*http.Request) error {
+	return func(_ *http.Request, via []*http.Request) error {
+		switch {
+		case n == NoFollow:
+			return http.ErrUseLastResponse
+		case n < len(via):
+			return fmt.Errorf("stopped after %d redirects", n)
+		default:
+			return nil
 		}
 	}
 }
 
+// Auth returns a functional option which sets the Authenticator that
+// authenticates every request of an Attacker.
+func Auth(au Authenticator) func(*Attacker) {
+	return func(a *Attacker) { a.auth = au }
+}
+
+// Assertions returns a functional option which sets the Assertions that
+// the responses to every hit must meet. Results of hits with unmet
+// assertions have their Error set.
+func Assertions(as ...Assertion) func(*Attacker) {
+	return func(a *Attacker) { a.assertions = as }
+}
+
 // Proxy returns a functional option which sets the `Proxy` field on
 // the http.Client's Transport
 func Proxy(proxy func(*http.Request) (*url.URL, error)) func(*Attacker) {
@@ -154,7 +215,7 @@ func LocalAddr(addr net.IPAddr) func(*Attacker) {
 	return func(a *Attacker) {
 		tr := a.client.Transport.(*http.Transport)
 		a.dialer.LocalAddr = &net.TCPAddr{IP: addr.IP, Zone: addr.Zone}
-		tr.Dial = a.dialer.Dial
+		tr.DialContext = a.dialer.DialContext
 	}
 }
 
@@ -166,7 +227,7 @@ func KeepAlive(keepalive bool) func(*Attacker) {
 		tr.DisableKeepAlives = !keepalive
 		if !keepalive {
 			a.dialer.KeepAlive = 0
-			tr.Dial = a.dialer.Dial
+			tr.DialContext = a.dialer.DialContext
 		}
 	}
 }
@@ -177,6 +238,7 @@ func TLSConfig(c *tls.Config) func(*Attacker) {
 	return func(a *Attacker) {
 		tr := a.client.Transport.(*http.Transport)
 		tr.TLSClientConfig = c
+		a.tls = c
 	}
 }
 
@@ -200,7 +262,7 @@ func H2C(enabled bool) func(*Attacker) {
 			a.client.Transport = &http2.Transport{
 				AllowHTTP: true,
 				DialTLS: func(network, addr string, cfg *tls.Config) (net.Conn, error) {
-					return tr.Dial(network, addr)
+					return tr.DialContext(context.Background(), network, addr)
 				},
 			}
 		}
@@ -213,9 +275,33 @@ func MaxBody(n int64) func(*Attacker) {
 	return func(a *Attacker) { a.maxBody = n }
 }
 
+// Metadata returns a functional option which makes an Attacker record the
+// protocol of responses and the remote and local addresses of their
+// connections in Results, besides the method and URL of the hit Targets,
+// which are always recorded.
+func Metadata(enabled bool) func(*Attacker) {
+	return func(a *Attacker) { a.metadata = enabled }
+}
+
+// ResponseHeaders returns a functional option which makes an Attacker record
+// the response headers with the given names in Results. The name "*" records
+// all of them.
+func ResponseHeaders(names ...string) func(*Attacker) {
+	return func(a *Attacker) {
+		a.headers = make([]string, 0, len(names))
+		for _, name := range names {
+			if name != "*" {
+				name = http.CanonicalHeaderKey(name)
+			}
+			a.headers = append(a.headers, name)
+		}
+	}
+}
+
 // UnixSocket changes the dialer for the attacker to use the specified unix socket file
 func UnixSocket(socket string) func(*Attacker) {
 	return func(a *Attacker) {
+		a.unixSocket = socket
 		if tr, ok := a.client.Transport.(*http.Transport); socket != "" && ok {
 			tr.DialContext = func(_ context.Context, _, _ string) (net.Conn, error) {
 				return net.Dial("unix", socket)
@@ -242,7 +328,7 @@ func (a *Attacker) Attack(tr TargeterProvider, p Pacer, du time.Duration, name s
 	}
 
 	results := make(chan *Result)
-	ticks := make(chan struct{})
+	ticks := make(chan time.Time)
 	for i := uint64(0); i < workers; i++ {
 		wg.Add(1)
 		// instantiate one targeter per worker
@@ -252,6 +338,8 @@ func (a *Attacker) Attack(tr TargeterProvider, p Pacer, du time.Duration, name s
 
 	go func() {
 		defer close(results)
+		defer a.closeQUIC()
+		defer a.closeGRPC()
 		defer wg.Wait()
 		defer close(ticks)
 
@@ -269,9 +357,18 @@ func (a *Attacker) Attack(tr TargeterProvider, p Pacer, du time.Duration, name s
 
 			time.Sleep(wait)
 
+			// The time at which the hit is due, which it may be sent after if
+			// the Pacer or all workers are running behind.
+			intended := began.Add(elapsed + wait)
+			if s, ok := p.(Scheduler); ok {
+				if due, ok := s.Due(count); ok {
+					intended = began.Add(due)
+				}
+			}
+
 			if workers < a.maxWorkers {
 				select {
-				case ticks <- struct{}{}:
+				case ticks <- intended:
 					count++
 					continue
 				case <-a.stopch:
@@ -285,7 +382,7 @@ func (a *Attacker) Attack(tr TargeterProvider, p Pacer, du time.Duration, name s
 			}
 
 			select {
-			case ticks <- struct{}{}:
+			case ticks <- intended:
 				count++
 			case <-a.stopch:
 				return
@@ -298,25 +395,67 @@ func (a *Attacker) Attack(tr TargeterProvider, p Pacer, du time.Duration, name s
 
 // Stop stops the current attack.
 func (a *Attacker) Stop() {
-	select {
-	case <-a.stopch:
-		return
-	default:
-		close(a.stopch)
-	}
+	// Workers may stop the attack concurrently.
+	a.stopOnce.Do(func() { close(a.stopch) })
 }
 
-func (a *Attacker) attack(tr Targeter, name string, workers *sync.WaitGroup, ticks <-chan struct{}, results chan<- *Result) {
+func (a *Attacker) attack(tr Targeter, name string, workers *sync.WaitGroup, ticks <-chan time.Time, results chan<- *Result) {
 	defer workers.Done()
-	for range ticks {
-		results <- a.hit(tr, name)
+
+	client, conns := a.workerClient()
+	defer conns.close()
+
+	for intended := range ticks {
+		conns.churn()
+		res := a.hitWith(client, tr, name)
+		res.Intended = intended
+		send(results, res)
+	}
+}
+
+// send sends the given Result of a hit followed by those of the WebSocket
+// messages it exchanged.
+func send(results chan<- *Result, res *Result) {
+	messages := res.messages
+	res.messages = nil
+
+	results <- res
+	for _, m := range messages {
+		results <- m
 	}
 }
 
+// workerClient returns the http.Client of a new worker, which has its own
+// cookie jar with WorkerCookies, and its own connections, if any, when it
+// churns them or has its own local address.
+func (a *Attacker) workerClient() (*http.Client, *workerConns) {
+	laddr := a.laddrs.worker()
+	own := a.conns.churns() || laddr != nil
+	if a.cookies != WorkerCookies && !own {
+		return &a.client, nil
+	}
+
+	c := a.client
+	if a.cookies == WorkerCookies {
+		c.Jar = newCookieJar(a.cookieSeed)
+	}
+
+	if !own {
+		return &c, nil
+	}
+	return &c, a.newWorkerConns(&c, laddr)
+}
+
+// hit hits the next Target of tr with the Attacker's client.
 func (a *Attacker) hit(tr Targeter, name string) *Result {
+	return a.hitWith(&a.client, tr, name)
+}
+
+func (a *Attacker) hitWith(client *http.Client, tr Targeter, name string) *Result {
 	var (
 		res = Result{Attack: name}
 		tgt Target
+		hdr http.Header
 		err error
 	)
 
@@ -326,43 +465,183 @@ func (a *Attacker) hit(tr Targeter, name string) *Result {
 	a.seq++
 	a.seqmu.Unlock()
 
-	defer func() {
+	if a.err != nil {
+		a.Stop()
 		res.Latency = time.Since(res.Timestamp)
+		res.Error = a.err.Error()
+		return &res
+	}
+
+	if err = tr.Next(&tgt); err != nil {
+		a.Stop()
+		res.Latency = time.Since(res.Timestamp)
+		res.Error = err.Error()
+		return &res
+	}
+
+	res.Tag, res.Method, res.URL = tgt.Tag, tgt.Method, tgt.URL
+
+	defer func() {
+		// WebSocket hits record the setup time of their connections.
+		if res.Latency == 0 {
+			res.Latency = time.Since(res.Timestamp)
+		}
 		if err != nil {
 			res.Error = err.Error()
+		} else if res.Code != 0 && res.Error == "" {
+			if aerr := a.assert(&tgt, hdr, &res); aerr != nil {
+				res.Error = aerr.Error()
+			}
 		}
+
+		feedback(tr, &Feedback{
+			Target:    &tgt,
+			Code:      res.Code,
+			Header:    hdr,
+			Body:      res.Body,
+			Latency:   res.Latency,
+			BytesIn:   res.BytesIn,
+			BytesOut:  res.BytesOut,
+			Err:       err,
+			ErrorKind: classify(err),
+		})
 	}()
 
-	if err = tr.Next(&tgt); err != nil {
-		a.Stop()
-		return &res
+	// Targets may override the client's settings, in which case a copy
+	// of it is used to leave the shared one untouched.
+	if tgt.Timeout > 0 || tgt.Redirects != nil {
+		c := *client
+		if tgt.Timeout > 0 {
+			c.Timeout = tgt.Timeout
+		}
+		if tgt.Redirects != nil {
+			c.CheckRedirect = checkRedirect(*tgt.Redirects)
+		}
+		client = &c
 	}
 
+	for {
+		if hdr, err = a.do(client, &tgt, &res); !a.retries.retry(int(res.Retries), res.Code, err) {
+			return &res
+		}
+
+		if err != nil {
+			res.RetryErrors = append(res.RetryErrors, err.Error())
+		} else {
+			res.RetryErrors = append(res.RetryErrors, res.Error)
+		}
+
+		timer := time.NewTimer(a.retries.backoff(int(res.Retries)))
+		select {
+		case <-timer.C:
+		case <-a.stopch:
+			timer.Stop()
+			return &res
+		}
+
+		res.Retries++
+	}
+}
+
+// do sends a request of tgt with the given client and records its response
+// in res, clearing that of previous attempts.
+func (a *Attacker) do(client *http.Client, tgt *Target, res *Result) (http.Header, error) {
+	res.Code, res.Error, res.Body, res.BytesIn, res.BytesOut = 0, "", nil, 0, 0
+	res.Proto, res.RemoteAddr, res.LocalAddr, res.Header, res.Timings = "", "", "", nil, nil
+	res.Latency, res.messages, res.Stream, res.Handshake = 0, nil, nil, 0
+
 	req, err := tgt.Request()
 	if err != nil {
-		return &res
+		return nil, &requestError{err}
 	}
 
-	r, err := a.client.Do(req)
-	if err != nil {
-		return &res
+	if a.auth != nil {
+		if err = a.auth.Authenticate(req); err != nil {
+			return nil, err
+		}
+	}
+
+	if isGRPC(req.URL) {
+		return a.doGRPC(client, req, tgt, res)
 	}
-	defer r.Body.Close()
 
-	body := io.Reader(r.Body)
-	if a.maxBody >= 0 {
-		body = io.LimitReader(r.Body, a.maxBody)
+	if a.trace || a.conns != nil {
+		trc := newTracer()
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), trc.clientTrace()))
+		defer func() {
+			t := trc.timings()
+			if a.trace {
+				res.Timings = t
+			}
+			if a.conns != nil {
+				res.Handshake = t.Connect + t.TLS
+			}
+		}()
+		defer trc.done()
 	}
 
-	res.Body, err = ioutil.ReadAll(body)
+	if a.metadata || a.laddrs != nil {
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+			GotConn: func(info httptrace.GotConnInfo) {
+				if a.metadata {
+					res.RemoteAddr = info.Conn.RemoteAddr().String()
+				}
+				res.LocalAddr = info.Conn.LocalAddr().String()
+			},
+		}))
+	}
+
+	if isWebSocket(req.URL) {
+		return a.doWebSocket(client, req, tgt, res)
+	}
+
+	var (
+		start     = time.Now()
+		firstByte time.Duration
+		timedOut  = func() bool { return false }
+		timeout   = client.Timeout
+	)
+
+	if a.stream {
+		req = req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+			GotFirstResponseByte: func() { firstByte = time.Since(start) },
+		}))
+		if a.streamHold > 0 {
+			var stop func()
+			client, req, timedOut, stop = withHeaderTimeout(client, req)
+			defer stop()
+		}
+	}
+
+	r, err := client.Do(req)
+	if timedOut() && err != nil {
+		err = &timeoutError{"stream: response headers", timeout}
+	}
 	if err != nil {
-		tr.Result(nil, 0, err)
-		return &res
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if a.metadata || a.http3 {
+		res.Proto = r.Proto
 	}
+	res.Header = a.responseHeaders(r.Header)
 
-	if _, err = io.Copy(ioutil.Discard, r.Body); err != nil {
-		tr.Result(nil, 0, err)
-		return &res
+	if a.stream {
+		if err = a.readStream(r, start, firstByte, res); err != nil {
+			return r.Header, err
+		}
+	} else {
+		body := io.Reader(r.Body)
+		if a.maxBody >= 0 {
+			body = io.LimitReader(r.Body, a.maxBody)
+		}
+
+		if res.Body, err = ioutil.ReadAll(body); err != nil {
+			return r.Header, err
+		} else if _, err = io.Copy(ioutil.Discard, r.Body); err != nil {
+			return r.Header, err
+		}
 	}
 
 	res.BytesIn = uint64(len(res.Body))
@@ -371,11 +650,100 @@ func (a *Attacker) hit(tr Targeter, name string) *Result {
 		res.BytesOut = uint64(req.ContentLength)
 	}
 
-	if res.Code = uint16(r.StatusCode); res.Code < 200 || res.Code >= 400 {
+	if res.Code = uint16(r.StatusCode); !tgt.Success(res.Code) {
 		res.Error = r.Status
 	}
 
-	tr.Result(res.Body, res.Code, nil)
+	return r.Header, nil
+}
+
+// withHeaderTimeout makes the timeout of client only apply until the response
+// headers of req are received, for responses whose bodies are read for longer,
+// by returning a copy of client without it and a copy of req whose context is
+// canceled after it. The first returned function must be called once the
+// headers are received and returns true if they timed out. The second one
+// releases the context of req and must be called once its response is read.
+func withHeaderTimeout(client *http.Client, req *http.Request) (*http.Client, *http.Request, func() bool, func()) {
+	if client.Timeout <= 0 {
+		return client, req, func() bool { return false }, func() {}
+	}
+
+	ctx, cancel := context.WithCancel(req.Context())
+	timer := time.AfterFunc(client.Timeout, cancel)
+
+	c := *client
+	c.Timeout = 0
+
+	return &c, req.WithContext(ctx), func() bool { return !timer.Stop() }, func() {
+		timer.Stop()
+		cancel()
+	}
+}
+
+// timeoutError is a net.Error of operations timed out by the Attacker
+// rather than by its client, so that they're classified as TimeoutErrors.
+type timeoutError struct {
+	op      string
+	timeout time.Duration
+}
+
+func (e *timeoutError) Error() string {
+	return fmt.Sprintf("%s timed out after %s", e.op, e.timeout)
+}
+
+func (e *timeoutError) Timeout() bool   { return true }
+func (e *timeoutError) Temporary() bool { return true }
+
+// responseHeaders returns the headers of h the Attacker records.
+func (a *Attacker) responseHeaders(h http.Header) http.Header {
+	if len(a.headers) == 0 || len(h) == 0 {
+		return nil
+	}
+
+	recorded := make(http.Header, len(a.headers))
+	for _, name := range a.headers {
+		if name == "*" {
+			for k, vs := range h {
+				recorded[k] = append([]string(nil), vs...)
+			}
+			break
+		}
+		if vs, ok := h[name]; ok {
+			recorded[name] = append([]string(nil), vs...)
+		}
+	}
+
+	if len(recorded) == 0 {
+		return nil
+	}
+
+	return recorded
+}
+
+// assert checks the response of a hit of tgt against the Attacker's and
+// the Target's assertions.
+func (a *Attacker) assert(tgt *Target, hdr http.Header, res *Result) error {
+	if len(a.assertions) == 0 && len(tgt.Assert) == 0 {
+		return nil
+	}
+
+	as := a.assertions
+	for _, expr := range tgt.Assert {
+		v, ok := a.parsed.Load(expr)
+		if !ok {
+			parsed, err := ParseAssertion(expr)
+			if err != nil {
+				return err
+			}
+			v, _ = a.parsed.LoadOrStore(expr, parsed)
+		}
+		as = append(as[:len(as):len(as)], v.(Assertion))
+	}
 
-	return &res
+	return assert(&assertee{
+		code:    res.Code,
+		header:  hdr,
+		body:    res.Body,
+		latency: res.Latency,
+	}, as)
 }
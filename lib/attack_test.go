@@ -3,6 +3,7 @@ package vegeta
 import (
 	"bytes"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -14,6 +15,8 @@ import (
 	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -80,7 +83,7 @@ func TestRedirects(t *testing.T) {
 	redirects := 2
 	atk := NewAttacker(Redirects(redirects))
 	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
-	res := atk.hit(tr, "")
+	res := atk.hit(tr.NewTargeter(), "")
 	want := fmt.Sprintf("stopped after %d redirects", redirects)
 	if got := res.Error; !strings.HasSuffix(got, want) {
 		t.Fatalf("want: '%v' in '%v'", want, got)
@@ -97,7 +100,7 @@ func TestNoFollow(t *testing.T) {
 	defer server.Close()
 	atk := NewAttacker(Redirects(NoFollow))
 	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
-	res := atk.hit(tr, "")
+	res := atk.hit(tr.NewTargeter(), "")
 	if res.Error != "" {
 		t.Fatalf("got err: %v", res.Error)
 	}
@@ -116,7 +119,7 @@ func TestTimeout(t *testing.T) {
 	defer server.Close()
 	atk := NewAttacker(Timeout(10 * time.Millisecond))
 	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
-	res := atk.hit(tr, "")
+	res := atk.hit(tr.NewTargeter(), "")
 
 	want := "Client.Timeout exceeded while awaiting headers"
 	if got := res.Error; !strings.Contains(got, want) {
@@ -128,6 +131,344 @@ func TestTimeout(t *testing.T) {
 	}
 }
 
+func TestTargetOverrides(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/slow":
+				<-time.After(20 * time.Millisecond)
+			case "/redirect":
+				http.Redirect(w, r, "/redirect", 302)
+			case "/missing":
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+	defer server.Close()
+
+	noFollow := NoFollow
+	atk := NewAttacker(Timeout(time.Second), Redirects(DefaultRedirects))
+	for _, tc := range []struct {
+		tgt  Target
+		code uint16
+		err  string
+	}{
+		{Target{URL: "/slow", Timeout: 10 * time.Millisecond}, 0, "Client.Timeout exceeded"},
+		{Target{URL: "/slow"}, 200, ""},
+		{Target{URL: "/redirect", Redirects: &noFollow}, 302, ""},
+		{Target{URL: "/redirect", Redirects: &noFollow, Expect: []uint16{200}}, 302, "302 Found"},
+		{Target{URL: "/missing", Expect: []uint16{404}, Tag: "missing"}, 404, ""},
+		{Target{URL: "/", Expect: []uint16{201}}, 200, "200 OK"},
+	} {
+		tc.tgt.Method, tc.tgt.URL = "GET", server.URL+tc.tgt.URL
+		res := atk.hit(NewStaticTargeter(tc.tgt).NewTargeter(), "")
+		if res.Code != tc.code {
+			t.Errorf("%s: got code %d, want %d", tc.tgt.URL, res.Code, tc.code)
+		}
+		if !strings.Contains(res.Error, tc.err) || (tc.err == "") != (res.Error == "") {
+			t.Errorf("%s: got error %q, want %q", tc.tgt.URL, res.Error, tc.err)
+		}
+		if res.Tag != tc.tgt.Tag {
+			t.Errorf("%s: got tag %q, want %q", tc.tgt.URL, res.Tag, tc.tgt.Tag)
+		}
+		var m Metrics
+		m.Add(res)
+		if success := m.success == 1; success != (tc.err == "") {
+			t.Errorf("%s: got success %t for error %q", tc.tgt.URL, success, res.Error)
+		}
+	}
+
+	if atk.client.Timeout != time.Second {
+		t.Errorf("the attacker's client timeout was modified: %s", atk.client.Timeout)
+	}
+}
+
+func TestAttackerAssertions(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"status": "error"}`))
+		}),
+	)
+	defer server.Close()
+
+	atk := NewAttacker(Assertions(MustParseAssertion("status:200")))
+	for _, tc := range []struct {
+		assert []string
+		err    string
+	}{
+		{nil, ""},
+		{[]string{"body-contains:error"}, ""},
+		{[]string{"jsonpath:$.status=ok"}, `assertion failed: $.status isn't "ok"`},
+	} {
+		tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL, Assert: tc.assert})
+		if res := atk.hit(tr.NewTargeter(), ""); res.Error != tc.err {
+			t.Errorf("%v: got error %q, want %q", tc.assert, res.Error, tc.err)
+		}
+	}
+
+	atk = NewAttacker(Assertions(MustParseAssertion("status:201")))
+	res := atk.hit(NewStaticTargeter(Target{Method: "GET", URL: server.URL}).NewTargeter(), "")
+	if want := "assertion failed: status 200 not in 201"; res.Error != want {
+		t.Errorf("got error %q, want %q", res.Error, want)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user, pass, ok := r.BasicAuth(); !ok || user != "goku" || pass != "kakarot" {
+				w.WriteHeader(http.StatusUnauthorized)
+			}
+		}),
+	)
+	defer server.Close()
+
+	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
+	if res := NewAttacker().hit(tr.NewTargeter(), ""); res.Code != http.StatusUnauthorized {
+		t.Fatalf("got code %d without auth, want %d", res.Code, http.StatusUnauthorized)
+	}
+
+	atk := NewAttacker(Auth(BasicAuth("goku", "kakarot")))
+	if res := atk.hit(tr.NewTargeter(), ""); res.Code != http.StatusOK {
+		t.Fatalf("got code %d, want %d", res.Code, http.StatusOK)
+	}
+
+	atk = NewAttacker(Auth(AuthenticatorFunc(func(*http.Request) error {
+		return errors.New("no credentials")
+	})))
+	if res := atk.hit(tr.NewTargeter(), ""); res.Error != "no credentials" {
+		t.Fatalf("got error %q, want %q", res.Error, "no credentials")
+	}
+}
+
+func TestIntendedTimes(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(20 * time.Millisecond)
+		}),
+	)
+	defer server.Close()
+
+	// A single worker can't keep up with the rate, so hits queue up. The
+	// short time unit makes the Pacer fall whole periods behind too.
+	atk := NewAttacker(Workers(1), MaxWorkers(1))
+	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
+	rate := Rate{Freq: 1, Per: 5 * time.Millisecond}
+
+	var (
+		delayed int
+		last    time.Duration
+	)
+	for r := range atk.Attack(tr, rate, 200*time.Millisecond, "") {
+		if r.Intended.IsZero() {
+			t.Fatal("got zero intended time")
+		} else if r.Intended.After(r.Timestamp) {
+			t.Fatalf("got intended time %s after the timestamp %s", r.Intended, r.Timestamp)
+		} else if r.CorrectedLatency() < r.Latency {
+			t.Fatalf("got corrected latency %s below the latency %s", r.CorrectedLatency(), r.Latency)
+		}
+
+		// The backlog grows by about 15ms with every hit, which the
+		// corrected latencies must keep counting.
+		if corrected := r.CorrectedLatency(); corrected <= last {
+			t.Fatalf("got corrected latency %s after %s, want it to keep growing", corrected, last)
+		} else {
+			last = corrected
+		}
+
+		if r.Timestamp.Sub(r.Intended) > 10*time.Millisecond {
+			delayed++
+		}
+	}
+
+	if delayed < 5 || last < 100*time.Millisecond {
+		t.Errorf("got %d delayed hits and a last corrected latency of %s, want a growing backlog", delayed, last)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("VEGETA"))
+		}),
+	)
+	defer server.Close()
+
+	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL}).NewTargeter()
+
+	if res := NewAttacker().hit(tr, ""); res.Timings != nil {
+		t.Fatalf("got timings %+v without tracing", res.Timings)
+	}
+
+	atk := NewAttacker(Trace(true))
+
+	first := atk.hit(tr, "")
+	if first.Error != "" {
+		t.Fatal(first.Error)
+	} else if tm := first.Timings; tm == nil {
+		t.Fatal("got nil timings")
+	} else if tm.Reused || tm.Connect <= 0 || tm.TLS <= 0 || tm.FirstByte <= 0 {
+		t.Errorf("got timings %+v, want those of a new TLS connection", tm)
+	} else if tm.FirstByte > first.Latency {
+		t.Errorf("got first byte %s after the latency %s", tm.FirstByte, first.Latency)
+	}
+
+	second := atk.hit(tr, "")
+	if tm := second.Timings; tm == nil {
+		t.Fatal("got nil timings")
+	} else if !tm.Reused || tm.Connect != 0 || tm.TLS != 0 || tm.FirstByte <= 0 {
+		t.Errorf("got timings %+v, want those of a reused connection", tm)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	t.Parallel()
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("X-Power", "9001")
+			w.Header().Set("X-Secret", "shh")
+		}),
+	)
+	defer server.Close()
+
+	tgt := Target{Method: "GET", URL: server.URL + "/metadata"}
+	tr := NewStaticTargeter(tgt).NewTargeter()
+
+	res := NewAttacker().hit(tr, "")
+	if res.Proto != "" || res.RemoteAddr != "" || res.Header != nil {
+		t.Fatalf("got metadata %+v without recording it", res)
+	} else if res.Method != tgt.Method || res.URL != tgt.URL {
+		t.Fatalf("got method %q and url %q, want them recorded without metadata", res.Method, res.URL)
+	}
+
+	atk := NewAttacker(Metadata(true), ResponseHeaders("x-power"))
+	res = atk.hit(tr, "")
+
+	for _, tc := range []struct{ field, got, want string }{
+		{"method", res.Method, tgt.Method},
+		{"url", res.URL, tgt.URL},
+		{"proto", res.Proto, "HTTP/1.1"},
+		{"remote address", res.RemoteAddr, server.Listener.Addr().String()},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("got %s %q, want %q", tc.field, tc.got, tc.want)
+		}
+	}
+
+	if want := (http.Header{"X-Power": {"9001"}}); !reflect.DeepEqual(res.Header, want) {
+		t.Errorf("got headers %v, want %v", res.Header, want)
+	}
+
+	res = NewAttacker(ResponseHeaders("*")).hit(tr, "")
+	if res.Header.Get("X-Secret") != "shh" || res.Header.Get("X-Power") != "9001" {
+		t.Errorf("got headers %v, want all of them", res.Header)
+	}
+}
+
+func TestRetries(t *testing.T) {
+	t.Parallel()
+
+	var (
+		mu   sync.Mutex
+		hits = map[string]int{}
+	)
+
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			mu.Lock()
+			hits[r.URL.Path]++
+			n := hits[r.URL.Path]
+			mu.Unlock()
+
+			switch {
+			case r.URL.Path == "/broken":
+				w.WriteHeader(http.StatusInternalServerError)
+			case n <= 2:
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}
+		}),
+	)
+	defer server.Close()
+
+	for _, tc := range []struct {
+		path    string
+		max     int
+		code    uint16
+		retries uint64
+	}{
+		{"/recovers", 3, 200, 2},
+		{"/exhausts", 1, 503, 1},
+		{"/broken", 3, 500, 0},
+	} {
+		atk := NewAttacker(Retries(RetryPolicy{Max: tc.max, Backoff: time.Millisecond}))
+		tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL + tc.path})
+		res := atk.hit(tr.NewTargeter(), "")
+
+		if res.Code != tc.code || res.Retries != tc.retries {
+			t.Errorf("%s: got code %d after %d retries, want %d after %d", tc.path, res.Code, res.Retries, tc.code, tc.retries)
+		}
+
+		if got, want := len(res.RetryErrors), int(tc.retries); got != want {
+			t.Errorf("%s: got %d retry errors, want %d", tc.path, got, want)
+		}
+
+		for _, e := range res.RetryErrors {
+			if e != "503 Service Unavailable" {
+				t.Errorf("%s: got retry error %q", tc.path, e)
+			}
+		}
+	}
+
+	atk := NewAttacker(Retries(RetryPolicy{Max: 3, On: []RetryCondition{RetryOnCodes(503)}}))
+	tr := NewStaticTargeter(Target{Method: "BAD METHOD", URL: server.URL})
+	if res := atk.hit(tr.NewTargeter(), ""); res.Retries != 0 || res.Error == "" {
+		t.Errorf("got %d retries of a bad request with error %q", res.Retries, res.Error)
+	}
+}
+
+func TestParseRetryCondition(t *testing.T) {
+	t.Parallel()
+
+	reset := &net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}
+
+	for _, tc := range []struct {
+		cond string
+		code uint16
+		err  error
+		want bool
+	}{
+		{"503", 503, nil, true},
+		{"503", 502, nil, false},
+		{"5xx", 599, nil, true},
+		{"5xx", 404, nil, false},
+		{"reset", 0, reset, true},
+		{"reset", 0, io.EOF, true},
+		{"timeout", 0, reset, false},
+		{"error", 0, reset, true},
+		{"error", 503, nil, false},
+	} {
+		on, err := ParseRetryCondition(tc.cond)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := on(tc.code, tc.err); got != tc.want {
+			t.Errorf("%s(%d, %v): got %v, want %v", tc.cond, tc.code, tc.err, got, tc.want)
+		}
+	}
+
+	for _, cond := range []string{"", "6xx", "99", "reboot"} {
+		if _, err := ParseRetryCondition(cond); err == nil {
+			t.Errorf("%q: got no error", cond)
+		}
+	}
+}
+
 func TestLocalAddr(t *testing.T) {
 	t.Parallel()
 	addr, err := net.ResolveIPAddr("ip", "127.0.0.1")
@@ -146,7 +487,7 @@ func TestLocalAddr(t *testing.T) {
 	defer server.Close()
 	atk := NewAttacker(LocalAddr(*addr))
 	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
-	atk.hit(tr, "")
+	atk.hit(tr.NewTargeter(), "")
 }
 
 func TestKeepAlive(t *testing.T) {
@@ -180,7 +521,7 @@ func TestStatusCodeErrors(t *testing.T) {
 	defer server.Close()
 	atk := NewAttacker()
 	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
-	res := atk.hit(tr, "")
+	res := atk.hit(tr.NewTargeter(), "")
 	if got, want := res.Error, "400 Bad Request"; got != want {
 		t.Fatalf("got: %v, want: %v", got, want)
 	}
@@ -189,13 +530,19 @@ func TestStatusCodeErrors(t *testing.T) {
 func TestBadTargeterError(t *testing.T) {
 	t.Parallel()
 	atk := NewAttacker()
-	tr := func(*Target) error { return io.EOF }
+	tr := targeterFunc(func(*Target) error { return io.EOF })
 	res := atk.hit(tr, "")
 	if got, want := res.Error, io.EOF.Error(); got != want {
 		t.Fatalf("got: %v, want: %v", got, want)
 	}
 }
 
+// targeterFunc adapts a plain function to the Targeter interface.
+type targeterFunc func(*Target) error
+
+func (f targeterFunc) Next(tgt *Target) error       { return f(tgt) }
+func (f targeterFunc) Result([]byte, uint16, error) {}
+
 func TestResponseBodyCapture(t *testing.T) {
 	t.Parallel()
 
@@ -208,7 +555,7 @@ func TestResponseBodyCapture(t *testing.T) {
 	defer server.Close()
 	atk := NewAttacker()
 	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
-	res := atk.hit(tr, "")
+	res := atk.hit(tr.NewTargeter(), "")
 	if got := res.Body; !bytes.Equal(got, want) {
 		t.Fatalf("got: %v, want: %v", got, want)
 	}
@@ -235,7 +582,7 @@ func TestProxyOption(t *testing.T) {
 	}))
 
 	tr := NewStaticTargeter(Target{Method: "GET", URL: "http://127.0.0.2"})
-	res := atk.hit(tr, "")
+	res := atk.hit(tr.NewTargeter(), "")
 	if got, want := res.Error, ""; got != want {
 		t.Errorf("got error: %q, want %q", got, want)
 	}
@@ -261,7 +608,7 @@ func TestMaxBody(t *testing.T) {
 		t.Run(fmt.Sprint(maxBody), func(t *testing.T) {
 			atk := NewAttacker(MaxBody(maxBody))
 			tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
-			res := atk.hit(tr, "")
+			res := atk.hit(tr.NewTargeter(), "")
 
 			want := body
 			if maxBody >= 0 {
@@ -319,7 +666,7 @@ func TestUnixSocket(t *testing.T) {
 	atk := NewAttacker(UnixSocket(socketFile))
 
 	tr := NewStaticTargeter(Target{Method: "GET", URL: "http://anyserver/"})
-	res := atk.hit(tr, "")
+	res := atk.hit(tr.NewTargeter(), "")
 	if !bytes.Equal(res.Body, body) {
 		t.Fatalf("got: %s, want: %s", string(res.Body), string(body))
 	}
@@ -353,8 +700,108 @@ func TestClient(t *testing.T) {
 	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
 
 	atk := NewAttacker(Client(client))
-	resp := atk.hit(tr, "TEST")
+	resp := atk.hit(tr.NewTargeter(), "TEST")
 	if !strings.Contains(resp.Error, "Client.Timeout exceeded while awaiting headers") {
 		t.Errorf("Expected timeout error")
 	}
 }
+
+// feedbackRecorder is a FeedbackTargeter which records the Feedback it gets.
+type feedbackRecorder struct {
+	Targeter
+	fbs []*Feedback
+}
+
+func (r *feedbackRecorder) Feedback(fb *Feedback) { r.fbs = append(r.fbs, fb) }
+
+// resultRecorder is a Targeter which records the errors passed to Result.
+type resultRecorder struct {
+	Targeter
+	errs []error
+}
+
+func (r *resultRecorder) Result(_ []byte, _ uint16, err error) { r.errs = append(r.errs, err) }
+
+func TestFeedback(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/slow" {
+				time.Sleep(50 * time.Millisecond)
+			}
+			w.Header().Set("X-Power", "9001")
+			w.WriteHeader(http.StatusTeapot)
+			w.Write([]byte("VEGETA"))
+		}),
+	)
+	defer server.Close()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	for _, tc := range []struct {
+		name string
+		tgt  Target
+		code uint16
+		body string
+		kind ErrorKind
+	}{
+		{"ok", Target{Method: "GET", URL: server.URL}, 418, "VEGETA", NoError},
+		{"timeout", Target{Method: "GET", URL: server.URL + "/slow"}, 0, "", TimeoutError},
+		{"refused", Target{Method: "GET", URL: closed.URL}, 0, "", ConnectionRefusedError},
+		{"request", Target{Method: "BAD METHOD", URL: server.URL}, 0, "", RequestError},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			atk := NewAttacker(Timeout(20 * time.Millisecond))
+			tr := &feedbackRecorder{Targeter: NewStaticTargeter(tc.tgt).NewTargeter()}
+			res := atk.hit(tr, "")
+
+			if len(tr.fbs) != 1 {
+				t.Fatalf("got %d feedbacks, want 1", len(tr.fbs))
+			}
+
+			fb := tr.fbs[0]
+			if !fb.Target.Equal(&tc.tgt) {
+				t.Errorf("got target %v, want %v", fb.Target, tc.tgt)
+			}
+
+			if got, want := fb.ErrorKind, tc.kind; got != want {
+				t.Errorf("got error kind %q (%v), want %q", got, fb.Err, want)
+			}
+
+			if got, want := fb.Code, tc.code; got != want {
+				t.Errorf("got code %d, want %d", got, want)
+			}
+
+			if got, want := string(fb.Body), tc.body; got != want {
+				t.Errorf("got body %q, want %q", got, want)
+			}
+
+			if fb.Latency != res.Latency || fb.BytesIn != res.BytesIn {
+				t.Errorf("feedback %+v doesn't match result %+v", fb, res)
+			}
+
+			if tc.kind == NoError && fb.Header.Get("X-Power") != "9001" {
+				t.Errorf("got headers %v, want X-Power", fb.Header)
+			} else if tc.kind != NoError && res.Error != fb.Err.Error() {
+				t.Errorf("got result error %q, want %q", res.Error, fb.Err)
+			}
+		})
+	}
+}
+
+func TestFeedbackResultAdapter(t *testing.T) {
+	t.Parallel()
+
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	atk := NewAttacker()
+	tr := &resultRecorder{Targeter: NewStaticTargeter(Target{Method: "GET", URL: closed.URL}).NewTargeter()}
+	atk.hit(tr, "")
+
+	if len(tr.errs) != 1 || tr.errs[0] == nil {
+		t.Fatalf("got Result errors %v, want one transport error", tr.errs)
+	}
+}
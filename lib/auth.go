@@ -0,0 +1,502 @@
+package vegeta
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// An Authenticator authenticates requests right before they're sent,
+// e.g. by setting their Authorization header.
+type Authenticator interface {
+	Authenticate(*http.Request) error
+}
+
+// AuthenticatorFunc is an adapter to allow the use of ordinary functions
+// as Authenticators.
+type AuthenticatorFunc func(*http.Request) error
+
+// Authenticate calls f(r).
+func (f AuthenticatorFunc) Authenticate(r *http.Request) error { return f(r) }
+
+// BasicAuth returns an Authenticator which sets the HTTP Basic Authentication
+// credentials of requests.
+func BasicAuth(user, password string) Authenticator {
+	return AuthenticatorFunc(func(r *http.Request) error {
+		r.SetBasicAuth(user, password)
+		return nil
+	})
+}
+
+// OAuth2Config configures the OAuth2 client credentials grant of
+// NewOAuth2Authenticator.
+type OAuth2Config struct {
+	// TokenURL is the URL of the token endpoint of the authorization server.
+	TokenURL string
+	// ClientID and ClientSecret are the credentials of the client, which
+	// are sent with HTTP Basic Authentication.
+	ClientID     string
+	ClientSecret string
+	// Scopes are the requested scopes. Optional.
+	Scopes []string
+	// RefreshBefore is how long before their expiry tokens are refreshed.
+	// It defaults to 10 seconds.
+	RefreshBefore time.Duration
+	// Client is the http.Client used to fetch tokens. It defaults to one
+	// with a 30 seconds timeout.
+	Client *http.Client
+}
+
+// NewOAuth2Authenticator returns an Authenticator which sets the bearer
+// token fetched from an OAuth2 authorization server with the client
+// credentials grant. The first token is fetched right away, so that bad
+// credentials fail early, and later ones in the background once requests
+// are authenticated within RefreshBefore of the expiry of the current one,
+// so that fetching them never delays requests. Failed fetches are retried
+// with an exponential backoff while the current token is still valid.
+// Requests fail once it expired.
+func NewOAuth2Authenticator(c OAuth2Config) (Authenticator, error) {
+	if c.RefreshBefore == 0 {
+		c.RefreshBefore = 10 * time.Second
+	}
+
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: DefaultTimeout}
+	}
+
+	a := &oauth2Authenticator{config: c, now: time.Now}
+
+	var err error
+	if a.token, a.expires, err = a.fetch(); err != nil {
+		return nil, fmt.Errorf("oauth2: %s", err)
+	}
+
+	return a, nil
+}
+
+// Bounds of the backoff between failed OAuth2 token fetches.
+const (
+	oauth2MinBackoff = time.Second
+	oauth2MaxBackoff = time.Minute
+)
+
+type oauth2Authenticator struct {
+	config OAuth2Config
+	now    func() time.Time
+
+	mu         sync.Mutex
+	token      string
+	expires    time.Time // zero if the token doesn't expire
+	refreshing bool      // whether a token is being fetched
+	failures   int       // number of failed fetches in a row
+	retry      time.Time // earliest time of the fetch after a failed one
+	err        error     // error of the last failed fetch
+}
+
+func (a *oauth2Authenticator) Authenticate(r *http.Request) error {
+	a.mu.Lock()
+	now, token, err := a.now(), a.token, a.err
+	expired := !a.expires.IsZero() && !now.Before(a.expires)
+	if !a.expires.IsZero() && !now.Before(a.expires.Add(-a.config.RefreshBefore)) &&
+		!a.refreshing && !now.Before(a.retry) {
+		a.refreshing = true
+		go a.refresh()
+	}
+	a.mu.Unlock()
+
+	if expired && err != nil {
+		return fmt.Errorf("oauth2: token expired: %s", err)
+	} else if expired {
+		return errors.New("oauth2: token expired")
+	}
+
+	r.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
+
+// refresh fetches a new token, backing off further fetches if it fails.
+func (a *oauth2Authenticator) refresh() {
+	token, expires, err := a.fetch()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.refreshing = false; err != nil {
+		backoff := oauth2MaxBackoff
+		if a.failures < 6 {
+			backoff = oauth2MinBackoff << uint(a.failures)
+		}
+		a.failures++
+		a.retry, a.err = a.now().Add(backoff), err
+		return
+	}
+
+	a.token, a.expires = token, expires
+	a.failures, a.retry, a.err = 0, time.Time{}, nil
+}
+
+// fetch fetches a new token and returns it with its expiry, which is zero if
+// it doesn't expire.
+func (a *oauth2Authenticator) fetch() (token string, expires time.Time, err error) {
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if len(a.config.Scopes) > 0 {
+		form.Set("scope", strings.Join(a.config.Scopes, " "))
+	}
+
+	req, err := http.NewRequest("POST", a.config.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	req.SetBasicAuth(url.QueryEscape(a.config.ClientID), url.QueryEscape(a.config.ClientSecret))
+
+	issued := a.now()
+	res, err := a.config.Client.Do(req)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer res.Body.Close()
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", time.Time{}, err
+	} else if res.StatusCode < 200 || res.StatusCode > 299 {
+		return "", time.Time{}, fmt.Errorf("token endpoint returned %s: %s", res.Status, bytes.TrimSpace(body))
+	}
+
+	var tok struct {
+		AccessToken string      `json:"access_token"`
+		TokenType   string      `json:"token_type"`
+		ExpiresIn   json.Number `json:"expires_in"`
+	}
+
+	if err = json.Unmarshal(body, &tok); err != nil {
+		return "", time.Time{}, fmt.Errorf("bad token response: %s", err)
+	} else if tok.AccessToken == "" {
+		return "", time.Time{}, errors.New("bad token response: missing access_token")
+	} else if tok.TokenType != "" && !strings.EqualFold(tok.TokenType, "bearer") {
+		return "", time.Time{}, fmt.Errorf("unsupported token type: %s", tok.TokenType)
+	}
+
+	if secs, err := tok.ExpiresIn.Int64(); err == nil && secs > 0 {
+		expires = issued.Add(time.Duration(secs) * time.Second)
+	}
+
+	return tok.AccessToken, expires, nil
+}
+
+// Supported JWT signing algorithms.
+const (
+	// HS256 is HMAC with SHA-256.
+	HS256 = "HS256"
+	// RS256 is RSASSA-PKCS1-v1_5 with SHA-256.
+	RS256 = "RS256"
+)
+
+// JWTConfig configures the tokens minted by NewJWTAuthenticator.
+type JWTConfig struct {
+	// Algorithm is the signing algorithm, either HS256 or RS256.
+	Algorithm string
+	// Key is the HMAC secret of HS256 or the PEM encoded RSA private key
+	// of RS256, in PKCS #1 or PKCS #8 form.
+	Key []byte
+	// Claims are the claims of the minted tokens.
+	Claims map[string]interface{}
+	// TTL is the lifetime of minted tokens, which set the iat and exp
+	// claims accordingly. Tokens don't expire if it's zero.
+	TTL time.Duration
+}
+
+// NewJWTAuthenticator returns an Authenticator which sets a bearer JSON Web
+// Token minted and signed locally with the given configuration. Expiring
+// tokens are minted again once half of their lifetime elapsed.
+func NewJWTAuthenticator(c JWTConfig) (Authenticator, error) {
+	a := &jwtAuthenticator{config: c, now: time.Now}
+
+	switch c.Algorithm {
+	case HS256:
+		if len(c.Key) == 0 {
+			return nil, errors.New("jwt: missing HS256 key")
+		}
+		a.sign = func(data []byte) ([]byte, error) {
+			mac := hmac.New(sha256.New, c.Key)
+			mac.Write(data)
+			return mac.Sum(nil), nil
+		}
+	case RS256:
+		key, err := parseRSAPrivateKey(c.Key)
+		if err != nil {
+			return nil, fmt.Errorf("jwt: %s", err)
+		}
+		a.sign = func(data []byte) ([]byte, error) {
+			sum := sha256.Sum256(data)
+			return rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, sum[:])
+		}
+	default:
+		return nil, fmt.Errorf("jwt: unsupported algorithm: %s", c.Algorithm)
+	}
+
+	return a, nil
+}
+
+type jwtAuthenticator struct {
+	config JWTConfig
+	now    func() time.Time
+	sign   func([]byte) ([]byte, error)
+
+	mu     sync.Mutex
+	token  string
+	minted time.Time
+}
+
+func (a *jwtAuthenticator) Authenticate(r *http.Request) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if now := a.now(); a.token == "" || (a.config.TTL > 0 && now.Sub(a.minted) >= a.config.TTL/2) {
+		tok, err := a.mint(now)
+		if err != nil {
+			return fmt.Errorf("jwt: %s", err)
+		}
+		a.token, a.minted = tok, now
+	}
+
+	r.Header.Set("Authorization", "Bearer "+a.token)
+	return nil
+}
+
+func (a *jwtAuthenticator) mint(now time.Time) (string, error) {
+	claims := make(map[string]interface{}, len(a.config.Claims)+2)
+	for k, v := range a.config.Claims {
+		claims[k] = v
+	}
+
+	if a.config.TTL > 0 {
+		claims["iat"] = now.Unix()
+		claims["exp"] = now.Add(a.config.TTL).Unix()
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": a.config.Algorithm, "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	data := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+
+	sig, err := a.sign([]byte(data))
+	if err != nil {
+		return "", err
+	}
+
+	return data + "." + enc.EncodeToString(sig), nil
+}
+
+func parseRSAPrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("bad RS256 key: no PEM data found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("bad RS256 key: %s", err)
+	}
+
+	rsaKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("bad RS256 key: not an RSA key")
+	}
+
+	return rsaKey, nil
+}
+
+// SigV4Config configures the AWS Signature Version 4 signing of
+// NewSigV4Authenticator.
+type SigV4Config struct {
+	// Region is the AWS region of the requests, e.g. us-east-1.
+	Region string
+	// Service is the signing name of the AWS service, e.g. execute-api.
+	Service string
+	// AccessKeyID and SecretAccessKey are the AWS credentials.
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is the session token of temporary credentials. Optional.
+	SessionToken string
+}
+
+// NewSigV4Authenticator returns an Authenticator which signs requests with
+// AWS Signature Version 4. It signs the Host, Content-Type and X-Amz-*
+// headers of requests.
+func NewSigV4Authenticator(c SigV4Config) Authenticator {
+	return &sigV4Authenticator{config: c, now: time.Now}
+}
+
+type sigV4Authenticator struct {
+	config SigV4Config
+	now    func() time.Time
+}
+
+const sigV4Algorithm = "AWS4-HMAC-SHA256"
+
+func (a *sigV4Authenticator) Authenticate(r *http.Request) error {
+	body := []byte{}
+	if r.GetBody != nil {
+		rc, err := r.GetBody()
+		if err != nil {
+			return fmt.Errorf("sigv4: %s", err)
+		}
+		defer rc.Close()
+		if body, err = ioutil.ReadAll(rc); err != nil {
+			return fmt.Errorf("sigv4: %s", err)
+		}
+	}
+
+	now := a.now().UTC()
+	date, day := now.Format("20060102T150405Z"), now.Format("20060102")
+	payload := sha256Hex(body)
+
+	r.Header.Set("X-Amz-Date", date)
+	if a.config.SessionToken != "" {
+		r.Header.Set("X-Amz-Security-Token", a.config.SessionToken)
+	}
+	if a.config.Service == "s3" {
+		r.Header.Set("X-Amz-Content-Sha256", payload)
+	}
+
+	host := r.Host
+	if host == "" {
+		host = r.URL.Host
+	}
+
+	headers := map[string]string{"host": host}
+	for k, vs := range r.Header {
+		name := strings.ToLower(k)
+		if name != "content-type" && !strings.HasPrefix(name, "x-amz-") {
+			continue
+		}
+		vals := make([]string, len(vs))
+		for i, v := range vs {
+			vals[i] = strings.Join(strings.Fields(v), " ")
+		}
+		headers[name] = strings.Join(vals, ",")
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var canonical bytes.Buffer
+	path := r.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	if a.config.Service != "s3" {
+		// Every service but S3 expects paths to be escaped twice.
+		segments := strings.Split(path, "/")
+		for i, s := range segments {
+			segments[i] = sigV4Escape(s)
+		}
+		path = strings.Join(segments, "/")
+	}
+
+	fmt.Fprintf(&canonical, "%s\n%s\n%s\n", r.Method, path, sigV4Query(r.URL.Query()))
+	for _, name := range names {
+		fmt.Fprintf(&canonical, "%s:%s\n", name, headers[name])
+	}
+
+	signed := strings.Join(names, ";")
+	fmt.Fprintf(&canonical, "\n%s\n%s", signed, payload)
+
+	scope := strings.Join([]string{day, a.config.Region, a.config.Service, "aws4_request"}, "/")
+	toSign := strings.Join([]string{sigV4Algorithm, date, scope, sha256Hex(canonical.Bytes())}, "\n")
+
+	key := []byte("AWS4" + a.config.SecretAccessKey)
+	for _, s := range []string{day, a.config.Region, a.config.Service, "aws4_request"} {
+		key = hmacSHA256(key, s)
+	}
+
+	r.Header.Set("Authorization", fmt.Sprintf("%s Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		sigV4Algorithm, a.config.AccessKeyID, scope, signed, hex.EncodeToString(hmacSHA256(key, toSign))))
+
+	return nil
+}
+
+// sigV4Query returns the canonical form of the given query parameters.
+func sigV4Query(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	escaped := make(map[string][]string, len(q))
+	for k, vs := range q {
+		ek := sigV4Escape(k)
+		keys = append(keys, ek)
+		for _, v := range vs {
+			escaped[ek] = append(escaped[ek], sigV4Escape(v))
+		}
+		sort.Strings(escaped[ek])
+	}
+	sort.Strings(keys)
+
+	params := make([]string, 0, len(q))
+	for _, k := range keys {
+		for _, v := range escaped[k] {
+			params = append(params, k+"="+v)
+		}
+	}
+	return strings.Join(params, "&")
+}
+
+// sigV4Escape percent encodes all but the unreserved characters of RFC 3986.
+func sigV4Escape(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,287 @@
+package vegeta
+
+import (
+	"crypto"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestBasicAuth(t *testing.T) {
+	t.Parallel()
+
+	req, _ := http.NewRequest("GET", "http://goku", nil)
+	if err := BasicAuth("goku", "kakarot").Authenticate(req); err != nil {
+		t.Fatal(err)
+	} else if user, pass, ok := req.BasicAuth(); !ok || user != "goku" || pass != "kakarot" {
+		t.Fatalf("got credentials %q:%q", user, pass)
+	}
+}
+
+func TestOAuth2Authenticator(t *testing.T) {
+	t.Parallel()
+
+	var fetches, tokens, failing int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&fetches, 1)
+		if id, secret, _ := r.BasicAuth(); id != "vegeta" || secret != "s3cr3t" {
+			http.Error(w, `{"error":"invalid_client"}`, http.StatusUnauthorized)
+			return
+		}
+
+		if r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "read write" {
+			http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+			return
+		}
+
+		if atomic.LoadInt32(&failing) == 1 {
+			http.Error(w, `{"error":"temporarily_unavailable"}`, http.StatusServiceUnavailable)
+			return
+		}
+
+		n := atomic.AddInt32(&tokens, 1)
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"bearer","expires_in":60}`, n)
+	}))
+	defer server.Close()
+
+	// The clock is read by background fetches too.
+	var now int64
+	setNow := func(d time.Duration) { atomic.StoreInt64(&now, int64(d)) }
+
+	au, err := NewOAuth2Authenticator(OAuth2Config{
+		TokenURL:     server.URL,
+		ClientID:     "vegeta",
+		ClientSecret: "s3cr3t",
+		Scopes:       []string{"read", "write"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	oa := au.(*oauth2Authenticator)
+	oa.mu.Lock()
+	oa.now = func() time.Time { return time.Unix(0, atomic.LoadInt64(&now)) }
+	oa.expires = time.Unix(60, 0)
+	oa.mu.Unlock()
+
+	authenticate := func() (token, err string) {
+		req, _ := http.NewRequest("GET", "http://goku", nil)
+		if e := au.Authenticate(req); e != nil {
+			return "", e.Error()
+		}
+		return strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer "), ""
+	}
+
+	// eventually waits for the background fetches to settle down to the
+	// given number of fetches.
+	eventually := func(want int32) {
+		t.Helper()
+		for deadline := time.Now().Add(time.Second); ; time.Sleep(time.Millisecond) {
+			oa.mu.Lock()
+			refreshing := oa.refreshing
+			oa.mu.Unlock()
+
+			if got := atomic.LoadInt32(&fetches); got == want && !refreshing {
+				return
+			} else if time.Now().After(deadline) {
+				t.Fatalf("got %d token fetches, want %d", got, want)
+			}
+		}
+	}
+
+	for _, tc := range []struct {
+		elapsed time.Duration
+		failing int32
+		token   string
+		err     string
+		fetches int32
+	}{
+		// The first token was fetched upfront.
+		{0, 0, "token-1", "", 1},
+		{49 * time.Second, 0, "token-1", "", 1},
+		// Tokens are refreshed in the background, without blocking.
+		{51 * time.Second, 0, "token-1", "", 2},
+		{52 * time.Second, 0, "token-2", "", 2},
+		// Failed fetches are backed off while the token is still valid.
+		{105 * time.Second, 1, "token-2", "", 3},
+		{105500 * time.Millisecond, 1, "token-2", "", 3},
+		{106500 * time.Millisecond, 1, "token-2", "", 4},
+		{107 * time.Second, 1, "token-2", "", 4},
+		// Expired tokens fail requests.
+		{112 * time.Second, 1, "", "oauth2: token expired: token endpoint returned 503 Service Unavailable: {\"error\":\"temporarily_unavailable\"}", 5},
+		{116 * time.Second, 0, "", "oauth2: token expired: token endpoint returned 503 Service Unavailable: {\"error\":\"temporarily_unavailable\"}", 6},
+		{117 * time.Second, 0, "token-3", "", 6},
+	} {
+		setNow(tc.elapsed)
+		atomic.StoreInt32(&failing, tc.failing)
+
+		if token, err := authenticate(); token != tc.token || err != tc.err {
+			t.Errorf("after %s: got token %q and error %q, want %q and %q", tc.elapsed, token, err, tc.token, tc.err)
+		}
+		eventually(tc.fetches)
+	}
+
+	_, err = NewOAuth2Authenticator(OAuth2Config{TokenURL: server.URL, ClientID: "goku"})
+	want := `oauth2: token endpoint returned 401 Unauthorized: {"error":"invalid_client"}`
+	if err == nil || err.Error() != want {
+		t.Fatalf("got error %v, want %q", err, want)
+	}
+}
+
+func TestJWTAuthenticator(t *testing.T) {
+	t.Parallel()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pemKey := pem.EncodeToMemory(&pem.Block{
+		Type:  "RSA PRIVATE KEY",
+		Bytes: x509.MarshalPKCS1PrivateKey(rsaKey),
+	})
+
+	for _, tc := range []struct {
+		alg    string
+		key    []byte
+		verify func(data, sig []byte) error
+	}{
+		{HS256, []byte("s3cr3t"), func(data, sig []byte) error {
+			mac := hmac.New(sha256.New, []byte("s3cr3t"))
+			mac.Write(data)
+			if !hmac.Equal(sig, mac.Sum(nil)) {
+				return fmt.Errorf("bad signature")
+			}
+			return nil
+		}},
+		{RS256, pemKey, func(data, sig []byte) error {
+			sum := sha256.Sum256(data)
+			return rsa.VerifyPKCS1v15(&rsaKey.PublicKey, crypto.SHA256, sum[:], sig)
+		}},
+	} {
+		au, err := NewJWTAuthenticator(JWTConfig{
+			Algorithm: tc.alg,
+			Key:       tc.key,
+			Claims:    map[string]interface{}{"sub": "goku"},
+			TTL:       time.Minute,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		now := time.Unix(1500000000, 0)
+		au.(*jwtAuthenticator).now = func() time.Time { return now }
+
+		token := func() string {
+			req, _ := http.NewRequest("GET", "http://goku", nil)
+			if err := au.Authenticate(req); err != nil {
+				t.Fatal(err)
+			}
+			return strings.TrimPrefix(req.Header.Get("Authorization"), "Bearer ")
+		}
+
+		tok := token()
+		parts := strings.Split(tok, ".")
+		if len(parts) != 3 {
+			t.Fatalf("%s: bad token %q", tc.alg, tok)
+		}
+
+		sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		if err := tc.verify([]byte(parts[0]+"."+parts[1]), sig); err != nil {
+			t.Errorf("%s: %s", tc.alg, err)
+		}
+
+		var header, claims map[string]interface{}
+		for i, v := range []*map[string]interface{}{&header, &claims} {
+			bs, _ := base64.RawURLEncoding.DecodeString(parts[i])
+			if err := json.Unmarshal(bs, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if header["alg"] != tc.alg || header["typ"] != "JWT" {
+			t.Errorf("%s: got header %v", tc.alg, header)
+		}
+
+		if claims["sub"] != "goku" || claims["iat"] != 1500000000.0 || claims["exp"] != 1500000060.0 {
+			t.Errorf("%s: got claims %v", tc.alg, claims)
+		}
+
+		if now = now.Add(29 * time.Second); token() != tok {
+			t.Errorf("%s: token minted before half its lifetime", tc.alg)
+		}
+
+		if now = now.Add(time.Second); token() == tok {
+			t.Errorf("%s: token not minted after half its lifetime", tc.alg)
+		}
+	}
+
+	for _, c := range []JWTConfig{
+		{Algorithm: "none"},
+		{Algorithm: HS256},
+		{Algorithm: RS256, Key: []byte("garbage")},
+	} {
+		if _, err := NewJWTAuthenticator(c); err == nil {
+			t.Errorf("%+v: got nil error", c)
+		}
+	}
+}
+
+func TestSigV4Authenticator(t *testing.T) {
+	t.Parallel()
+
+	// Test cases from the AWS Signature Version 4 test suite.
+	for _, tc := range []struct {
+		name string
+		req  *http.Request
+		sig  string
+	}{
+		{
+			name: "get-vanilla",
+			req:  httptest.NewRequest("GET", "http://example.amazonaws.com/", nil),
+			sig:  "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name: "get-vanilla-query-order-key-case",
+			req:  httptest.NewRequest("GET", "http://example.amazonaws.com/?Param2=value2&Param1=value1", nil),
+			sig:  "b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+	} {
+		tc.req.Header = http.Header{}
+		au := NewSigV4Authenticator(SigV4Config{
+			Region:          "us-east-1",
+			Service:         "service",
+			AccessKeyID:     "AKIDEXAMPLE",
+			SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY",
+		})
+		au.(*sigV4Authenticator).now = func() time.Time {
+			return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+		}
+
+		if err := au.Authenticate(tc.req); err != nil {
+			t.Fatal(err)
+		}
+
+		want := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+			"SignedHeaders=host;x-amz-date, Signature=" + tc.sig
+		if got := tc.req.Header.Get("Authorization"); got != want {
+			t.Errorf("%s: got %q, want %q", tc.name, got, want)
+		}
+
+		if got, want := tc.req.Header.Get("X-Amz-Date"), "20150830T123600Z"; got != want {
+			t.Errorf("%s: got date %q, want %q", tc.name, got, want)
+		}
+	}
+}
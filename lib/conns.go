@@ -0,0 +1,305 @@
+package vegeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// ConnectionPolicy sets when and how fast an Attacker opens new connections,
+// which is useful to load TLS handshakes and the connection tracking of load
+// balancers rather than requests alone.
+type ConnectionPolicy struct {
+	// Requests is the number of requests each worker sends over its
+	// connections before opening new ones. Zero reuses them indefinitely.
+	Requests uint64
+	// Lifetime is how long each worker reuses its connections before
+	// opening new ones. Zero reuses them indefinitely.
+	Lifetime time.Duration
+	// MaxOpen is the maximum number of connections open at once, beyond
+	// which new ones wait for others to close. Idle connections count as
+	// open, but are closed to make room for new ones once the cap is reached.
+	// Zero doesn't cap them.
+	MaxOpen int
+	// Pacer paces the opening of connections independently from the
+	// requests, with new ones waiting for their turn. Nil doesn't pace them.
+	Pacer Pacer
+}
+
+// churns returns true if the policy makes workers open new connections.
+func (p *ConnectionPolicy) churns() bool {
+	return p != nil && (p.Requests > 0 || p.Lifetime > 0)
+}
+
+// NewConnections returns a functional option which sets when and how fast an
+// Attacker opens new connections and makes it record the handshake of every
+// hit which opened one in the Handshake of its Result. A zero ConnectionPolicy
+// leaves connections as they are. Workers which open new connections after
+// some requests or time have connections of their own, rather than sharing
+// those of the Attacker. Only HTTP/1.1 and HTTP/2 over TLS connections of an
+// *http.Transport are supported, so its attacks fail otherwise, e.g. with H2C
+// or HTTP3, as reported by the Err method of the Attacker. gRPC Targets share
+// a connection per server among all workers, which MaxOpen and Pacer gate,
+// but which is never renewed after Requests or Lifetime.
+func NewConnections(p ConnectionPolicy) func(*Attacker) {
+	return func(a *Attacker) {
+		if p.Requests == 0 && p.Lifetime == 0 && p.MaxOpen == 0 && p.Pacer == nil {
+			a.conns = nil
+		} else {
+			a.conns = &p
+		}
+	}
+}
+
+// wireConns wires the connection gate of the ConnectionPolicy of the
+// Attacker, if any, into the dials of its transport.
+func (a *Attacker) wireConns() error {
+	if a.conns == nil {
+		return nil
+	}
+
+	tr, ok := a.client.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("connection policies require an *http.Transport, not %T", a.client.Transport)
+	}
+
+	if p := a.conns; p.MaxOpen > 0 || p.Pacer != nil {
+		a.gate = &connGate{pacer: p.Pacer, idle: map[idleCloser]struct{}{tr: {}}}
+		if p.MaxOpen > 0 {
+			a.gate.open = make(chan struct{}, p.MaxOpen)
+		}
+		tr.DialContext = a.gate.dial(tr.DialContext)
+	}
+
+	return nil
+}
+
+// idleCloser is a transport which can close its idle connections.
+type idleCloser interface {
+	CloseIdleConnections()
+}
+
+// errConnPacerStopped is returned by dials once the connection Pacer stops.
+var errConnPacerStopped = errors.New("connection pacer stopped")
+
+// connGate caps and paces the dials of connections.
+type connGate struct {
+	open  chan struct{} // a slot per open connection, if capped
+	pacer Pacer
+	mu    sync.Mutex
+	began time.Time
+	dials uint64
+	idle  map[idleCloser]struct{} // transports of the gated connections
+}
+
+// dial returns a dial function which dials with the given one once the
+// gate lets it through.
+func (g *connGate) dial(dial func(context.Context, string, string) (net.Conn, error)) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if g.open != nil {
+			select {
+			case g.open <- struct{}{}:
+			default:
+				// Idle connections hold slots too, which connections to
+				// other hosts may need, so make room by closing them.
+				g.closeIdle()
+				select {
+				case g.open <- struct{}{}:
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+		}
+
+		release := func() {
+			if g.open != nil {
+				<-g.open
+			}
+		}
+
+		if err := g.wait(ctx); err != nil {
+			release()
+			return nil, err
+		}
+
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			release()
+			return nil, err
+		}
+
+		return &gatedConn{Conn: conn, release: release}, nil
+	}
+}
+
+// track makes the gate close the idle connections of the given transport
+// when it runs out of slots, until untracked.
+func (g *connGate) track(t idleCloser) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.idle == nil {
+		g.idle = map[idleCloser]struct{}{}
+	}
+	g.idle[t] = struct{}{}
+}
+
+func (g *connGate) untrack(t idleCloser) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.idle, t)
+}
+
+// closeIdle closes the idle connections of the tracked transports.
+func (g *connGate) closeIdle() {
+	g.mu.Lock()
+	idle := make([]idleCloser, 0, len(g.idle))
+	for t := range g.idle {
+		idle = append(idle, t)
+	}
+	g.mu.Unlock()
+
+	for _, t := range idle {
+		t.CloseIdleConnections()
+	}
+}
+
+// wait waits for the turn of the next dial as given by the Pacer, if any.
+func (g *connGate) wait(ctx context.Context) error {
+	if g.pacer == nil {
+		return nil
+	}
+
+	g.mu.Lock()
+	if g.began.IsZero() {
+		g.began = time.Now()
+	}
+	wait, stop := g.pacer.Pace(time.Since(g.began), g.dials)
+	g.dials++
+	g.mu.Unlock()
+
+	if stop {
+		return errConnPacerStopped
+	} else if wait <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// gatedConn is a connection let through by a connGate, which frees its slot
+// once closed.
+type gatedConn struct {
+	net.Conn
+	once    sync.Once
+	release func()
+}
+
+func (c *gatedConn) Close() error {
+	c.once.Do(c.release)
+	return c.Conn.Close()
+}
+
+// workerConns holds the connections of a worker with a transport of its own,
+// which it opens anew after they served the requests or lived for the time
+// of a ConnectionPolicy, if any, by closing them while idle.
+type workerConns struct {
+	policy   *ConnectionPolicy
+	gate     *connGate
+	idle     []idleCloser
+	requests uint64
+	since    time.Time
+}
+
+// newWorkerConns gives the given client of a worker a copy of the transport
+// of the Attacker, with connections of its own bound to the given local
+// address, if any. It returns nil if the transport can't be copied.
+func (a *Attacker) newWorkerConns(client *http.Client, laddr *net.IPAddr) *workerConns {
+	tr, ok := client.Transport.(*http.Transport)
+	if !ok {
+		return nil
+	}
+
+	wc := &workerConns{policy: a.conns, gate: a.gate}
+
+	wt := tr.Clone()
+	if laddr != nil {
+		if wt.DialContext = a.laddrs.dial(laddr); a.gate != nil {
+			wt.DialContext = a.gate.dial(wt.DialContext)
+		}
+	}
+
+	if _, h2 := tr.TLSNextProto["h2"]; h2 {
+		// The HTTP/2 connections of the copy must be its own too.
+		wt.TLSNextProto = nil
+		if t2, err := http2.ConfigureTransports(wt); err == nil {
+			wc.idle = append(wc.idle, t2)
+		}
+	}
+	wc.idle = append(wc.idle, wt)
+	client.Transport = wt
+
+	if wc.gate != nil {
+		for _, t := range wc.idle {
+			wc.gate.track(t)
+		}
+	}
+
+	return wc
+}
+
+// churn closes the connections of the worker before its next request if
+// they served enough requests or lived long enough.
+func (wc *workerConns) churn() {
+	if wc == nil || !wc.policy.churns() {
+		return
+	}
+
+	now := time.Now()
+	if wc.since.IsZero() {
+		wc.since = now
+	}
+
+	if p := wc.policy; p.Requests > 0 && wc.requests >= p.Requests ||
+		p.Lifetime > 0 && now.Sub(wc.since) >= p.Lifetime {
+		wc.closeIdle()
+		wc.requests, wc.since = 0, now
+	}
+
+	wc.requests++
+}
+
+// closeIdle closes the idle connections of the worker.
+func (wc *workerConns) closeIdle() {
+	for _, t := range wc.idle {
+		t.CloseIdleConnections()
+	}
+}
+
+// close closes the idle connections of the worker once it stops.
+func (wc *workerConns) close() {
+	if wc == nil {
+		return
+	}
+
+	if wc.gate != nil {
+		for _, t := range wc.idle {
+			wc.gate.untrack(t)
+		}
+	}
+	wc.closeIdle()
+}
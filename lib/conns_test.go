@@ -0,0 +1,248 @@
+package vegeta
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// connServer returns a server which counts the connections opened to it,
+// over TLS with HTTP/2 enabled if tls is true.
+func connServer(tls bool, opened *int64) *httptest.Server {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt64(opened, 1)
+		}
+	}
+
+	if tls {
+		server.EnableHTTP2 = true
+		server.StartTLS()
+	} else {
+		server.Start()
+	}
+
+	return server
+}
+
+func TestNewConnections(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name  string
+		tls   bool
+		proto string
+	}{
+		{"http1", false, "HTTP/1.1"},
+		{"http2", true, "HTTP/2.0"},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var opened int64
+			server := connServer(tc.tls, &opened)
+			defer server.Close()
+
+			atk := NewAttacker(
+				Workers(1),
+				MaxWorkers(1),
+				HTTP2(true),
+				NewConnections(ConnectionPolicy{Requests: 3}),
+				ResponseHeaders("X-Proto"),
+			)
+
+			tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
+
+			var hits, handshakes int64
+			for res := range atk.Attack(tr, Rate{Freq: 100, Per: time.Second}, 200*time.Millisecond, "") {
+				if res.Error != "" {
+					t.Fatal(res.Error)
+				} else if got := res.Header.Get("X-Proto"); got != tc.proto {
+					t.Fatalf("got proto %s, want %s", got, tc.proto)
+				}
+
+				if hits++; res.Handshake > 0 {
+					handshakes++
+				}
+			}
+
+			conns := atomic.LoadInt64(&opened)
+			if want := (hits + 2) / 3; conns != want || handshakes != want {
+				t.Errorf("got %d connections opened and %d handshakes for %d hits, want %d", conns, handshakes, hits, want)
+			}
+		})
+	}
+}
+
+func TestNewConnectionsLifetime(t *testing.T) {
+	t.Parallel()
+
+	var opened int64
+	server := connServer(false, &opened)
+	defer server.Close()
+
+	atk := NewAttacker(Workers(1), MaxWorkers(1), NewConnections(ConnectionPolicy{Lifetime: 50 * time.Millisecond}))
+	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
+
+	var hits int64
+	for range atk.Attack(tr, Rate{Freq: 100, Per: time.Second}, 300*time.Millisecond, "") {
+		hits++
+	}
+
+	if conns := atomic.LoadInt64(&opened); conns < 4 || conns > 8 || conns >= hits {
+		t.Errorf("got %d connections opened for %d hits, want about one every 50ms", conns, hits)
+	}
+
+	// Zero policies leave connections as they are.
+	atk = NewAttacker(NewConnections(ConnectionPolicy{}))
+	if res := atk.hit(tr.NewTargeter(), ""); atk.conns != nil || res.Handshake != 0 {
+		t.Errorf("got policy %+v and handshake %s, want none", atk.conns, res.Handshake)
+	}
+}
+
+func TestNewConnectionsMaxOpen(t *testing.T) {
+	t.Parallel()
+
+	var opened int64
+	a, b := connServer(false, &opened), connServer(false, &opened)
+	defer a.Close()
+	defer b.Close()
+
+	// Options which set the dialer don't undo the gate given before them.
+	atk := NewAttacker(
+		NewConnections(ConnectionPolicy{MaxOpen: 1}),
+		LocalAddr(net.IPAddr{IP: net.IPv4(127, 0, 0, 1)}),
+		Timeout(time.Second),
+	)
+
+	began := time.Now()
+	for _, server := range []*httptest.Server{a, b, a} {
+		tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
+		if res := atk.hit(tr.NewTargeter(), ""); res.Error != "" {
+			t.Fatal(res.Error)
+		}
+	}
+
+	// The idle connection to each host made room for the next one.
+	if elapsed := time.Since(began); elapsed > 500*time.Millisecond {
+		t.Errorf("got hits taking %s, want them not to wait for idle connections", elapsed)
+	} else if conns, open := atomic.LoadInt64(&opened), len(atk.gate.open); conns != 3 || open != 1 {
+		t.Errorf("got %d connections opened and %d open, want 3 and 1", conns, open)
+	}
+
+	// Transports other than *http.Transport fail the attack.
+	atk = NewAttacker(NewConnections(ConnectionPolicy{Requests: 1}), H2C(true))
+	if err := atk.Err(); err == nil {
+		t.Fatal("got no error with H2C")
+	} else if res := atk.hit(NewStaticTargeter(Target{Method: "GET", URL: a.URL}).NewTargeter(), ""); res.Error != err.Error() {
+		t.Errorf("got error %q, want %q", res.Error, err)
+	}
+}
+
+// idleConns is a transport with idle connections.
+type idleConns struct{ conns []net.Conn }
+
+func (c *idleConns) CloseIdleConnections() {
+	for _, conn := range c.conns {
+		conn.Close()
+	}
+}
+
+func TestConnGate(t *testing.T) {
+	t.Parallel()
+
+	dial := func(context.Context, string, string) (net.Conn, error) {
+		c, _ := net.Pipe()
+		return c, nil
+	}
+
+	t.Run("max open", func(t *testing.T) {
+		t.Parallel()
+
+		g := connGate{open: make(chan struct{}, 2)}
+		d := g.dial(dial)
+
+		c1, err := d(context.Background(), "tcp", "goku:80")
+		if err != nil {
+			t.Fatal(err)
+		} else if _, err = d(context.Background(), "tcp", "goku:80"); err != nil {
+			t.Fatal(err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		if _, err = d(ctx, "tcp", "goku:80"); err != context.DeadlineExceeded {
+			t.Fatalf("got error %v, want the third dial to wait for a slot", err)
+		}
+
+		// Closing twice only frees one slot.
+		c1.Close()
+		c1.Close()
+
+		if _, err = d(context.Background(), "tcp", "goku:80"); err != nil {
+			t.Fatal(err)
+		} else if n := len(g.open); n != 2 {
+			t.Errorf("got %d open connections, want 2", n)
+		}
+	})
+
+	t.Run("idle", func(t *testing.T) {
+		t.Parallel()
+
+		idle := &idleConns{}
+		g := connGate{open: make(chan struct{}, 1)}
+		g.track(idle)
+		d := g.dial(dial)
+
+		c, err := d(context.Background(), "tcp", "goku:80")
+		if err != nil {
+			t.Fatal(err)
+		}
+		idle.conns = append(idle.conns, c)
+
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+
+		// Closing the idle connection makes room for the next one.
+		if _, err = d(ctx, "tcp", "vegeta:80"); err != nil {
+			t.Fatalf("got error %v, want idle connections closed to make room", err)
+		}
+
+		g.untrack(idle)
+		if _, err = d(ctx, "tcp", "goku:80"); err != context.DeadlineExceeded {
+			t.Errorf("got error %v, want untracked idle connections kept", err)
+		}
+	})
+
+	t.Run("pacer", func(t *testing.T) {
+		t.Parallel()
+
+		g := connGate{pacer: Rate{Freq: 100, Per: time.Second}}
+		d := g.dial(dial)
+
+		began := time.Now()
+		for i := 0; i < 6; i++ {
+			if _, err := d(context.Background(), "tcp", "goku:80"); err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		if elapsed := time.Since(began); elapsed < 45*time.Millisecond {
+			t.Errorf("got 6 dials in %s, want them paced at 100/s", elapsed)
+		}
+
+		g.pacer = PacerFunc(func(time.Duration, uint64) (time.Duration, bool) { return 0, true })
+		if _, err := d(context.Background(), "tcp", "goku:80"); err != errConnPacerStopped {
+			t.Errorf("got error %v, want %v", err, errConnPacerStopped)
+		}
+	})
+}
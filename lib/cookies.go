@@ -0,0 +1,123 @@
+package vegeta
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CookieMode sets how an Attacker keeps the cookies set by responses.
+type CookieMode uint8
+
+// Supported CookieModes.
+const (
+	// NoCookies doesn't keep cookies.
+	NoCookies CookieMode = iota
+	// SharedCookies keeps cookies in a single jar shared by all workers.
+	SharedCookies
+	// WorkerCookies keeps cookies in one jar per worker, so that workers
+	// keep independent sessions like distinct users do.
+	WorkerCookies
+)
+
+// A CookieSeed is a cookie stored in cookie jars before an attack begins.
+type CookieSeed struct {
+	// URL is the URL of the response that would have set the cookie.
+	URL *url.URL
+	// Cookie is the seeded cookie.
+	Cookie *http.Cookie
+}
+
+// Cookies returns a functional option which makes an Attacker keep the
+// cookies set by responses and send them in the following requests, like
+// browsers do. Cookie jars are seeded with the given cookies.
+func Cookies(mode CookieMode, seed []CookieSeed) func(*Attacker) {
+	return func(a *Attacker) {
+		a.cookies, a.cookieSeed = mode, seed
+		switch mode {
+		case SharedCookies:
+			a.client.Jar = newCookieJar(seed)
+		case NoCookies:
+			a.client.Jar = nil
+		}
+	}
+}
+
+func newCookieJar(seed []CookieSeed) http.CookieJar {
+	// cookiejar.New never returns an error.
+	jar, _ := cookiejar.New(nil)
+	for _, s := range seed {
+		jar.SetCookies(s.URL, []*http.Cookie{s.Cookie})
+	}
+	return jar
+}
+
+// ReadCookieFile reads the cookies of a file in the Netscape cookie file
+// format written by curl and browser extensions, which has one cookie per
+// line with tab separated fields:
+//
+//    domain  include-subdomains  path  secure  expiry  name  value
+//
+// Expired cookies are skipped.
+func ReadCookieFile(r io.Reader) ([]CookieSeed, error) {
+	var (
+		seed []CookieSeed
+		sc   = bufio.NewScanner(r)
+		now  = time.Now()
+	)
+
+	for n := 1; sc.Scan(); n++ {
+		line, httpOnly := strings.TrimSpace(sc.Text()), false
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			line, httpOnly = line[len("#HttpOnly_"):], true
+		} else if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Split(line, "\t")
+		if len(fields) == 6 {
+			fields = append(fields, "") // empty value
+		} else if len(fields) != 7 {
+			return nil, fmt.Errorf("bad cookie file line %d: want 7 tab separated fields, got %d", n, len(fields))
+		}
+
+		expiry, err := strconv.ParseInt(fields[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad cookie file line %d: bad expiry: %s", n, fields[4])
+		}
+
+		domain := strings.TrimPrefix(fields[0], ".")
+		c := &http.Cookie{
+			Name:     fields[5],
+			Value:    fields[6],
+			Path:     fields[2],
+			Secure:   strings.EqualFold(fields[3], "TRUE"),
+			HttpOnly: httpOnly,
+		}
+
+		if strings.EqualFold(fields[1], "TRUE") {
+			c.Domain = domain
+		}
+
+		if expiry > 0 {
+			if c.Expires = time.Unix(expiry, 0); c.Expires.Before(now) {
+				continue
+			}
+		}
+
+		u := &url.URL{Scheme: "http", Host: domain, Path: c.Path}
+		if c.Secure {
+			u.Scheme = "https"
+		}
+
+		seed = append(seed, CookieSeed{URL: u, Cookie: c})
+	}
+
+	return seed, sc.Err()
+}
@@ -0,0 +1,113 @@
+package vegeta
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReadCookieFile(t *testing.T) {
+	t.Parallel()
+
+	file := strings.Join([]string{
+		"# Netscape HTTP Cookie File",
+		"",
+		".goku.io\tTRUE\t/\tTRUE\t0\tsession\tkakarot",
+		"#HttpOnly_vegeta.io\tFALSE\t/api\tFALSE\t4102444800\tid\t42",
+		"vegeta.io\tFALSE\t/\tFALSE\t0\tempty",
+		"vegeta.io\tFALSE\t/\tFALSE\t1\texpired\tyes",
+	}, "\n")
+
+	seed, err := ReadCookieFile(strings.NewReader(file))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []CookieSeed{
+		{
+			URL:    &url.URL{Scheme: "https", Host: "goku.io", Path: "/"},
+			Cookie: &http.Cookie{Name: "session", Value: "kakarot", Path: "/", Domain: "goku.io", Secure: true},
+		},
+		{
+			URL:    &url.URL{Scheme: "http", Host: "vegeta.io", Path: "/api"},
+			Cookie: &http.Cookie{Name: "id", Value: "42", Path: "/api", HttpOnly: true, Expires: time.Unix(4102444800, 0)},
+		},
+		{
+			URL:    &url.URL{Scheme: "http", Host: "vegeta.io", Path: "/"},
+			Cookie: &http.Cookie{Name: "empty", Path: "/"},
+		},
+	}
+
+	if !reflect.DeepEqual(seed, want) {
+		t.Fatalf("got %+v, want %+v", seed, want)
+	}
+
+	if _, err := ReadCookieFile(strings.NewReader("goku.io\tTRUE\t/")); err == nil {
+		t.Error("got nil error for a bad line")
+	}
+}
+
+func TestCookies(t *testing.T) {
+	t.Parallel()
+
+	var sessions int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("session"); err == nil {
+			fmt.Fprint(w, c.Value)
+			return
+		}
+		session := fmt.Sprint(atomic.AddInt32(&sessions, 1))
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: session})
+		fmt.Fprint(w, session)
+	}))
+	defer server.Close()
+
+	u, _ := url.Parse(server.URL)
+	seed := []CookieSeed{{URL: u, Cookie: &http.Cookie{Name: "session", Value: "seeded"}}}
+
+	for _, tc := range []struct {
+		name string
+		opt  func(*Attacker)
+		want [][]string // bodies of the hits of each worker
+	}{
+		{"none", Cookies(NoCookies, nil), [][]string{{"1", "2"}, {"3", "4"}}},
+		{"shared", Cookies(SharedCookies, nil), [][]string{{"5", "5"}, {"5", "5"}}},
+		{"worker", Cookies(WorkerCookies, nil), [][]string{{"6", "6"}, {"7", "7"}}},
+		{"seeded", Cookies(WorkerCookies, seed), [][]string{{"seeded", "seeded"}, {"seeded", "seeded"}}},
+	} {
+		atk := NewAttacker(tc.opt)
+		tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
+
+		// Workers run one after the other so that sessions are numbered
+		// deterministically.
+		for i, want := range tc.want {
+			var wg sync.WaitGroup
+			ticks := make(chan time.Time, len(want))
+			results := make(chan *Result, len(want))
+			for range want {
+				ticks <- time.Now()
+			}
+			close(ticks)
+
+			wg.Add(1)
+			atk.attack(tr.NewTargeter(), "", &wg, ticks, results)
+			close(results)
+
+			var got []string
+			for r := range results {
+				got = append(got, string(r.Body))
+			}
+
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: worker #%d: got sessions %v, want %v", tc.name, i, got, want)
+			}
+		}
+	}
+}
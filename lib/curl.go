@@ -0,0 +1,539 @@
+package vegeta
+
+import (
+	"bufio"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type curlTargeter struct {
+	body []byte
+	hdr  http.Header
+	rd   *bufio.Reader
+	mu   sync.Mutex
+}
+
+func (c *curlTargeter) Next(tgt *Target) error {
+	if tgt == nil {
+		return ErrNilTarget
+	}
+
+	c.mu.Lock()
+	args, err := readCurlCommand(c.rd)
+	c.mu.Unlock()
+
+	if err == io.EOF {
+		return ErrNoTargets
+	} else if err != nil {
+		return err
+	}
+
+	*tgt = Target{Body: c.body, Header: http.Header{}}
+	for k, vs := range c.hdr {
+		tgt.Header[k] = append(tgt.Header[k], vs...)
+	}
+
+	return parseCurlCommand(tgt, args)
+}
+
+func (c *curlTargeter) Result(body []byte, code uint16, err error) { /* noop */ }
+
+// NewTargeter returns same curlTargeter, because it's immutable
+func (c *curlTargeter) NewTargeter() Targeter { return c }
+
+// NewCurlTargeter returns a new Targeter that decodes one Target from the
+// curl commands read from the given io.Reader on every invocation, such as
+// the ones copied from the developer tools of web browsers.
+//
+//    curl -X POST https://foo.bar/a/b/c \
+//      -H 'Content-Type: application/json' \
+//      --data-binary @/path/to/body/file
+//
+//    # Lines starting with # are ignored.
+//    curl 'https://foo.bar/b/c/a' -H 'Header-X: 123' --compressed
+//
+// Commands are split into words like a POSIX shell does, including quotes and
+// line continuations. The supported options are -X, -H, -d, --data-raw,
+// --data-binary, --data-urlencode, -G, -I, -u, -b, -A, -e, --url and
+// --compressed. Options which don't change the request, like -s or -k,
+// are ignored.
+//
+// body will be set as the Target's body if no data is provided.
+// hdr will be merged with the each Target's headers.
+func NewCurlTargeter(src io.Reader, body []byte, hdr http.Header) TargeterProvider {
+	return &curlTargeter{body: body, hdr: hdr, rd: bufio.NewReader(src)}
+}
+
+// curlOptions maps the supported curl options to whether they take an argument.
+var curlOptions = map[string]bool{
+	"-X": true, "--request": true,
+	"-H": true, "--header": true,
+	"-d": true, "--data": true, "--data-ascii": true,
+	"--data-raw": true, "--data-binary": true, "--data-urlencode": true,
+	"-u": true, "--user": true,
+	"-b": true, "--cookie": true,
+	"-A": true, "--user-agent": true,
+	"-e": true, "--referer": true,
+	"--url": true,
+	"-G": false, "--get": false,
+	"-I": false, "--head": false,
+	"--compressed": false,
+	// Options which don't change the request.
+	"-o": true, "--output": true,
+	"-m": true, "--max-time": true,
+	"--connect-timeout": true,
+	"-w": true, "--write-out": true,
+	"-s": false, "--silent": false,
+	"-S": false, "--show-error": false,
+	"-k": false, "--insecure": false,
+	"-L": false, "--location": false,
+	"-v": false, "--verbose": false,
+	"-i": false, "--include": false,
+	"-f": false, "--fail": false,
+	"--http1.1": false, "--http2": false,
+}
+
+// parseCurlCommand sets the fields of tgt according to the words of a
+// curl command.
+func parseCurlCommand(tgt *Target, args []string) (err error) {
+	if args[0] != "curl" {
+		return fmt.Errorf("bad curl command: %s", args[0])
+	}
+
+	var (
+		method  string
+		data    []string
+		get     bool
+		head    bool
+		removed = map[string]bool{}
+	)
+
+	for i := 1; i < len(args); i++ {
+		opt, arg := args[i], ""
+		if !strings.HasPrefix(opt, "-") || opt == "-" {
+			if tgt.URL != "" {
+				return fmt.Errorf("bad curl command: more than one URL")
+			}
+			tgt.URL = opt
+			continue
+		}
+
+		hasArg := false
+		if strings.HasPrefix(opt, "--") {
+			if j := strings.IndexByte(opt, '='); j != -1 {
+				opt, arg = opt[:j], opt[j+1:]
+				hasArg = true
+			}
+		} else if len(opt) > 2 {
+			// Short options may have their argument attached (-XPOST)
+			// or be combined with others (-sSL).
+			if curlOptions[opt[:2]] {
+				opt, arg = opt[:2], opt[2:]
+				hasArg = true
+			} else {
+				var expanded []string
+				for j := 1; j < len(opt); j++ {
+					short := "-" + opt[j:j+1]
+					if expanded = append(expanded, short); curlOptions[short] {
+						if rest := opt[j+1:]; rest != "" {
+							expanded = append(expanded, rest)
+						}
+						break
+					}
+				}
+				args = append(args[:i:i], append(expanded, args[i+1:]...)...)
+				i--
+				continue
+			}
+		}
+
+		takesArg, ok := curlOptions[opt]
+		if !ok {
+			return fmt.Errorf("bad curl option: %s", opt)
+		} else if hasArg && !takesArg {
+			return fmt.Errorf("bad curl option: %s doesn't take an argument", opt)
+		} else if takesArg && !hasArg {
+			if i++; i == len(args) {
+				return fmt.Errorf("bad curl option: %s requires an argument", opt)
+			}
+			arg = args[i]
+		}
+
+		switch opt {
+		case "-X", "--request":
+			method = arg
+		case "-H", "--header":
+			kv := strings.SplitN(arg, ":", 2)
+			if name := strings.TrimSpace(kv[0]); len(kv) == 2 && name != "" {
+				if value := strings.TrimSpace(kv[1]); value != "" {
+					tgt.Header[name] = append(tgt.Header[name], value)
+				} else {
+					// An empty value removes the header.
+					removed[strings.ToLower(name)] = true
+				}
+			} else if strings.HasSuffix(arg, ";") {
+				// A trailing semicolon sends the header with an empty value.
+				name := strings.TrimSpace(arg[:len(arg)-1])
+				tgt.Header[name] = append(tgt.Header[name], "")
+			} else {
+				return fmt.Errorf("bad header: %s", arg)
+			}
+		case "-d", "--data", "--data-ascii", "--data-binary":
+			if strings.HasPrefix(arg, "@") {
+				bs, err := ioutil.ReadFile(arg[1:])
+				if err != nil {
+					return fmt.Errorf("bad body: %s", err)
+				}
+				if arg = string(bs); opt != "--data-binary" {
+					arg = strings.NewReplacer("\r", "", "\n", "").Replace(arg)
+				}
+			}
+			data = append(data, arg)
+		case "--data-raw":
+			data = append(data, arg)
+		case "--data-urlencode":
+			if arg, err = curlURLEncode(arg); err != nil {
+				return err
+			}
+			data = append(data, arg)
+		case "-G", "--get":
+			get = true
+		case "-I", "--head":
+			head = true
+		case "-u", "--user":
+			if !strings.Contains(arg, ":") {
+				arg += ":"
+			}
+			tgt.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(arg)))
+		case "-b", "--cookie":
+			if !strings.Contains(arg, "=") {
+				return fmt.Errorf("bad cookie: cookie files aren't supported: %s", arg)
+			}
+			tgt.Header["Cookie"] = append(tgt.Header["Cookie"], arg)
+		case "-A", "--user-agent":
+			tgt.Header["User-Agent"] = []string{arg}
+		case "-e", "--referer":
+			tgt.Header["Referer"] = []string{arg}
+		case "--url":
+			if tgt.URL != "" {
+				return fmt.Errorf("bad curl command: more than one URL")
+			}
+			tgt.URL = arg
+		case "--compressed":
+			if !hasHeader(tgt.Header, "Accept-Encoding") {
+				tgt.Header["Accept-Encoding"] = []string{"deflate, gzip"}
+			}
+		}
+	}
+
+	if tgt.URL == "" {
+		return ErrNoURL
+	} else if !strings.Contains(tgt.URL, "://") {
+		tgt.URL = "http://" + tgt.URL // curl's default scheme
+	}
+
+	if len(data) > 0 {
+		if get {
+			sep := "?"
+			if strings.Contains(tgt.URL, "?") {
+				sep = "&"
+			}
+			tgt.URL += sep + strings.Join(data, "&")
+		} else {
+			tgt.Body = []byte(strings.Join(data, "&"))
+			if !hasHeader(tgt.Header, "Content-Type") && !removed["content-type"] {
+				tgt.Header["Content-Type"] = []string{"application/x-www-form-urlencoded"}
+			}
+		}
+	}
+
+	if _, err = url.ParseRequestURI(tgt.URL); err != nil {
+		return fmt.Errorf("bad URL: %s", tgt.URL)
+	}
+
+	switch {
+	case method != "":
+		tgt.Method = method
+	case head:
+		tgt.Method = "HEAD"
+	case len(data) > 0 && !get:
+		tgt.Method = "POST"
+	default:
+		tgt.Method = "GET"
+	}
+
+	return nil
+}
+
+// curlURLEncode encodes the argument of --data-urlencode, which is one of
+// content, =content, name=content, @file or name@file.
+func curlURLEncode(arg string) (string, error) {
+	var name, content string
+	if i := strings.IndexAny(arg, "=@"); i == -1 {
+		content = arg
+	} else if name, content = arg[:i], arg[i+1:]; arg[i] == '@' {
+		bs, err := ioutil.ReadFile(content)
+		if err != nil {
+			return "", fmt.Errorf("bad body: %s", err)
+		}
+		content = string(bs)
+	}
+
+	if content = url.QueryEscape(content); name != "" {
+		return name + "=" + content, nil
+	}
+	return content, nil
+}
+
+func hasHeader(hdr http.Header, name string) bool {
+	for k := range hdr {
+		if strings.EqualFold(k, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// readCurlCommand reads the words of the next command in rd like a POSIX
+// shell would, skipping empty lines and comments. It supports single, double
+// and ANSI-C ($'...') quotes, backslash escapes and line continuations.
+func readCurlCommand(rd *bufio.Reader) (args []string, err error) {
+	var (
+		word   []byte
+		inWord bool
+	)
+
+	flush := func() {
+		if inWord {
+			args = append(args, string(word))
+			word, inWord = nil, false
+		}
+	}
+
+	for {
+		c, err := rd.ReadByte()
+		if err == io.EOF {
+			if flush(); len(args) > 0 {
+				return args, nil
+			}
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case c == '\n':
+			if flush(); len(args) > 0 {
+				return args, nil
+			}
+		case c == ' ' || c == '\t' || c == '\r':
+			flush()
+		case c == '#' && !inWord:
+			if _, err = rd.ReadString('\n'); err != nil && err != io.EOF {
+				return nil, err
+			}
+			if len(args) > 0 {
+				return args, nil
+			}
+		case c == '\\':
+			if c, err = rd.ReadByte(); err != nil {
+				return nil, fmt.Errorf("bad curl command: unexpected end after \\")
+			}
+			if c == '\r' {
+				if c, err = rd.ReadByte(); err != nil || c != '\n' {
+					return nil, fmt.Errorf("bad curl command: unexpected \\r")
+				}
+			}
+			if c != '\n' { // line continuation
+				word, inWord = append(word, c), true
+			}
+		case c == '\'':
+			s, err := rd.ReadString('\'')
+			if err != nil {
+				return nil, fmt.Errorf("bad curl command: unterminated quote")
+			}
+			word, inWord = append(word, s[:len(s)-1]...), true
+		case c == '"':
+			if word, err = readDoubleQuoted(rd, word); err != nil {
+				return nil, err
+			}
+			inWord = true
+		case c == '$':
+			if next, _ := rd.Peek(1); len(next) == 1 && next[0] == '\'' {
+				rd.ReadByte()
+				if word, err = readANSIQuoted(rd, word); err != nil {
+					return nil, err
+				}
+			} else {
+				word = append(word, c)
+			}
+			inWord = true
+		default:
+			word, inWord = append(word, c), true
+		}
+	}
+}
+
+func readDoubleQuoted(rd *bufio.Reader, word []byte) ([]byte, error) {
+	for {
+		c, err := rd.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("bad curl command: unterminated quote")
+		}
+
+		switch c {
+		case '"':
+			return word, nil
+		case '\\':
+			if c, err = rd.ReadByte(); err != nil {
+				return nil, fmt.Errorf("bad curl command: unterminated quote")
+			}
+			switch c {
+			case '\n': // line continuation
+			case '$', '`', '"', '\\':
+				word = append(word, c)
+			default:
+				word = append(word, '\\', c)
+			}
+		default:
+			word = append(word, c)
+		}
+	}
+}
+
+var ansiEscapes = map[byte]byte{
+	'a': '\a', 'b': '\b', 'e': 0x1b, 'f': '\f', 'n': '\n', 'r': '\r',
+	't': '\t', 'v': '\v', '\\': '\\', '\'': '\'', '"': '"', '?': '?',
+}
+
+func readANSIQuoted(rd *bufio.Reader, word []byte) ([]byte, error) {
+	for {
+		c, err := rd.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("bad curl command: unterminated quote")
+		}
+
+		if c == '\'' {
+			return word, nil
+		} else if c != '\\' {
+			word = append(word, c)
+			continue
+		}
+
+		if c, err = rd.ReadByte(); err != nil {
+			return nil, fmt.Errorf("bad curl command: unterminated quote")
+		}
+
+		if e, ok := ansiEscapes[c]; ok {
+			word = append(word, e)
+		} else if c == 'x' {
+			var hex []byte
+			for len(hex) < 2 {
+				next, _ := rd.Peek(1)
+				if len(next) == 0 || !strings.ContainsRune("0123456789abcdefABCDEF", rune(next[0])) {
+					break
+				}
+				hex = append(hex, next[0])
+				rd.ReadByte()
+			}
+			n, err := strconv.ParseUint(string(hex), 16, 8)
+			if err != nil {
+				return nil, fmt.Errorf("bad curl command: bad escape \\x%s", hex)
+			}
+			word = append(word, byte(n))
+		} else {
+			word = append(word, '\\', c)
+		}
+	}
+}
+
+// NewCurlTargetEncoder returns a TargetEncoder that encodes Targets as curl
+// commands, one per line, which can be read by NewCurlTargeter.
+func NewCurlTargetEncoder(w io.Writer) TargetEncoder {
+	return func(t *Target) error {
+		var b strings.Builder
+		b.WriteString("curl -X ")
+		b.WriteString(curlQuote(t.Method))
+		b.WriteByte(' ')
+		b.WriteString(curlQuote(t.URL))
+
+		keys := make([]string, 0, len(t.Header))
+		for k := range t.Header {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			for _, v := range t.Header[k] {
+				b.WriteString(" -H ")
+				if v == "" {
+					b.WriteString(curlQuote(k + ";"))
+				} else {
+					b.WriteString(curlQuote(k + ": " + v))
+				}
+			}
+		}
+
+		if len(t.Body) > 0 {
+			if !hasHeader(t.Header, "Content-Type") {
+				// Don't let curl set a form Content-Type.
+				b.WriteString(" -H 'Content-Type:'")
+			}
+			b.WriteString(" --data-binary ")
+			b.WriteString(curlQuote(string(t.Body)))
+		}
+
+		b.WriteByte('\n')
+		_, err := io.WriteString(w, b.String())
+		return err
+	}
+}
+
+// curlQuote quotes s for a POSIX shell. Strings with control characters or
+// non ASCII bytes are ANSI-C quoted so that commands fit in a single line.
+func curlQuote(s string) string {
+	ansi := false
+	for i := 0; i < len(s); i++ {
+		if s[i] < 0x20 || s[i] >= 0x7f {
+			ansi = true
+			break
+		}
+	}
+
+	if !ansi {
+		if s != "" && strings.Trim(s, "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789-_./:") == "" {
+			return s
+		}
+		return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+	}
+
+	var b strings.Builder
+	b.WriteString("$'")
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case c == '\n':
+			b.WriteString(`\n`)
+		case c == '\r':
+			b.WriteString(`\r`)
+		case c == '\t':
+			b.WriteString(`\t`)
+		case c == '\\' || c == '\'':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < 0x20 || c >= 0x7f:
+			fmt.Fprintf(&b, `\x%02x`, c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('\'')
+	return b.String()
+}
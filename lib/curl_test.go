@@ -0,0 +1,173 @@
+package vegeta
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestCurlTargeter(t *testing.T) {
+	t.Parallel()
+
+	bodyf, err := ioutil.TempFile("", "vegeta-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(bodyf.Name())
+
+	if _, err = bodyf.WriteString("power=9000\n&level=super\n"); err != nil {
+		t.Fatal(err)
+	}
+	bodyf.Close()
+
+	for _, tc := range []struct {
+		name string
+		in   string
+		body []byte
+		hdr  http.Header
+		out  Target
+		err  error
+	}{
+		{
+			name: "simple",
+			in:   "curl http://goku:9090/path/to/dragon?item=ball",
+			out:  Target{Method: "GET", URL: "http://goku:9090/path/to/dragon?item=ball", Header: http.Header{}},
+		},
+		{
+			name: "devtools",
+			in: `# Copied from the browser
+curl 'https://goku/power' \
+  -H 'Accept: application/json' \
+  -H "X-Level: \"super\"" \
+  --data-raw $'{"power":\x39000,\n"name":"it\'s over"}' \
+  --compressed`,
+			out: Target{
+				Method: "POST",
+				URL:    "https://goku/power",
+				Body:   []byte("{\"power\":9000,\n\"name\":\"it's over\"}"),
+				Header: http.Header{
+					"Accept":          []string{"application/json"},
+					"X-Level":         []string{`"super"`},
+					"Accept-Encoding": []string{"deflate, gzip"},
+					"Content-Type":    []string{"application/x-www-form-urlencoded"},
+				},
+			},
+		},
+		{
+			name: "method and user",
+			in:   "curl -sSXPUT -u goku:kakarot --user-agent=vegeta goku/power -H 'Content-Type:'  -d a=1 -d b=2",
+			out: Target{
+				Method: "PUT",
+				URL:    "http://goku/power",
+				Body:   []byte("a=1&b=2"),
+				Header: http.Header{
+					"Authorization": []string{"Basic Z29rdTprYWthcm90"},
+					"User-Agent":    []string{"vegeta"},
+				},
+			},
+		},
+		{
+			name: "data files",
+			in:   fmt.Sprintf("curl http://goku -d @%[1]s --data-binary @%[1]s", bodyf.Name()),
+			out: Target{
+				Method: "POST",
+				URL:    "http://goku",
+				Body:   []byte("power=9000&level=super&power=9000\n&level=super\n"),
+				Header: http.Header{"Content-Type": []string{"application/x-www-form-urlencoded"}},
+			},
+		},
+		{
+			name: "get with data",
+			in:   "curl -G 'http://goku/search?a=1' --data-urlencode 'q=dragon ball' -I",
+			out:  Target{Method: "HEAD", URL: "http://goku/search?a=1&q=dragon+ball", Header: http.Header{}},
+		},
+		{
+			name: "default body and header",
+			in:   "curl -X POST http://goku -b 'session=abc'",
+			body: []byte("default"),
+			hdr:  http.Header{"X-Attack": []string{"vegeta"}},
+			out: Target{
+				Method: "POST",
+				URL:    "http://goku",
+				Body:   []byte("default"),
+				Header: http.Header{"X-Attack": []string{"vegeta"}, "Cookie": []string{"session=abc"}},
+			},
+		},
+		{name: "no url", in: "curl -X GET", err: ErrNoURL},
+		{name: "not curl", in: "wget http://goku", err: fmt.Errorf("bad curl command: wget")},
+		{name: "unknown option", in: "curl --foo http://goku", err: fmt.Errorf("bad curl option: --foo")},
+		{name: "missing argument", in: "curl http://goku -H", err: fmt.Errorf("bad curl option: -H requires an argument")},
+		{name: "unterminated quote", in: "curl 'http://goku", err: fmt.Errorf("bad curl command: unterminated quote")},
+		{name: "empty", in: "\n# nothing\n", err: ErrNoTargets},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			tr := NewCurlTargeter(strings.NewReader(tc.in), tc.body, tc.hdr)
+
+			var got Target
+			err := tr.NewTargeter().Next(&got)
+			if got, want := fmt.Sprint(err), fmt.Sprint(tc.err); got != want {
+				t.Fatalf("got error %q, want %q", got, want)
+			} else if err != nil {
+				return
+			}
+
+			if !got.Equal(&tc.out) {
+				t.Errorf("got: %#v\nwant: %#v", got, tc.out)
+			}
+		})
+	}
+}
+
+func TestCurlTargetEncoder(t *testing.T) {
+	t.Parallel()
+
+	tgts := []Target{
+		{Method: "GET", URL: "http://goku/power?level=super&x=1", Header: http.Header{}},
+		{
+			Method: "POST",
+			URL:    "https://goku/power",
+			Body:   []byte("{\"name\": \"it's over\"}\n\x00\xff"),
+			Header: http.Header{
+				"Authorization": []string{"Bearer 'secret'"},
+				"X-Empty":       []string{""},
+				"X-Multi":       []string{"a", "b"},
+			},
+		},
+		{
+			Method: "PUT",
+			URL:    "https://goku/power",
+			Body:   []byte("power=9000"),
+			Header: http.Header{"Content-Type": []string{"application/x-www-form-urlencoded"}},
+		},
+	}
+
+	var buf bytes.Buffer
+	enc := NewCurlTargetEncoder(&buf)
+	for i := range tgts {
+		if err := enc.Encode(&tgts[i]); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got, want := strings.Count(buf.String(), "\n"), len(tgts); got != want {
+		t.Fatalf("got %d lines, want %d:\n%s", got, want, buf.String())
+	}
+
+	tr := NewCurlTargeter(&buf, nil, nil).NewTargeter()
+	for i, want := range tgts {
+		var got Target
+		if err := tr.Next(&got); err != nil {
+			t.Fatalf("#%d: %s", i, err)
+		} else if !got.Equal(&want) {
+			t.Errorf("#%d: got: %#v\nwant: %#v", i, got, want)
+		}
+	}
+
+	if err := tr.Next(&Target{}); err != ErrNoTargets {
+		t.Errorf("got error %v, want %v", err, ErrNoTargets)
+	}
+}
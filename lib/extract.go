@@ -0,0 +1,145 @@
+package vegeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// An extractor pulls a single value out of a response body.
+type extractor interface {
+	extract(body []byte) (string, error)
+}
+
+// parseExtractor parses an extraction expression of the form
+// "jsonpath:<path>" or "regex:<pattern>".
+func parseExtractor(expr string) (extractor, error) {
+	tokens := strings.SplitN(expr, ":", 2)
+	if len(tokens) < 2 {
+		return nil, fmt.Errorf("bad extractor: %s", expr)
+	}
+
+	switch kind, arg := tokens[0], tokens[1]; kind {
+	case "jsonpath":
+		return parseJSONPath(arg)
+	case "regex":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return nil, fmt.Errorf("bad extractor: %s", err)
+		}
+		return regexExtractor{re}, nil
+	default:
+		return nil, fmt.Errorf("bad extractor kind: %s", kind)
+	}
+}
+
+// regexExtractor extracts the first capture group of a regular expression
+// or the whole match if the expression has no groups.
+type regexExtractor struct{ re *regexp.Regexp }
+
+func (e regexExtractor) extract(body []byte) (string, error) {
+	m := e.re.FindSubmatch(body)
+	switch {
+	case m == nil:
+		return "", fmt.Errorf("regex %q: no match", e.re)
+	case len(m) > 1:
+		return string(m[1]), nil
+	default:
+		return string(m[0]), nil
+	}
+}
+
+// jsonPath is a minimal JSONPath implementation supporting the root
+// element ($), child members (.name or ['name']) and array indexes ([n]).
+type jsonPath struct {
+	expr  string
+	steps []interface{} // string keys or int indexes
+}
+
+func parseJSONPath(expr string) (*jsonPath, error) {
+	p := &jsonPath{expr: expr}
+	if !strings.HasPrefix(expr, "$") {
+		return nil, fmt.Errorf("bad jsonpath %q: must start with $", expr)
+	}
+
+	for s := expr[1:]; len(s) > 0; {
+		switch s[0] {
+		case '.':
+			s = s[1:]
+			i := strings.IndexAny(s, ".[")
+			if i == -1 {
+				i = len(s)
+			}
+			if i == 0 {
+				return nil, fmt.Errorf("bad jsonpath %q: empty member", expr)
+			}
+			p.steps = append(p.steps, s[:i])
+			s = s[i:]
+		case '[':
+			i := strings.IndexByte(s, ']')
+			if i == -1 {
+				return nil, fmt.Errorf("bad jsonpath %q: unclosed bracket", expr)
+			}
+			sel := s[1:i]
+			if n := len(sel); n >= 2 && (sel[0] == '\'' || sel[0] == '"') && sel[n-1] == sel[0] {
+				p.steps = append(p.steps, sel[1:n-1])
+			} else if idx, err := strconv.Atoi(sel); err == nil {
+				p.steps = append(p.steps, idx)
+			} else {
+				return nil, fmt.Errorf("bad jsonpath %q: bad selector %s", expr, sel)
+			}
+			s = s[i+1:]
+		default:
+			return nil, fmt.Errorf("bad jsonpath %q: unexpected %q", expr, s[0])
+		}
+	}
+
+	return p, nil
+}
+
+func (p *jsonPath) extract(body []byte) (string, error) {
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("jsonpath %q: %s", p.expr, err)
+	}
+
+	for _, step := range p.steps {
+		switch s := step.(type) {
+		case string:
+			obj, ok := v.(map[string]interface{})
+			if !ok {
+				return "", fmt.Errorf("jsonpath %q: %q not found", p.expr, s)
+			}
+			if v, ok = obj[s]; !ok {
+				return "", fmt.Errorf("jsonpath %q: %q not found", p.expr, s)
+			}
+		case int:
+			arr, ok := v.([]interface{})
+			if s < 0 && ok {
+				s += len(arr)
+			}
+			if !ok || s < 0 || s >= len(arr) {
+				return "", fmt.Errorf("jsonpath %q: index %d not found", p.expr, s)
+			}
+			v = arr[s]
+		}
+	}
+
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case nil:
+		return "", fmt.Errorf("jsonpath %q: null value", p.expr)
+	default:
+		bs, err := json.Marshal(t)
+		return string(bs), err
+	}
+}
@@ -0,0 +1,45 @@
+package vegeta
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestExtractors(t *testing.T) {
+	t.Parallel()
+
+	body := []byte(`{"user": {"name": "goku", "age": 42, "power": [9000, 9001], "ki": {"max": true}}}`)
+	for _, tc := range []struct {
+		expr string
+		out  string
+		err  error
+	}{
+		{expr: "jsonpath:$.user.name", out: "goku"},
+		{expr: "jsonpath:$['user']['age']", out: "42"},
+		{expr: "jsonpath:$.user.power[1]", out: "9001"},
+		{expr: "jsonpath:$.user.power[-1]", out: "9001"},
+		{expr: "jsonpath:$.user.ki", out: `{"max":true}`},
+		{expr: "jsonpath:$.user.power[2]", err: fmt.Errorf(`jsonpath "$.user.power[2]": index 2 not found`)},
+		{expr: "jsonpath:$.vegeta", err: fmt.Errorf(`jsonpath "$.vegeta": "vegeta" not found`)},
+		{expr: "jsonpath:user", err: fmt.Errorf(`bad jsonpath "user": must start with $`)},
+		{expr: "regex:\"age\":\\s*(\\d+)", out: "42"},
+		{expr: "regex:goku", out: "goku"},
+		{expr: "regex:vegeta", err: fmt.Errorf(`regex "vegeta": no match`)},
+		{expr: "regex:(", err: fmt.Errorf("bad extractor: error parsing regexp: missing closing ): `(`")},
+		{expr: "xpath://user", err: fmt.Errorf("bad extractor kind: xpath")},
+		{expr: "goku", err: fmt.Errorf("bad extractor: goku")},
+	} {
+		ex, err := parseExtractor(tc.expr)
+
+		var out string
+		if err == nil {
+			out, err = ex.extract(body)
+		}
+
+		if got, want := fmt.Sprint(err), fmt.Sprint(tc.err); got != want {
+			t.Errorf("%s: got error %q, want %q", tc.expr, got, want)
+		} else if got, want := out, tc.out; got != want {
+			t.Errorf("%s: got %q, want %q", tc.expr, got, want)
+		}
+	}
+}
@@ -0,0 +1,133 @@
+package vegeta
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"syscall"
+	"time"
+)
+
+// Feedback holds the outcome of the hit of a Target, which is fed back to
+// the Targeter that returned it.
+type Feedback struct {
+	// Target is the Target that was hit.
+	Target *Target
+	// Code is the response status code, if a response was received.
+	Code uint16
+	// Header holds the response headers, if a response was received.
+	Header http.Header
+	// Body holds the response body, as limited by MaxBody.
+	Body []byte
+	// Latency is the time elapsed since the hit began.
+	Latency time.Duration
+	// BytesIn is the number of bytes read from the response body.
+	BytesIn uint64
+	// BytesOut is the number of bytes of the request body.
+	BytesOut uint64
+	// Err is the error that made the hit fail, if any. Unsuccessful
+	// status codes aren't errors.
+	Err error
+	// ErrorKind classifies Err.
+	ErrorKind ErrorKind
+}
+
+// ErrorKind classifies the errors of failed hits.
+type ErrorKind uint8
+
+// The supported ErrorKinds.
+const (
+	// NoError is the ErrorKind of hits without errors.
+	NoError ErrorKind = iota
+	// RequestError is the ErrorKind of Targets that can't be turned into requests.
+	RequestError
+	// TimeoutError is the ErrorKind of hits that timed out.
+	TimeoutError
+	// DNSError is the ErrorKind of hits whose host couldn't be resolved.
+	DNSError
+	// ConnectionRefusedError is the ErrorKind of hits whose connection was refused.
+	ConnectionRefusedError
+	// ConnectionResetError is the ErrorKind of hits whose connection was
+	// reset or closed by the peer.
+	ConnectionResetError
+	// TLSError is the ErrorKind of hits that failed to establish a TLS session.
+	TLSError
+	// OtherError is the ErrorKind of hits that failed for any other reason.
+	OtherError
+)
+
+var errorKinds = [...]string{
+	NoError:                "none",
+	RequestError:           "request",
+	TimeoutError:           "timeout",
+	DNSError:               "dns",
+	ConnectionRefusedError: "connection refused",
+	ConnectionResetError:   "connection reset",
+	TLSError:               "tls",
+	OtherError:             "other",
+}
+
+// String returns a human readable description of the ErrorKind.
+func (k ErrorKind) String() string {
+	if int(k) < len(errorKinds) {
+		return errorKinds[k]
+	}
+	return errorKinds[OtherError]
+}
+
+// requestError wraps the errors of turning a Target into a request.
+type requestError struct{ err error }
+
+func (e *requestError) Error() string { return e.err.Error() }
+func (e *requestError) Unwrap() error { return e.err }
+
+// classify returns the ErrorKind of the given hit error.
+func classify(err error) ErrorKind {
+	var (
+		reqErr  *requestError
+		netErr  net.Error
+		dnsErr  *net.DNSError
+		hostErr x509.HostnameError
+		authErr x509.UnknownAuthorityError
+		certErr x509.CertificateInvalidError
+		recErr  tls.RecordHeaderError
+	)
+
+	switch {
+	case err == nil:
+		return NoError
+	case errors.As(err, &reqErr):
+		return RequestError
+	case errors.As(err, &dnsErr):
+		return DNSError
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return TimeoutError
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return ConnectionRefusedError
+	case errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return ConnectionResetError
+	case errors.As(err, &hostErr),
+		errors.As(err, &authErr),
+		errors.As(err, &certErr),
+		errors.As(err, &recErr):
+		return TLSError
+	default:
+		return OtherError
+	}
+}
+
+// feedback delivers the given Feedback to the Targeter, adapting it to a
+// Result call for Targeters that don't implement FeedbackTargeter.
+func feedback(tr Targeter, fb *Feedback) {
+	if ft, ok := tr.(FeedbackTargeter); ok {
+		ft.Feedback(fb)
+		return
+	}
+	tr.Result(fb.Body, fb.Code, fb.Err)
+}
@@ -0,0 +1,95 @@
+package vegeta
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync/atomic"
+)
+
+// ErrNoRows is returned when a Feeder data source has no rows.
+var ErrNoRows = errors.New("feeder: no rows")
+
+// A Feeder round-robins over rows of template variables loaded from a data
+// file. It's safe for concurrent use.
+type Feeder struct {
+	rows []map[string]string
+	i    int64
+}
+
+// NewFeeder returns a Feeder which round-robins over the given rows.
+func NewFeeder(rows ...map[string]string) *Feeder {
+	return &Feeder{rows: rows, i: -1}
+}
+
+// Next returns the next row of the Feeder.
+func (f *Feeder) Next() map[string]string {
+	if len(f.rows) == 0 {
+		return nil
+	}
+	return f.rows[atomic.AddInt64(&f.i, 1)%int64(len(f.rows))]
+}
+
+// ReadCSVFeeder reads a Feeder out of CSV records. The first record is the
+// header which names the variable of each column.
+func ReadCSVFeeder(r io.Reader) (*Feeder, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("feeder: %s", err)
+	} else if len(records) < 2 {
+		return nil, ErrNoRows
+	}
+
+	names := records[0]
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		row := make(map[string]string, len(names))
+		for i, name := range names {
+			row[name] = rec[i]
+		}
+		rows = append(rows, row)
+	}
+
+	return NewFeeder(rows...), nil
+}
+
+// ReadJSONFeeder reads a Feeder out of JSON objects, one per line.
+// Non-string values are set to their JSON encoding.
+func ReadJSONFeeder(r io.Reader) (*Feeder, error) {
+	var rows []map[string]string
+	sc := bufio.NewScanner(r)
+	for sc.Scan() {
+		line := bytes.TrimSpace(sc.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var obj map[string]json.RawMessage
+		if err := json.Unmarshal(line, &obj); err != nil {
+			return nil, fmt.Errorf("feeder: %s", err)
+		}
+
+		row := make(map[string]string, len(obj))
+		for k, raw := range obj {
+			var s string
+			if err := json.Unmarshal(raw, &s); err == nil {
+				row[k] = s
+			} else {
+				row[k] = string(raw)
+			}
+		}
+		rows = append(rows, row)
+	}
+
+	if err := sc.Err(); err != nil {
+		return nil, fmt.Errorf("feeder: %s", err)
+	} else if len(rows) == 0 {
+		return nil, ErrNoRows
+	}
+
+	return NewFeeder(rows...), nil
+}
@@ -0,0 +1,77 @@
+package vegeta
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFeeders(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		read func(io.Reader) (*Feeder, error)
+		in   string
+		out  []map[string]string
+		err  error
+	}{
+		{
+			name: "csv",
+			read: ReadCSVFeeder,
+			in:   "user,power\ngoku,9000\n\"gohan, jr\",9001\n",
+			out: []map[string]string{
+				{"user": "goku", "power": "9000"},
+				{"user": "gohan, jr", "power": "9001"},
+			},
+		},
+		{
+			name: "csv/header only",
+			read: ReadCSVFeeder,
+			in:   "user,power\n",
+			err:  ErrNoRows,
+		},
+		{
+			name: "csv/bad record",
+			read: ReadCSVFeeder,
+			in:   "user,power\ngoku\n",
+			err:  fmt.Errorf("feeder: record on line 2: wrong number of fields"),
+		},
+		{
+			name: "json",
+			read: ReadJSONFeeder,
+			in:   "{\"user\": \"goku\", \"power\": 9000}\n\n{\"user\": \"gohan\", \"tags\": [\"jr\"]}\n",
+			out: []map[string]string{
+				{"user": "goku", "power": "9000"},
+				{"user": "gohan", "tags": `["jr"]`},
+			},
+		},
+		{
+			name: "json/empty",
+			read: ReadJSONFeeder,
+			in:   "\n",
+			err:  ErrNoRows,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := tc.read(strings.NewReader(tc.in))
+			if got, want := fmt.Sprint(err), fmt.Sprint(tc.err); got != want {
+				t.Fatalf("got error %q, want %q", got, want)
+			} else if err != nil {
+				return
+			}
+
+			// Rows are handed out in a round-robin fashion.
+			for i := 0; i < 2*len(tc.out); i++ {
+				if got, want := f.Next(), tc.out[i%len(tc.out)]; !reflect.DeepEqual(got, want) {
+					t.Errorf("row #%d: got %v, want %v", i, got, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,167 @@
+package vegeta
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// A formField is a field of a form body declared in a target, either as
+// name=value or, for file fields, as name=@path;type=image/png;filename=a.png.
+type formField struct {
+	name        string
+	value       string
+	path        string // path of the file of file fields
+	contentType string
+	filename    string
+}
+
+func parseFormField(line string) (f formField, err error) {
+	kv := strings.SplitN(line, "=", 2)
+	if f.name = strings.TrimSpace(kv[0]); f.name == "" || len(kv) != 2 {
+		return f, fmt.Errorf("bad form field: %s", line)
+	}
+
+	if f.value = strings.TrimSpace(kv[1]); !strings.HasPrefix(f.value, "@") {
+		return f, nil
+	}
+
+	params := strings.Split(f.value[1:], ";")
+	if f.path, f.value = strings.TrimSpace(params[0]), ""; f.path == "" {
+		return f, fmt.Errorf("bad form field: %s", line)
+	}
+
+	f.filename = filepath.Base(f.path)
+	for _, p := range params[1:] {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return f, fmt.Errorf("bad form field: %s", line)
+		}
+
+		switch v := strings.TrimSpace(kv[1]); strings.TrimSpace(kv[0]) {
+		case "type":
+			f.contentType = v
+		case "filename":
+			f.filename = v
+		default:
+			return f, fmt.Errorf("bad form field: %s", line)
+		}
+	}
+
+	return f, nil
+}
+
+// setFormBody sets the body of tgt to the given form fields, encoded
+// according to its Content-Type header. Targets without one are encoded as
+// multipart/form-data when they have file fields and as
+// application/x-www-form-urlencoded otherwise.
+func setFormBody(tgt *Target, fields []formField) error {
+	key, ct := "Content-Type", ""
+	for k, vs := range tgt.Header {
+		if strings.EqualFold(k, key) && len(vs) > 0 {
+			key, ct = k, vs[0]
+		}
+	}
+
+	files := false
+	for _, f := range fields {
+		files = files || f.path != ""
+	}
+
+	var (
+		mediaType string
+		params    map[string]string
+		err       error
+	)
+
+	if ct == "" && files {
+		mediaType = "multipart/form-data"
+	} else if ct == "" {
+		mediaType = "application/x-www-form-urlencoded"
+	} else if mediaType, params, err = mime.ParseMediaType(ct); err != nil {
+		return fmt.Errorf("bad form: %s", err)
+	}
+
+	switch mediaType {
+	case "multipart/form-data":
+		var buf bytes.Buffer
+		w := multipart.NewWriter(&buf)
+		if b := params["boundary"]; b != "" {
+			if err = w.SetBoundary(b); err != nil {
+				return fmt.Errorf("bad form: %s", err)
+			}
+		}
+
+		for _, f := range fields {
+			if err = writeFormField(w, f); err != nil {
+				return err
+			}
+		}
+
+		if err = w.Close(); err != nil {
+			return err
+		}
+
+		tgt.Body, ct = buf.Bytes(), w.FormDataContentType()
+	case "application/x-www-form-urlencoded":
+		if files {
+			return fmt.Errorf("bad form: file fields require a multipart/form-data Content-Type")
+		}
+
+		form := url.Values{}
+		for _, f := range fields {
+			form.Add(f.name, f.value)
+		}
+
+		tgt.Body, ct = []byte(form.Encode()), mediaType
+	default:
+		return fmt.Errorf("bad form: unsupported Content-Type: %s", ct)
+	}
+
+	// Header values may be shared with other Targets so they must not be
+	// modified in place.
+	tgt.Header[key] = []string{ct}
+
+	return nil
+}
+
+func writeFormField(w *multipart.Writer, f formField) error {
+	if f.path == "" {
+		return w.WriteField(f.name, f.value)
+	}
+
+	data, err := ioutil.ReadFile(f.path)
+	if err != nil {
+		return fmt.Errorf("bad form field: %s", err)
+	}
+
+	contentType := f.contentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	h := textproto.MIMEHeader{}
+	h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		escapeQuotes(f.name), escapeQuotes(f.filename)))
+	h.Set("Content-Type", contentType)
+
+	part, err := w.CreatePart(h)
+	if err != nil {
+		return err
+	}
+
+	_, err = part.Write(data)
+	return err
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
@@ -0,0 +1,207 @@
+package vegeta
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// A GroupBy returns the key of the group a Result belongs to.
+type GroupBy func(*Result) string
+
+// unknownGroup is the key of the group of Results missing the field they're
+// grouped by, like those written by older versions without the method and
+// URL of their Targets.
+const unknownGroup = "(unknown)"
+
+func orUnknown(key string) string {
+	if key == "" {
+		return unknownGroup
+	}
+	return key
+}
+
+// GroupByMethod groups Results by the method of their Targets.
+func GroupByMethod(r *Result) string { return orUnknown(r.Method) }
+
+// GroupByHost groups Results by the host of their Targets.
+func GroupByHost(r *Result) string {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		return unknownGroup
+	}
+	return orUnknown(u.Host)
+}
+
+// GroupByAttack groups Results by the name of their attack.
+func GroupByAttack(r *Result) string { return orUnknown(r.Attack) }
+
+// GroupByTag groups Results by the tag of their Targets.
+func GroupByTag(r *Result) string { return orUnknown(r.Tag) }
+
+// GroupByCode groups Results by their status code.
+func GroupByCode(r *Result) string { return strconv.Itoa(int(r.Code)) }
+
+// GroupByURL returns a GroupBy which groups Results by the URL of their
+// Targets templated with the given patterns. See TemplateURL.
+func GroupByURL(patterns []URLPattern) GroupBy {
+	return func(r *Result) string {
+		if r.URL == "" {
+			return unknownGroup
+		}
+		return TemplateURL(r.URL, patterns)
+	}
+}
+
+// ParseGroupBy parses a comma separated list of the url, method, host, tag,
+// attack and code groupings into a GroupBy whose keys join theirs with
+// spaces. URLs are templated with the given patterns.
+func ParseGroupBy(by string, patterns []URLPattern) (GroupBy, error) {
+	var groupBys []GroupBy
+	for _, name := range strings.Split(by, ",") {
+		switch strings.TrimSpace(name) {
+		case "url":
+			groupBys = append(groupBys, GroupByURL(patterns))
+		case "method":
+			groupBys = append(groupBys, GroupByMethod)
+		case "host":
+			groupBys = append(groupBys, GroupByHost)
+		case "tag":
+			groupBys = append(groupBys, GroupByTag)
+		case "attack":
+			groupBys = append(groupBys, GroupByAttack)
+		case "code":
+			groupBys = append(groupBys, GroupByCode)
+		default:
+			return nil, fmt.Errorf("bad grouping: %q", name)
+		}
+	}
+
+	if len(groupBys) == 1 {
+		return groupBys[0], nil
+	}
+
+	return func(r *Result) string {
+		keys := make([]string, len(groupBys))
+		for i, by := range groupBys {
+			keys[i] = by(r)
+		}
+		return strings.Join(keys, " ")
+	}, nil
+}
+
+// A URLPattern replaces the URL path segments which match it with its name
+// when templating URLs.
+type URLPattern struct {
+	// Name replaces the matching path segments, e.g. :id.
+	Name string
+
+	re *regexp.Regexp
+}
+
+// NewURLPattern returns a URLPattern which replaces the path segments fully
+// matching the given regular expression with the given name.
+func NewURLPattern(name, expr string) (URLPattern, error) {
+	re, err := regexp.Compile("^(?:" + expr + ")$")
+	if err != nil {
+		return URLPattern{}, fmt.Errorf("bad url pattern: %s", err)
+	}
+	return URLPattern{Name: name, re: re}, nil
+}
+
+// ParseURLPattern parses a URLPattern given as name=regexp.
+func ParseURLPattern(s string) (URLPattern, error) {
+	kv := strings.SplitN(s, "=", 2)
+	if len(kv) != 2 || kv[0] == "" || kv[1] == "" {
+		return URLPattern{}, fmt.Errorf("bad url pattern: %s", s)
+	}
+	return NewURLPattern(kv[0], kv[1])
+}
+
+// MustURLPattern is like NewURLPattern but panics if the regular
+// expression can't be compiled.
+func MustURLPattern(name, expr string) URLPattern {
+	p, err := NewURLPattern(name, expr)
+	if err != nil {
+		panic(err)
+	}
+	return p
+}
+
+// DefaultURLPatterns replace numeric ids, UUIDs and hexadecimal hashes
+// with :id.
+var DefaultURLPatterns = []URLPattern{
+	MustURLPattern(":id", `[0-9]+`),
+	MustURLPattern(":id", `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`),
+	MustURLPattern(":id", `[0-9a-fA-F]{16,}`),
+}
+
+// TemplateURL templates the given URL by replacing each of its path segments
+// with the name of the first of the given patterns it matches, so that the
+// URLs of the same endpoint collapse into one, e.g. /users/123 and
+// /users/456 into /users/:id. Queries and fragments are dropped.
+func TemplateURL(rawurl string, patterns []URLPattern) string {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return rawurl
+	}
+
+	segments := strings.Split(u.Path, "/")
+	for i, seg := range segments {
+		if seg == "" {
+			continue
+		}
+		for _, p := range patterns {
+			if p.re.MatchString(seg) {
+				segments[i] = p.Name
+				break
+			}
+		}
+	}
+
+	u.Path = strings.Join(segments, "/")
+	u.RawPath, u.RawQuery, u.Fragment = "", "", ""
+
+	return u.String()
+}
+
+// GroupedMetrics holds the Metrics of each group of Results.
+type GroupedMetrics struct {
+	// Groups maps the keys of the groups to their Metrics.
+	Groups map[string]*Metrics
+
+	by      GroupBy
+	buckets Buckets
+}
+
+// NewGroupedMetrics returns GroupedMetrics which group Results with the
+// given GroupBy. The Metrics of groups have a Histogram with the given
+// buckets, if any.
+func NewGroupedMetrics(by GroupBy, buckets Buckets) *GroupedMetrics {
+	return &GroupedMetrics{Groups: map[string]*Metrics{}, by: by, buckets: buckets}
+}
+
+// Add implements the Add method of the Report interface by adding the given
+// Result to the Metrics of its group.
+func (g *GroupedMetrics) Add(r *Result) {
+	key := g.by(r)
+	m, ok := g.Groups[key]
+	if !ok {
+		m = &Metrics{}
+		if len(g.buckets) > 0 {
+			m.Histogram = &Histogram{Buckets: g.buckets}
+		}
+		g.Groups[key] = m
+	}
+	m.Add(r)
+}
+
+// Close implements the Close method of the Report interface by closing the
+// Metrics of every group.
+func (g *GroupedMetrics) Close() {
+	for _, m := range g.Groups {
+		m.Close()
+	}
+}
@@ -0,0 +1,139 @@
+package vegeta
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateURL(t *testing.T) {
+	t.Parallel()
+
+	patterns := append([]URLPattern{MustURLPattern(":slug", `[a-z]+(-[a-z]+)+`)}, DefaultURLPatterns...)
+
+	for _, tc := range []struct {
+		in, want string
+	}{
+		{"http://api/users/123", "http://api/users/:id"},
+		{"http://api/users/123/posts/42?page=2#top", "http://api/users/:id/posts/:id"},
+		{"http://api/items/6ba7b810-9dad-11d1-80b4-00c04fd430c8", "http://api/items/:id"},
+		{"http://api/blobs/da39a3ee5e6b4b0d3255bfef95601890afd80709", "http://api/blobs/:id"},
+		{"http://api/posts/hello-big-world", "http://api/posts/:slug"},
+		{"http://api/users/me/", "http://api/users/me/"},
+		{"http://api/v2/cafe", "http://api/v2/cafe"},
+		{"/users/7", "/users/:id"},
+	} {
+		if got := TemplateURL(tc.in, patterns); got != tc.want {
+			t.Errorf("TemplateURL(%q): got %q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestParseURLPattern(t *testing.T) {
+	t.Parallel()
+
+	for _, s := range []string{"", ":id", "=[0-9]+", ":id=", ":id=[0-9"} {
+		if _, err := ParseURLPattern(s); err == nil {
+			t.Errorf("ParseURLPattern(%q): got no error", s)
+		}
+	}
+
+	p, err := ParseURLPattern(":id=[0-9]+")
+	if err != nil {
+		t.Fatal(err)
+	} else if got := TemplateURL("http://api/a1/1", []URLPattern{p}); got != "http://api/a1/:id" {
+		t.Errorf("got %q, want matches of whole segments only", got)
+	}
+}
+
+func TestParseGroupBy(t *testing.T) {
+	t.Parallel()
+
+	r := &Result{Attack: "smoke", Code: 404, Method: "GET", URL: "http://api:8080/users/123?q=1", Tag: "users"}
+
+	for _, tc := range []struct {
+		by, want string
+	}{
+		{"url", "http://api:8080/users/:id"},
+		{"method", "GET"},
+		{"host", "api:8080"},
+		{"tag", "users"},
+		{"attack", "smoke"},
+		{"code", "404"},
+		{"method,url", "GET http://api:8080/users/:id"},
+	} {
+		by, err := ParseGroupBy(tc.by, DefaultURLPatterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if got := by(r); got != tc.want {
+			t.Errorf("%s: got %q, want %q", tc.by, got, tc.want)
+		}
+	}
+
+	by, err := ParseGroupBy("url,method,tag", DefaultURLPatterns)
+	if err != nil {
+		t.Fatal(err)
+	} else if got, want := by(&Result{}), "(unknown) (unknown) (unknown)"; got != want {
+		t.Errorf("got %q, want %q without methods, URLs and tags", got, want)
+	}
+
+	if _, err := ParseGroupBy("url,path", nil); err == nil {
+		t.Error("got no error for an unknown grouping")
+	}
+}
+
+func TestGroupedMetrics(t *testing.T) {
+	t.Parallel()
+
+	g := NewGroupedMetrics(GroupByURL(DefaultURLPatterns), Buckets{0, time.Millisecond})
+	for i, url := range []string{"http://api/users/1", "http://api/users/2", "http://api/health"} {
+		g.Add(&Result{
+			Code:      200,
+			URL:       url,
+			Timestamp: time.Unix(int64(i), 0),
+			Latency:   time.Duration(i+1) * time.Millisecond,
+		})
+	}
+	g.Close()
+
+	if got, want := len(g.Groups), 2; got != want {
+		t.Fatalf("got %d groups, want %d", got, want)
+	}
+
+	users := g.Groups["http://api/users/:id"]
+	if users == nil {
+		t.Fatalf("got groups %v, want http://api/users/:id", g.Groups)
+	} else if got, want := users.Requests, uint64(2); got != want {
+		t.Errorf("got %d requests, want %d", got, want)
+	} else if got, want := users.Latencies.Max, 2*time.Millisecond; got != want {
+		t.Errorf("got max latency %s, want %s", got, want)
+	} else if users.Histogram == nil || users.Histogram.Total != 2 {
+		t.Errorf("got histogram %+v, want one of 2 results", users.Histogram)
+	}
+
+	var text bytes.Buffer
+	if err := NewGroupedTextReporter(g).Report(&text); err != nil {
+		t.Fatal(err)
+	}
+
+	health := strings.Index(text.String(), "Group: http://api/health\n")
+	if i := strings.Index(text.String(), "Group: http://api/users/:id\n"); health < 0 || i < health {
+		t.Errorf("got text report without sorted groups:\n%s", text.String())
+	}
+
+	var js bytes.Buffer
+	if err := NewGroupedJSONReporter(g).Report(&js); err != nil {
+		t.Fatal(err)
+	}
+
+	var groups map[string]struct{ Requests uint64 }
+	if err := json.Unmarshal(js.Bytes(), &groups); err != nil {
+		t.Fatal(err)
+	} else if got := groups["http://api/health"].Requests; got != 1 {
+		t.Errorf("got %d requests in JSON report, want 1", got)
+	}
+}
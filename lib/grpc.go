@@ -0,0 +1,393 @@
+package vegeta
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// gRPC Targets have URLs with these schemes, a host and port and the full
+// name of the called method as path, e.g.
+// grpc://localhost:50051/helloworld.Greeter/SayHello. Their bodies hold the
+// JSON encoded request messages and their headers the request metadata.
+const (
+	grpcScheme    = "grpc"
+	grpcTLSScheme = "grpcs"
+)
+
+func isGRPC(u *url.URL) bool {
+	return u.Scheme == grpcScheme || u.Scheme == grpcTLSScheme
+}
+
+// GRPCDescriptors returns a functional option which makes an Attacker resolve
+// the methods of gRPC Targets with the given descriptors instead of the
+// server reflection service of their servers.
+func GRPCDescriptors(files *protoregistry.Files) func(*Attacker) {
+	return func(a *Attacker) { a.grpcFiles = files }
+}
+
+// ReadProtoDescriptorSet reads a binary encoded protobuf FileDescriptorSet,
+// as written by protoc with --descriptor_set_out and --include_imports.
+func ReadProtoDescriptorSet(r io.Reader) (*protoregistry.Files, error) {
+	bs, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var set descriptorpb.FileDescriptorSet
+	if err = proto.Unmarshal(bs, &set); err != nil {
+		return nil, fmt.Errorf("bad descriptor set: %s", err)
+	}
+
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("bad descriptor set: %s", err)
+	}
+
+	return files, nil
+}
+
+// grpcConn is a gRPC client connection to a server shared by all workers.
+type grpcConn struct {
+	cc      *grpc.ClientConn
+	mu      sync.Mutex // serializes method resolutions
+	methods sync.Map   // full method names to their descriptors
+	errmu   sync.Mutex
+	dialErr error // error of the last dial to the server, if it failed
+}
+
+// grpcConn returns the connection to the server of the given gRPC URL,
+// which is established on first use and lives until the attack ends.
+func (a *Attacker) grpcConn(u *url.URL) (*grpcConn, error) {
+	key := u.Scheme + "://" + u.Host
+
+	a.grpcmu.Lock()
+	defer a.grpcmu.Unlock()
+
+	if c, ok := a.grpcConns[key]; ok {
+		return c, nil
+	}
+
+	creds := insecure.NewCredentials()
+	if u.Scheme == grpcTLSScheme {
+		c := DefaultTLSConfig
+		if a.tls != nil {
+			c = a.tls
+		}
+		creds = credentials.NewTLS(c.Clone())
+	}
+
+	// Connections are bound to local addresses and gated by connection
+	// policies like those of the transport, though their workers share them.
+	dial := a.dialer.DialContext
+	if a.laddrs != nil {
+		dial = a.laddrs.dial(nil)
+	}
+	if a.gate != nil {
+		dial = a.gate.dial(dial)
+	}
+
+	c := &grpcConn{}
+	cc, err := grpc.NewClient("passthrough:///"+u.Host,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithContextDialer(func(ctx context.Context, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, "tcp", addr)
+			c.errmu.Lock()
+			c.dialErr = err
+			c.errmu.Unlock()
+			return conn, err
+		}),
+	)
+	if err != nil {
+		return nil, err
+	}
+	c.cc = cc
+
+	if a.grpcConns == nil {
+		a.grpcConns = map[string]*grpcConn{}
+	}
+	a.grpcConns[key] = c
+
+	return c, nil
+}
+
+// closeGRPC closes the gRPC connections of the Attacker once an attack
+// ended. The next attack establishes new ones.
+func (a *Attacker) closeGRPC() {
+	a.grpcmu.Lock()
+	defer a.grpcmu.Unlock()
+
+	for _, c := range a.grpcConns {
+		c.cc.Close()
+	}
+	a.grpcConns = nil
+}
+
+// clientError returns the error of a failed call whose status the client
+// rather than the server failed it with, or nil. The gRPC status codes of
+// calls timed out by the Attacker or sent while the server can't be dialed
+// hide their errors, which are classified like those of HTTP requests.
+func (c *grpcConn) clientError(ctx context.Context, timeout time.Duration, err error, p *peer.Peer) error {
+	if err == nil {
+		return nil
+	} else if ctx.Err() == context.DeadlineExceeded {
+		return &timeoutError{"grpc: call", timeout}
+	} else if status.Code(err) != codes.Unavailable || p.Addr != nil {
+		return nil
+	}
+
+	c.errmu.Lock()
+	defer c.errmu.Unlock()
+
+	if c.dialErr != nil {
+		return fmt.Errorf("grpc: %w", c.dialErr)
+	}
+	return nil
+}
+
+// method returns the descriptor of the unary method with the given full
+// name, as in the path of gRPC URLs, resolved with the given files or, if
+// nil, with the server reflection service of the connected server.
+func (c *grpcConn) method(ctx context.Context, files *protoregistry.Files, name string) (protoreflect.MethodDescriptor, error) {
+	if md, ok := c.methods.Load(name); ok {
+		return md.(protoreflect.MethodDescriptor), nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if md, ok := c.methods.Load(name); ok {
+		return md.(protoreflect.MethodDescriptor), nil
+	}
+
+	i := strings.LastIndex(name, "/")
+	if i <= 0 || i == len(name)-1 {
+		return nil, &requestError{fmt.Errorf("grpc: bad method: %s", name)}
+	}
+	service, method := name[:i], name[i+1:]
+
+	if files == nil {
+		var err error
+		if files, err = c.reflect(ctx, service); err != nil {
+			return nil, err
+		}
+	}
+
+	d, err := files.FindDescriptorByName(protoreflect.FullName(service))
+	if err != nil {
+		return nil, &requestError{fmt.Errorf("grpc: unknown service: %s", service)}
+	}
+
+	sd, ok := d.(protoreflect.ServiceDescriptor)
+	if !ok {
+		return nil, &requestError{fmt.Errorf("grpc: not a service: %s", service)}
+	}
+
+	md := sd.Methods().ByName(protoreflect.Name(method))
+	if md == nil {
+		return nil, &requestError{fmt.Errorf("grpc: unknown method: %s", name)}
+	} else if md.IsStreamingClient() || md.IsStreamingServer() {
+		return nil, &requestError{fmt.Errorf("grpc: streaming method %s isn't supported", name)}
+	}
+
+	c.methods.Store(name, md)
+
+	return md, nil
+}
+
+// reflect returns the descriptors of the file defining the given service
+// and its dependencies, as returned by the server reflection service.
+func (c *grpcConn) reflect(ctx context.Context, service string) (*protoregistry.Files, error) {
+	stream, err := rpb.NewServerReflectionClient(c.cc).ServerReflectionInfo(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("grpc: server reflection: %w", err)
+	}
+	defer stream.CloseSend()
+
+	err = stream.Send(&rpb.ServerReflectionRequest{
+		MessageRequest: &rpb.ServerReflectionRequest_FileContainingSymbol{
+			FileContainingSymbol: service,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("grpc: server reflection: %w", err)
+	}
+
+	resp, err := stream.Recv()
+	if err != nil {
+		return nil, fmt.Errorf("grpc: server reflection: %w", err)
+	} else if e := resp.GetErrorResponse(); e != nil {
+		return nil, &requestError{fmt.Errorf("grpc: server reflection: %s", e.GetErrorMessage())}
+	}
+
+	var set descriptorpb.FileDescriptorSet
+	for _, bs := range resp.GetFileDescriptorResponse().GetFileDescriptorProto() {
+		var fd descriptorpb.FileDescriptorProto
+		if err = proto.Unmarshal(bs, &fd); err != nil {
+			return nil, fmt.Errorf("grpc: server reflection: %s", err)
+		}
+		set.File = append(set.File, &fd)
+	}
+
+	files, err := protodesc.NewFiles(&set)
+	if err != nil {
+		return nil, fmt.Errorf("grpc: server reflection: %s", err)
+	}
+
+	return files, nil
+}
+
+// doGRPC calls the unary method of the given gRPC request of tgt and records
+// its response in res, with the gRPC status code mapped onto an HTTP status
+// code. Calls failed by the client rather than the server return an error
+// instead. The request metadata are taken from the request headers, which
+// Authenticators may have set.
+func (a *Attacker) doGRPC(client *http.Client, req *http.Request, tgt *Target, res *Result) (http.Header, error) {
+	ctx := context.Background()
+	if client.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, client.Timeout)
+		defer cancel()
+	}
+
+	conn, err := a.grpcConn(req.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	name := strings.TrimPrefix(req.URL.Path, "/")
+	md, err := conn.method(ctx, a.grpcFiles, name)
+	if cerr := conn.clientError(ctx, client.Timeout, err, &peer.Peer{}); cerr != nil {
+		return nil, cerr
+	} else if err != nil {
+		return nil, err
+	}
+
+	in := dynamicpb.NewMessage(md.Input())
+	if len(tgt.Body) > 0 {
+		if err = protojson.Unmarshal(tgt.Body, in); err != nil {
+			return nil, &requestError{fmt.Errorf("grpc: bad request message: %s", err)}
+		}
+	}
+
+	var (
+		out             = dynamicpb.NewMessage(md.Output())
+		header, trailer metadata.MD
+		p               peer.Peer
+	)
+
+	ctx = metadata.NewOutgoingContext(ctx, grpcMetadata(req.Header))
+	err = conn.cc.Invoke(ctx, "/"+name, in, out, grpc.Header(&header), grpc.Trailer(&trailer), grpc.Peer(&p))
+	if cerr := conn.clientError(ctx, client.Timeout, err, &p); cerr != nil {
+		return nil, cerr
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil, err
+	}
+
+	hdr := make(http.Header, len(header)+len(trailer))
+	for _, md := range []metadata.MD{header, trailer} {
+		for k, vs := range md {
+			hdr[http.CanonicalHeaderKey(k)] = append(hdr[http.CanonicalHeaderKey(k)], vs...)
+		}
+	}
+
+	if a.metadata {
+		res.Proto = "grpc"
+		if p.Addr != nil {
+			res.RemoteAddr = p.Addr.String()
+		}
+	}
+	if (a.metadata || a.laddrs != nil) && p.LocalAddr != nil {
+		res.LocalAddr = p.LocalAddr.String()
+	}
+
+	res.Header = a.responseHeaders(hdr)
+	res.BytesOut = uint64(proto.Size(in))
+	res.Code = grpcHTTPStatus(st.Code())
+
+	if st.Code() == codes.OK {
+		res.BytesIn = uint64(proto.Size(out))
+		if res.Body, err = protojson.Marshal(out); err != nil {
+			return hdr, err
+		} else if a.maxBody >= 0 && int64(len(res.Body)) > a.maxBody {
+			res.Body = res.Body[:a.maxBody]
+		}
+	}
+
+	if !tgt.Success(res.Code) {
+		res.Error = fmt.Sprintf("grpc: %s: %s", st.Code(), st.Message())
+	}
+
+	return hdr, nil
+}
+
+// grpcMetadata returns the gRPC metadata of the given request headers,
+// leaving out those reserved by HTTP/2 and gRPC.
+func grpcMetadata(h http.Header) metadata.MD {
+	md := make(metadata.MD, len(h))
+	for k, vs := range h {
+		switch k = strings.ToLower(k); k {
+		case "host", "connection", "content-length", "content-type", "te", "transfer-encoding", "user-agent":
+			continue
+		}
+		md[k] = append(md[k], vs...)
+	}
+	return md
+}
+
+// grpcHTTPStatus maps gRPC status codes onto HTTP status codes like gRPC
+// gateways do.
+func grpcHTTPStatus(c codes.Code) uint16 {
+	switch c {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499 // Client Closed Request
+	case codes.InvalidArgument, codes.FailedPrecondition, codes.OutOfRange:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default: // Unknown, Internal, DataLoss
+		return http.StatusInternalServerError
+	}
+}
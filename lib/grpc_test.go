@@ -0,0 +1,342 @@
+package vegeta
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/reflection"
+	rpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// echoFile describes the test Echo service, whose Echo method responds with
+// the text of requests and their authorization metadata, failing with their
+// code if it's not zero.
+var echoFile = &descriptorpb.FileDescriptorProto{
+	Name:    proto.String("echo.proto"),
+	Package: proto.String("vegeta.test"),
+	Syntax:  proto.String("proto3"),
+	MessageType: []*descriptorpb.DescriptorProto{
+		{
+			Name: proto.String("EchoRequest"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				echoField("text", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				echoField("code", 2, descriptorpb.FieldDescriptorProto_TYPE_INT32),
+			},
+		},
+		{
+			Name: proto.String("EchoResponse"),
+			Field: []*descriptorpb.FieldDescriptorProto{
+				echoField("text", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+				echoField("auth", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING),
+			},
+		},
+	},
+	Service: []*descriptorpb.ServiceDescriptorProto{{
+		Name: proto.String("Echo"),
+		Method: []*descriptorpb.MethodDescriptorProto{
+			{
+				Name:       proto.String("Echo"),
+				InputType:  proto.String(".vegeta.test.EchoRequest"),
+				OutputType: proto.String(".vegeta.test.EchoResponse"),
+			},
+			{
+				Name:            proto.String("Stream"),
+				InputType:       proto.String(".vegeta.test.EchoRequest"),
+				OutputType:      proto.String(".vegeta.test.EchoResponse"),
+				ServerStreaming: proto.Bool(true),
+			},
+		},
+	}},
+}
+
+func echoField(name string, n int32, typ descriptorpb.FieldDescriptorProto_Type) *descriptorpb.FieldDescriptorProto {
+	return &descriptorpb.FieldDescriptorProto{
+		Name:     proto.String(name),
+		JsonName: proto.String(name),
+		Number:   proto.Int32(n),
+		Type:     typ.Enum(),
+		Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+	}
+}
+
+// echoServer starts a gRPC server of the Echo service, with server
+// reflection if reflect is true, and returns its address.
+func echoServer(t testing.TB, files *protoregistry.Files, reflect bool, opts ...grpc.ServerOption) (string, func()) {
+	d, err := files.FindDescriptorByName("vegeta.test.Echo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	md := d.(protoreflect.ServiceDescriptor).Methods().ByName("Echo")
+
+	echo := func(_ interface{}, ctx context.Context, dec func(interface{}) error, _ grpc.UnaryServerInterceptor) (interface{}, error) {
+		in := dynamicpb.NewMessage(md.Input())
+		if err := dec(in); err != nil {
+			return nil, err
+		}
+
+		if code := in.Get(md.Input().Fields().ByName("code")).Int(); code != 0 {
+			return nil, status.Error(codes.Code(code), "echo failed")
+		}
+
+		var auth string
+		if md, ok := metadata.FromIncomingContext(ctx); ok && len(md["authorization"]) > 0 {
+			auth = md["authorization"][0]
+		}
+
+		grpc.SetHeader(ctx, metadata.Pairs("x-echo", "true"))
+
+		out := dynamicpb.NewMessage(md.Output())
+		out.Set(md.Output().Fields().ByName("text"), in.Get(md.Input().Fields().ByName("text")))
+		out.Set(md.Output().Fields().ByName("auth"), protoreflect.ValueOfString(auth))
+
+		return out, nil
+	}
+
+	srv := grpc.NewServer(opts...)
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "vegeta.test.Echo",
+		HandlerType: (*interface{})(nil),
+		Methods:     []grpc.MethodDesc{{MethodName: "Echo", Handler: echo}},
+		Streams: []grpc.StreamDesc{{
+			StreamName:    "Stream",
+			ServerStreams: true,
+			Handler:       func(interface{}, grpc.ServerStream) error { return nil },
+		}},
+		Metadata: "echo.proto",
+	}, struct{}{})
+
+	if reflect {
+		rpb.RegisterServerReflectionServer(srv, reflection.NewServerV1(reflection.ServerOptions{
+			Services:           srv,
+			DescriptorResolver: files,
+		}))
+	}
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go srv.Serve(ln)
+
+	return ln.Addr().String(), srv.Stop
+}
+
+func TestGRPC(t *testing.T) {
+	t.Parallel()
+
+	set := &descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{echoFile}}
+	bs, err := proto.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files, err := ReadProtoDescriptorSet(bytes.NewReader(bs))
+	if err != nil {
+		t.Fatal(err)
+	} else if _, err = ReadProtoDescriptorSet(strings.NewReader("junk")); err == nil {
+		t.Fatal("got no error reading a bad descriptor set")
+	}
+
+	for _, tc := range []struct {
+		name    string
+		reflect bool
+		opts    []func(*Attacker)
+	}{
+		{"reflection", true, nil},
+		{"descriptors", false, []func(*Attacker){GRPCDescriptors(files)}},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			addr, stop := echoServer(t, files, tc.reflect)
+			defer stop()
+
+			atk := NewAttacker(append(tc.opts, Metadata(true), ResponseHeaders("x-echo"))...)
+			hit := func(method, body string) *Result {
+				tgt := Target{
+					Method: "POST",
+					URL:    "grpc://" + addr + "/vegeta.test.Echo/" + method,
+					Body:   []byte(body),
+					Header: http.Header{"Authorization": {"Bearer goku"}},
+				}
+				return atk.hit(NewStaticTargeter(tgt).NewTargeter(), "")
+			}
+
+			res := hit("Echo", `{"text":"kamehameha"}`)
+			if res.Code != 200 || res.Error != "" {
+				t.Fatalf("got code %d and error %q, want success", res.Code, res.Error)
+			}
+
+			var body map[string]string
+			if err := json.Unmarshal(res.Body, &body); err != nil {
+				t.Fatal(err)
+			} else if body["text"] != "kamehameha" || body["auth"] != "Bearer goku" {
+				t.Errorf("got body %s, want the echoed text and authorization", res.Body)
+			}
+
+			if res.BytesOut == 0 || res.BytesIn == 0 {
+				t.Errorf("got %d bytes out and %d bytes in, want message sizes", res.BytesOut, res.BytesIn)
+			} else if res.Proto != "grpc" || res.RemoteAddr != addr {
+				t.Errorf("got proto %q and remote address %q, want grpc and %s", res.Proto, res.RemoteAddr, addr)
+			} else if res.Header.Get("X-Echo") != "true" {
+				t.Errorf("got headers %v, want X-Echo", res.Header)
+			}
+
+			for _, tc := range []struct {
+				code codes.Code
+				want uint16
+			}{
+				{codes.NotFound, 404},
+				{codes.Unavailable, 503},
+				{codes.Unauthenticated, 401},
+				{codes.Internal, 500},
+			} {
+				res = hit("Echo", `{"code":`+strconv.Itoa(int(tc.code))+`}`)
+				if res.Code != tc.want {
+					t.Errorf("%s: got code %d, want %d", tc.code, res.Code, tc.want)
+				} else if want := "grpc: " + tc.code.String() + ": echo failed"; res.Error != want {
+					t.Errorf("%s: got error %q, want %q", tc.code, res.Error, want)
+				}
+			}
+
+			for _, tc := range []struct{ method, body, err string }{
+				{"Stream", "{}", "streaming method"},
+				{"Nope", "{}", "unknown method"},
+				{"Echo", `{"text":1}`, "bad request message"},
+			} {
+				if res = hit(tc.method, tc.body); !strings.Contains(res.Error, tc.err) {
+					t.Errorf("%s: got error %q, want %q", tc.method, res.Error, tc.err)
+				}
+			}
+		})
+	}
+}
+
+func TestGRPCHTTPStatus(t *testing.T) {
+	t.Parallel()
+
+	for c := codes.OK; c <= codes.Unauthenticated; c++ {
+		if code := grpcHTTPStatus(c); http.StatusText(int(code)) == "" && code != 499 {
+			t.Errorf("%s: got unknown status code %d", c, code)
+		}
+	}
+}
+
+func TestGRPCClientErrors(t *testing.T) {
+	t.Parallel()
+
+	files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{echoFile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	addr, stop := echoServer(t, files, false)
+	defer stop()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	closed := ln.Addr().String()
+	ln.Close()
+
+	for _, tc := range []struct {
+		name string
+		addr string
+		opts []func(*Attacker)
+		kind ErrorKind
+	}{
+		{"ok", addr, nil, NoError},
+		{"timeout", addr, []func(*Attacker){Timeout(time.Nanosecond)}, TimeoutError},
+		{"refused", closed, nil, ConnectionRefusedError},
+	} {
+		tgt := Target{Method: "POST", URL: "grpc://" + tc.addr + "/vegeta.test.Echo/Echo", Body: []byte("{}")}
+		tr := &feedbackRecorder{Targeter: NewStaticTargeter(tgt).NewTargeter()}
+
+		res := NewAttacker(append(tc.opts, GRPCDescriptors(files))...).hit(tr, "")
+		if len(tr.fbs) != 1 {
+			t.Fatalf("%s: got %d feedbacks, want 1", tc.name, len(tr.fbs))
+		} else if fb := tr.fbs[0]; fb.ErrorKind != tc.kind {
+			t.Errorf("%s: got error kind %s (%v), want %s", tc.name, fb.ErrorKind, fb.Err, tc.kind)
+		} else if tc.kind != NoError && res.Code != 0 {
+			t.Errorf("%s: got code %d, want none", tc.name, res.Code)
+		}
+	}
+
+	// Attacks close their connections once they end.
+	atk := NewAttacker(GRPCDescriptors(files))
+	tgt := Target{Method: "POST", URL: "grpc://" + addr + "/vegeta.test.Echo/Echo", Body: []byte("{}")}
+	for res := range atk.Attack(NewStaticTargeter(tgt), ConstantPacer{Freq: 100, Per: time.Second}, 50*time.Millisecond, "") {
+		if res.Code != 200 {
+			t.Fatalf("got code %d and error %q, want success", res.Code, res.Error)
+		}
+	}
+
+	if len(atk.grpcConns) != 0 {
+		t.Errorf("got %d open connections after the attack ended", len(atk.grpcConns))
+	}
+}
+
+func TestGRPCConnections(t *testing.T) {
+	t.Parallel()
+
+	files, err := protodesc.NewFiles(&descriptorpb.FileDescriptorSet{File: []*descriptorpb.FileDescriptorProto{echoFile}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ts := httptest.NewTLSServer(nil)
+	defer ts.Close()
+	cert, roots := ts.TLS.Certificates[0], ts.Client().Transport.(*http.Transport).TLSClientConfig.RootCAs
+
+	addr, stop := echoServer(t, files, false, grpc.Creds(credentials.NewServerTLSFromCert(&cert)))
+	defer stop()
+
+	for _, tc := range []struct {
+		name  string
+		opts  []func(*Attacker)
+		err   string
+		laddr string
+	}{
+		{"trusted", []func(*Attacker){TLSConfig(&tls.Config{RootCAs: roots})}, "", ""},
+		// HTTP/3 replaces the transport but not the TLS config of gRPC.
+		{"untrusted", []func(*Attacker){TLSConfig(&tls.Config{RootCAs: x509.NewCertPool()}), HTTP3(true)}, "certificate", ""},
+		{"local addresses", []func(*Attacker){
+			TLSConfig(&tls.Config{RootCAs: roots}),
+			LocalAddrs([]net.IPAddr{{IP: net.IPv4(127, 0, 0, 1)}}, RoundRobinAddrs, PortRange{}),
+		}, "", "127.0.0.1:"},
+	} {
+		atk := NewAttacker(append(tc.opts, GRPCDescriptors(files))...)
+		tgt := Target{Method: "POST", URL: "grpcs://" + addr + "/vegeta.test.Echo/Echo", Body: []byte("{}")}
+		res := atk.hit(NewStaticTargeter(tgt).NewTargeter(), "")
+		atk.closeGRPC()
+
+		if !strings.Contains(res.Error, tc.err) || (tc.err == "") != (res.Error == "") {
+			t.Errorf("%s: got error %q, want %q", tc.name, res.Error, tc.err)
+		} else if !strings.HasPrefix(res.LocalAddr, tc.laddr) || (tc.laddr == "") != (res.LocalAddr == "") {
+			t.Errorf("%s: got local address %q, want %q", tc.name, res.LocalAddr, tc.laddr)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+package vegeta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// HAROptions configures which entries of a HAR file NewHARTargeter turns
+// into Targets.
+type HAROptions struct {
+	// Hosts is the list of request hosts to include. Entries starting with
+	// "*." match any subdomain. All hosts are included when empty.
+	Hosts []string
+	// ContentTypes is the list of response media types to include, e.g.
+	// "application/json". Entries ending in "/*" match any subtype.
+	// All content types are included when empty.
+	ContentTypes []string
+}
+
+// harLog is the subset of the HTTP Archive (HAR) 1.2 format we care about.
+// See http://www.softwareishard.com/blog/har-12-spec/
+type harLog struct {
+	Log struct {
+		Entries []harEntry `json:"entries"`
+	} `json:"log"`
+}
+
+type harEntry struct {
+	StartedDateTime time.Time `json:"startedDateTime"`
+	Request         struct {
+		Method   string         `json:"method"`
+		URL      string         `json:"url"`
+		Headers  []harNameValue `json:"headers"`
+		Cookies  []harNameValue `json:"cookies"`
+		PostData *struct {
+			MimeType string         `json:"mimeType"`
+			Text     string         `json:"text"`
+			Params   []harNameValue `json:"params"`
+		} `json:"postData"`
+	} `json:"request"`
+	Response struct {
+		Content struct {
+			MimeType string `json:"mimeType"`
+		} `json:"content"`
+	} `json:"response"`
+}
+
+type harNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// NewHARTargeter returns a Replay of the requests recorded in the HTTP
+// Archive (HAR) read from the given io.Reader, in the order they were started.
+// Requests keep their method, headers, cookies and body. Use the Replay's
+// Pacer to hit them with the timing they were recorded with.
+//
+// body will be set as the Target's body if no body is recorded.
+// hdr will be merged with each Target's headers.
+func NewHARTargeter(src io.Reader, body []byte, hdr http.Header, opts HAROptions) (*Replay, error) {
+	var har harLog
+	if err := json.NewDecoder(src).Decode(&har); err != nil {
+		return nil, fmt.Errorf("bad har: %s", err)
+	}
+
+	var (
+		tgts    []Target
+		offsets []time.Duration
+		began   time.Time
+	)
+
+	entries := har.Log.Entries
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].StartedDateTime.Before(entries[j].StartedDateTime)
+	})
+
+	for i := range entries {
+		e := &entries[i]
+		u, err := url.Parse(e.Request.URL)
+		if err != nil {
+			return nil, fmt.Errorf("bad har entry #%d: %s", i+1, err)
+		}
+
+		if !harMatch(opts.Hosts, u.Hostname(), matchHost) ||
+			!harMatch(opts.ContentTypes, e.Response.Content.MimeType, matchContentType) {
+			continue
+		}
+
+		tgt, err := harTarget(e, body, hdr)
+		if err != nil {
+			return nil, fmt.Errorf("bad har entry #%d: %s", i+1, err)
+		}
+
+		if len(tgts) == 0 {
+			began = e.StartedDateTime
+		}
+
+		tgts = append(tgts, *tgt)
+		offsets = append(offsets, e.StartedDateTime.Sub(began))
+	}
+
+	if len(tgts) == 0 {
+		return nil, ErrNoTargets
+	}
+
+	return NewReplay(func(tgt *Target) (time.Duration, error) {
+		if len(tgts) == 0 {
+			return 0, ErrNoTargets
+		}
+		offset := offsets[0]
+		*tgt, tgts, offsets = tgts[0], tgts[1:], offsets[1:]
+		return offset, nil
+	}), nil
+}
+
+func harTarget(e *harEntry, body []byte, hdr http.Header) (*Target, error) {
+	switch {
+	case e.Request.Method == "":
+		return nil, ErrNoMethod
+	case e.Request.URL == "":
+		return nil, ErrNoURL
+	}
+
+	tgt := Target{
+		Method: e.Request.Method,
+		URL:    e.Request.URL,
+		Body:   body,
+		Header: http.Header{},
+	}
+
+	for k, vs := range hdr {
+		tgt.Header[k] = append(tgt.Header[k], vs...)
+	}
+
+	for _, h := range e.Request.Headers {
+		switch {
+		case strings.HasPrefix(h.Name, ":"): // HTTP/2 pseudo-headers
+		case strings.EqualFold(h.Name, "Content-Length"):
+		default:
+			tgt.Header[h.Name] = append(tgt.Header[h.Name], h.Value)
+		}
+	}
+
+	if len(e.Request.Cookies) > 0 && tgt.Header.Get("Cookie") == "" {
+		cookies := make([]string, len(e.Request.Cookies))
+		for i, c := range e.Request.Cookies {
+			cookies[i] = (&http.Cookie{Name: c.Name, Value: c.Value}).String()
+		}
+		tgt.Header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	if pd := e.Request.PostData; pd != nil {
+		if pd.Text != "" {
+			tgt.Body = []byte(pd.Text)
+		} else if len(pd.Params) > 0 {
+			form := url.Values{}
+			for _, p := range pd.Params {
+				form.Add(p.Name, p.Value)
+			}
+			tgt.Body = []byte(form.Encode())
+		}
+	}
+
+	return &tgt, nil
+}
+
+// harMatch returns true if the given value matches any of the patterns
+// or if there are no patterns.
+func harMatch(patterns []string, value string, match func(pattern, value string) bool) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+
+	for _, p := range patterns {
+		if match(p, value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchHost(pattern, host string) bool {
+	if strings.HasPrefix(pattern, "*.") {
+		return strings.HasSuffix(host, pattern[1:])
+	}
+	return strings.EqualFold(pattern, host)
+}
+
+func matchContentType(pattern, contentType string) bool {
+	mt, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	} else if strings.HasSuffix(pattern, "/*") {
+		return strings.HasPrefix(mt, pattern[:len(pattern)-1])
+	}
+	return strings.EqualFold(pattern, mt)
+}
@@ -0,0 +1,192 @@
+package vegeta
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testHAR = `{"log": {"entries": [
+	{
+		"startedDateTime": "2018-01-01T00:00:01.500Z",
+		"request": {
+			"method": "POST",
+			"url": "http://api.goku.io/login",
+			"headers": [
+				{"name": ":authority", "value": "api.goku.io"},
+				{"name": "Content-Length", "value": "27"},
+				{"name": "Content-Type", "value": "application/x-www-form-urlencoded"}
+			],
+			"cookies": [{"name": "session", "value": "abc"}],
+			"postData": {
+				"mimeType": "application/x-www-form-urlencoded",
+				"params": [{"name": "user", "value": "goku"}, {"name": "power", "value": "9000"}]
+			}
+		},
+		"response": {"content": {"mimeType": "application/json; charset=utf-8"}}
+	},
+	{
+		"startedDateTime": "2018-01-01T00:00:00Z",
+		"request": {
+			"method": "GET",
+			"url": "http://goku.io/",
+			"headers": [{"name": "Accept", "value": "text/html"}]
+		},
+		"response": {"content": {"mimeType": "text/html"}}
+	},
+	{
+		"startedDateTime": "2018-01-01T00:00:02Z",
+		"request": {
+			"method": "GET",
+			"url": "http://cdn.vegeta.io/logo.png",
+			"headers": []
+		},
+		"response": {"content": {"mimeType": "image/png"}}
+	},
+	{
+		"startedDateTime": "2018-01-01T00:00:03Z",
+		"request": {
+			"method": "PUT",
+			"url": "http://api.goku.io/power",
+			"headers": [{"name": "Cookie", "value": "session=xyz"}],
+			"cookies": [{"name": "session", "value": "xyz"}],
+			"postData": {"mimeType": "application/json", "text": "{\"power\": 9001}"}
+		},
+		"response": {"content": {"mimeType": "application/json"}}
+	}
+]}}`
+
+func TestHARTargeter(t *testing.T) {
+	t.Parallel()
+
+	hdr := http.Header{"X-Attack": []string{"vegeta"}}
+	for _, tc := range []struct {
+		name    string
+		opts    HAROptions
+		targets []Target
+		offsets []time.Duration
+		err     error
+	}{
+		{
+			name: "all",
+			targets: []Target{
+				{
+					Method: "GET",
+					URL:    "http://goku.io/",
+					Body:   []byte("default"),
+					Header: http.Header{"X-Attack": []string{"vegeta"}, "Accept": []string{"text/html"}},
+				},
+				{
+					Method: "POST",
+					URL:    "http://api.goku.io/login",
+					Body:   []byte("power=9000&user=goku"),
+					Header: http.Header{
+						"X-Attack":     []string{"vegeta"},
+						"Content-Type": []string{"application/x-www-form-urlencoded"},
+						"Cookie":       []string{"session=abc"},
+					},
+				},
+				{
+					Method: "GET",
+					URL:    "http://cdn.vegeta.io/logo.png",
+					Body:   []byte("default"),
+					Header: http.Header{"X-Attack": []string{"vegeta"}},
+				},
+				{
+					Method: "PUT",
+					URL:    "http://api.goku.io/power",
+					Body:   []byte(`{"power": 9001}`),
+					Header: http.Header{"X-Attack": []string{"vegeta"}, "Cookie": []string{"session=xyz"}},
+				},
+			},
+			offsets: []time.Duration{0, 1500 * time.Millisecond, 2 * time.Second, 3 * time.Second},
+		},
+		{
+			name: "hosts",
+			opts: HAROptions{Hosts: []string{"*.vegeta.io", "goku.io"}},
+			targets: []Target{
+				{
+					Method: "GET",
+					URL:    "http://goku.io/",
+					Body:   []byte("default"),
+					Header: http.Header{"X-Attack": []string{"vegeta"}, "Accept": []string{"text/html"}},
+				},
+				{
+					Method: "GET",
+					URL:    "http://cdn.vegeta.io/logo.png",
+					Body:   []byte("default"),
+					Header: http.Header{"X-Attack": []string{"vegeta"}},
+				},
+			},
+			offsets: []time.Duration{0, 2 * time.Second},
+		},
+		{
+			name: "content types",
+			opts: HAROptions{ContentTypes: []string{"application/json", "image/*"}},
+			targets: []Target{
+				{
+					Method: "POST",
+					URL:    "http://api.goku.io/login",
+					Body:   []byte("power=9000&user=goku"),
+					Header: http.Header{
+						"X-Attack":     []string{"vegeta"},
+						"Content-Type": []string{"application/x-www-form-urlencoded"},
+						"Cookie":       []string{"session=abc"},
+					},
+				},
+				{
+					Method: "GET",
+					URL:    "http://cdn.vegeta.io/logo.png",
+					Body:   []byte("default"),
+					Header: http.Header{"X-Attack": []string{"vegeta"}},
+				},
+				{
+					Method: "PUT",
+					URL:    "http://api.goku.io/power",
+					Body:   []byte(`{"power": 9001}`),
+					Header: http.Header{"X-Attack": []string{"vegeta"}, "Cookie": []string{"session=xyz"}},
+				},
+			},
+			offsets: []time.Duration{0, 500 * time.Millisecond, 1500 * time.Millisecond},
+		},
+		{
+			name: "no matches",
+			opts: HAROptions{Hosts: []string{"vegeta.io"}},
+			err:  ErrNoTargets,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			r, err := NewHARTargeter(strings.NewReader(testHAR), []byte("default"), hdr, tc.opts)
+			if got, want := fmt.Sprint(err), fmt.Sprint(tc.err); got != want {
+				t.Fatalf("got error %q, want %q", got, want)
+			} else if err != nil {
+				return
+			}
+
+			for i, want := range tc.targets {
+				var got Target
+				offset, err := r.decode(&got)
+				if err != nil {
+					t.Fatalf("#%d: %s", i, err)
+				} else if !got.Equal(&want) {
+					t.Errorf("#%d: got: %#v, want: %#v", i, got, want)
+				} else if offset != tc.offsets[i] {
+					t.Errorf("#%d: got offset %s, want %s", i, offset, tc.offsets[i])
+				}
+			}
+
+			if _, err := r.decode(&Target{}); err != ErrNoTargets {
+				t.Errorf("got error %v, want %v", err, ErrNoTargets)
+			}
+		})
+	}
+
+	if _, err := NewHARTargeter(strings.NewReader("{"), nil, nil, HAROptions{}); err == nil {
+		t.Error("got nil error for bad har")
+	}
+}
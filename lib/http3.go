@@ -0,0 +1,108 @@
+package vegeta
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// HTTP3 returns a functional option which makes an Attacker send its requests
+// over HTTP/3, on QUIC connections, instead of HTTP/1.1 or HTTP/2. The TLS
+// configuration and local address of the Attacker still apply, whichever
+// order their options are given in. It requires the default transport, so
+// it can't be used with H2C, Client, UnixSocket, connection policies or
+// multiple local addresses, and the Attacker records an error otherwise. Results of its
+// hits record the protocol of their responses.
+func HTTP3(enabled bool) func(*Attacker) {
+	return func(a *Attacker) { a.http3 = enabled }
+}
+
+// wireHTTP3 replaces the transport of the Attacker with an HTTP/3 one which
+// uses its final TLS configuration.
+func (a *Attacker) wireHTTP3() error {
+	if !a.http3 {
+		return nil
+	}
+
+	tr, ok := a.client.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("HTTP/3 requires an *http.Transport, not %T", a.client.Transport)
+	} else if a.unixSocket != "" {
+		return errors.New("HTTP/3 can't be used with a unix socket")
+	} else if a.conns != nil || a.laddrs != nil {
+		return errors.New("HTTP/3 can't be used with connection policies or multiple local addresses")
+	}
+
+	a.client.Transport = &http3.Transport{
+		TLSClientConfig: tr.TLSClientConfig,
+		Dial:            a.dialQUIC,
+	}
+	return nil
+}
+
+// dialQUIC dials a QUIC connection to the given address over the UDP socket
+// of the Attacker, which all of its QUIC connections share.
+func (a *Attacker) dialQUIC(ctx context.Context, addr string, tlsc *tls.Config, qc *quic.Config) (*quic.Conn, error) {
+	tr, err := a.quicTransport()
+	if err != nil {
+		return nil, err
+	}
+
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	return tr.DialEarly(ctx, raddr, tlsc, qc)
+}
+
+// quicTransport returns the QUIC transport of the Attacker, listening on a
+// UDP socket bound to its local address, which is created on first use.
+func (a *Attacker) quicTransport() (*quic.Transport, error) {
+	a.quicmu.Lock()
+	defer a.quicmu.Unlock()
+
+	if a.quic != nil {
+		return a.quic, nil
+	}
+
+	// Unspecified local addresses listen on all of them, IPv4 and IPv6 alike.
+	var laddr *net.UDPAddr
+	if tcp, ok := a.dialer.LocalAddr.(*net.TCPAddr); ok && !tcp.IP.IsUnspecified() {
+		laddr = &net.UDPAddr{IP: tcp.IP, Zone: tcp.Zone}
+	}
+
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+
+	a.quic = &quic.Transport{Conn: conn}
+	return a.quic, nil
+}
+
+// closeQUIC closes the QUIC connections of the Attacker, along with its QUIC
+// transport and UDP socket, once an attack ended. The next attack opens new
+// ones.
+func (a *Attacker) closeQUIC() {
+	a.quicmu.Lock()
+	defer a.quicmu.Unlock()
+
+	if a.quic == nil {
+		return
+	}
+
+	if tr, ok := a.client.Transport.(*http3.Transport); ok {
+		tr.CloseIdleConnections()
+	}
+
+	a.quic.Close()
+	a.quic.Conn.Close()
+	a.quic = nil
+}
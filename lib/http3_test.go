@@ -0,0 +1,102 @@
+package vegeta
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// http3Server starts an HTTP/3 server of the given handler on a loopback UDP
+// socket and returns its URL.
+func http3Server(t testing.TB, h http.Handler) (string, func()) {
+	// Borrow the self-signed certificate of an httptest TLS server.
+	tlsServer := httptest.NewTLSServer(h)
+	tlsServer.Close()
+
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := &http3.Server{
+		Handler:   h,
+		TLSConfig: http3.ConfigureTLSConfig(&tls.Config{Certificates: tlsServer.TLS.Certificates}),
+	}
+	go srv.Serve(conn)
+
+	return "https://" + conn.LocalAddr().String(), func() {
+		srv.Close()
+		conn.Close()
+	}
+}
+
+func TestHTTP3(t *testing.T) {
+	t.Parallel()
+
+	url, stop := http3Server(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Header().Set("X-Proto", r.Proto)
+		w.Write(body)
+	}))
+	defer stop()
+
+	atk := NewAttacker(LocalAddr(net.IPAddr{IP: net.IPv4(127, 0, 0, 1)}), HTTP3(true), ResponseHeaders("x-proto"))
+	tgt := Target{Method: "POST", URL: url, Body: []byte("quic")}
+
+	for i := 0; i < 2; i++ {
+		res := atk.hit(NewStaticTargeter(tgt).NewTargeter(), "")
+		if res.Code != 200 || res.Error != "" {
+			t.Fatalf("got code %d and error %q, want success", res.Code, res.Error)
+		} else if res.Proto != "HTTP/3.0" || res.Header.Get("X-Proto") != "HTTP/3.0" {
+			t.Errorf("got proto %q and request proto %q, want HTTP/3.0", res.Proto, res.Header.Get("X-Proto"))
+		} else if string(res.Body) != "quic" || res.BytesOut != 4 || res.BytesIn != 4 {
+			t.Errorf("got body %q with %d bytes out and %d in, want echo", res.Body, res.BytesOut, res.BytesIn)
+		}
+	}
+
+	// Attacks close their QUIC connections and UDP socket once they end.
+	for res := range atk.Attack(NewStaticTargeter(tgt), ConstantPacer{Freq: 100, Per: time.Second}, 50*time.Millisecond, "") {
+		if res.Code != 200 {
+			t.Fatalf("got code %d and error %q, want success", res.Code, res.Error)
+		}
+	}
+
+	if atk.quic != nil {
+		t.Error("got an open QUIC transport after the attack ended")
+	}
+
+	// The TLS configuration applies when given after HTTP3, which doesn't
+	// skip the verification of the self-signed certificate then.
+	res := NewAttacker(HTTP3(true), TLSConfig(&tls.Config{})).hit(NewStaticTargeter(tgt).NewTargeter(), "")
+	if !strings.Contains(res.Error, "certificate") {
+		t.Errorf("got error %q, want a certificate error", res.Error)
+	}
+
+	// HTTP/3 can't be used with other transports or unix sockets.
+	for _, opt := range []func(*Attacker){
+		H2C(true),
+		Client(&http.Client{Transport: &http3.Transport{}}),
+		UnixSocket("/tmp/goku.sock"),
+	} {
+		if err := NewAttacker(HTTP3(true), opt).Err(); err == nil {
+			t.Error("got no error with an unsupported option")
+		}
+	}
+
+	// The responses of other attacks don't record their protocol without
+	// metadata.
+	plain := httptest.NewServer(http.NotFoundHandler())
+	defer plain.Close()
+
+	res = NewAttacker(HTTP3(false)).hit(NewStaticTargeter(Target{Method: "GET", URL: plain.URL}).NewTargeter(), "")
+	if res.Code != 404 || res.Proto != "" {
+		t.Errorf("got code %d and proto %q, want a plain HTTP/1.1 response", res.Code, res.Proto)
+	}
+}
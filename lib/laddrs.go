@@ -0,0 +1,133 @@
+package vegeta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"syscall"
+)
+
+// LocalAddrMode sets how an Attacker assigns its local addresses to its
+// connections.
+type LocalAddrMode uint8
+
+// Supported LocalAddrModes.
+const (
+	// RoundRobinAddrs binds each new connection to the next local address.
+	RoundRobinAddrs LocalAddrMode = iota
+	// WorkerAddrs binds the connections of each worker to a single local
+	// address, with workers assigned the next one as they start.
+	WorkerAddrs
+)
+
+// PortRange is a range of local ports, from First to Last inclusive.
+type PortRange struct {
+	First, Last uint16
+}
+
+// LocalAddrs returns a functional option which binds the connections of an
+// Attacker to the given local IP addresses, assigned as set by mode, rather
+// than to a single one, which runs out of ephemeral ports when connections
+// churn. Without addresses, connections are bound to the local address set
+// by LocalAddr. Connections are bound to the ports of the given range, in
+// turn, unless it's zero, in which case ports are ephemeral. Results record
+// the local address of the connection their hits were sent over. Only TCP
+// connections of an *http.Transport are supported, so its attacks fail
+// otherwise, e.g. with UnixSocket, H2C or HTTP3, as reported by the Err method
+// of the Attacker.
+func LocalAddrs(addrs []net.IPAddr, mode LocalAddrMode, ports PortRange) func(*Attacker) {
+	return func(a *Attacker) {
+		if len(addrs) == 0 && ports == (PortRange{}) {
+			a.laddrs = nil
+		} else {
+			a.laddrs = &localAddrs{addrs: addrs, mode: mode, ports: ports}
+		}
+	}
+}
+
+// wireLocalAddrs binds the dials of the transport of the Attacker to its
+// local addresses, if any, on top of its dialer.
+func (a *Attacker) wireLocalAddrs() error {
+	if a.laddrs == nil {
+		return nil
+	}
+
+	tr, ok := a.client.Transport.(*http.Transport)
+	if !ok {
+		return fmt.Errorf("local addresses require an *http.Transport, not %T", a.client.Transport)
+	} else if a.unixSocket != "" {
+		return errors.New("local addresses can't be used with a unix socket")
+	}
+
+	a.laddrs.dialer = a.dialer
+	tr.DialContext = a.laddrs.dial(nil)
+	return nil
+}
+
+// localAddrs binds dialed connections to local addresses and ports.
+type localAddrs struct {
+	dialer *net.Dialer
+	addrs  []net.IPAddr
+	mode   LocalAddrMode
+	ports  PortRange
+	next   uint64 // next address, of dials or workers
+	port   uint64 // next port of the range
+}
+
+// worker returns the local address of the next worker, or nil if workers
+// don't have their own.
+func (l *localAddrs) worker() *net.IPAddr {
+	if l == nil || l.mode != WorkerAddrs || len(l.addrs) == 0 {
+		return nil
+	}
+	return l.nextAddr()
+}
+
+func (l *localAddrs) nextAddr() *net.IPAddr {
+	n := atomic.AddUint64(&l.next, 1) - 1
+	return &l.addrs[n%uint64(len(l.addrs))]
+}
+
+// dial returns a dial function which binds connections to the given local
+// address, or to the next one if nil, and to the next free port of the range.
+func (l *localAddrs) dial(from *net.IPAddr) func(context.Context, string, string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		d := *l.dialer
+		laddr := *d.LocalAddr.(*net.TCPAddr)
+		if ip := from; ip != nil || len(l.addrs) > 0 {
+			if ip == nil {
+				ip = l.nextAddr()
+			}
+			laddr.IP, laddr.Zone = ip.IP, ip.Zone
+		}
+		d.LocalAddr = &laddr
+
+		if l.ports == (PortRange{}) {
+			return d.DialContext(ctx, network, addr)
+		}
+
+		// Ports of the range may be taken, including by connections of
+		// the Attacker lingering in TIME_WAIT, so each is tried in turn.
+		var err error
+		size := uint64(l.ports.Last-l.ports.First) + 1
+		for i := uint64(0); i < size; i++ {
+			laddr.Port = int(uint64(l.ports.First) + (atomic.AddUint64(&l.port, 1)-1)%size)
+
+			var conn net.Conn
+			if conn, err = d.DialContext(ctx, network, addr); err == nil || !isAddrInUse(err) {
+				return conn, err
+			}
+		}
+
+		return nil, fmt.Errorf("no free local port in %d-%d: %v", l.ports.First, l.ports.Last, err)
+	}
+}
+
+// isAddrInUse returns true if the given dial error is due to its local
+// address being taken.
+func isAddrInUse(err error) bool {
+	return errors.Is(err, syscall.EADDRINUSE)
+}
@@ -0,0 +1,109 @@
+package vegeta
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLocalAddrs(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+	defer server.Close()
+
+	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
+	addrs := []net.IPAddr{{IP: net.IPv4(127, 0, 0, 1)}, {IP: net.IPv4(127, 0, 0, 2)}}
+
+	ip := func(t *testing.T, res *Result) string {
+		if res.Error != "" {
+			t.Fatal(res.Error)
+		}
+		host, _, err := net.SplitHostPort(res.LocalAddr)
+		if err != nil {
+			t.Fatalf("got local address %q: %v", res.LocalAddr, err)
+		}
+		return host
+	}
+
+	t.Run("round robin", func(t *testing.T) {
+		// Options which set the dialer don't undo those given before them.
+		atk := NewAttacker(LocalAddrs(addrs, RoundRobinAddrs, PortRange{}), KeepAlive(false))
+		for i, want := range []string{"127.0.0.1", "127.0.0.2", "127.0.0.1", "127.0.0.2"} {
+			if got := ip(t, atk.hit(tr.NewTargeter(), "")); got != want {
+				t.Errorf("hit #%d: got local address %s, want %s", i, got, want)
+			}
+		}
+	})
+
+	t.Run("worker", func(t *testing.T) {
+		atk := NewAttacker(
+			Workers(1),
+			MaxWorkers(1),
+			KeepAlive(false),
+			LocalAddrs(addrs, WorkerAddrs, PortRange{}),
+		)
+
+		var hits int
+		for res := range atk.Attack(tr, Rate{Freq: 100, Per: time.Second}, 50*time.Millisecond, "") {
+			if got := ip(t, res); got != "127.0.0.1" {
+				t.Fatalf("got local address %s, want 127.0.0.1 for every hit of the worker", got)
+			}
+			hits++
+		}
+
+		if hits < 2 {
+			t.Errorf("got %d hits, want several", hits)
+		}
+	})
+
+	t.Run("port range", func(t *testing.T) {
+		// Take a free port to bind to and another one to find taken.
+		taken, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer taken.Close()
+
+		free, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		free.Close()
+
+		port := func(l net.Listener) uint16 { return uint16(l.Addr().(*net.TCPAddr).Port) }
+
+		atk := NewAttacker(
+			LocalAddr(net.IPAddr{IP: net.IPv4(127, 0, 0, 1)}),
+			LocalAddrs(nil, RoundRobinAddrs, PortRange{port(free), port(free)}),
+		)
+
+		res := atk.hit(tr.NewTargeter(), "")
+		if want := "127.0.0.1:" + strconv.Itoa(int(port(free))); res.Error != "" || res.LocalAddr != want {
+			t.Errorf("got local address %q and error %q, want %s", res.LocalAddr, res.Error, want)
+		}
+
+		atk = NewAttacker(
+			LocalAddr(net.IPAddr{IP: net.IPv4(127, 0, 0, 1)}),
+			LocalAddrs(nil, RoundRobinAddrs, PortRange{port(taken), port(taken)}),
+		)
+
+		if res = atk.hit(tr.NewTargeter(), ""); !strings.Contains(res.Error, "no free local port") {
+			t.Errorf("got error %q, want no free local port", res.Error)
+		}
+	})
+
+	// Unix sockets have no local addresses to bind to.
+	if err := NewAttacker(LocalAddrs(addrs, RoundRobinAddrs, PortRange{}), UnixSocket("/tmp/goku.sock")).Err(); err == nil {
+		t.Error("got no error with a unix socket")
+	}
+
+	// Results don't record local addresses without LocalAddrs or Metadata.
+	if res := NewAttacker(LocalAddrs(nil, WorkerAddrs, PortRange{})).hit(tr.NewTargeter(), ""); res.LocalAddr != "" {
+		t.Errorf("got local address %q, want none", res.LocalAddr)
+	}
+}
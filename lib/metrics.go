@@ -12,6 +12,19 @@ import (
 type Metrics struct {
 	// Latencies holds computed request latency metrics.
 	Latencies LatencyMetrics `json:"latencies"`
+	// CorrectedLatencies holds computed request latency metrics measured
+	// from the times at which requests were intended to be sent, if known.
+	// See Result.CorrectedLatency.
+	CorrectedLatencies *LatencyMetrics `json:"corrected_latencies,omitempty"`
+	// Timings holds computed metrics of the phases of traced requests, if any.
+	Timings *TimingMetrics `json:"timings,omitempty"`
+	// Retries holds computed metrics of the retries of requests, if any.
+	Retries *RetryMetrics `json:"retries,omitempty"`
+	// Stream holds computed metrics of streamed responses, if any.
+	Stream *StreamMetrics `json:"stream,omitempty"`
+	// Connections holds computed metrics of the connections opened by
+	// requests, if recorded.
+	Connections *ConnectionMetrics `json:"connections,omitempty"`
 	// Histogram, only if requested
 	Histogram *Histogram `json:"buckets,omitempty"`
 	// BytesIn holds computed incoming byte metrics.
@@ -41,8 +54,14 @@ type Metrics struct {
 	// Errors is a set of unique errors returned by the targets during the attack.
 	Errors []string `json:"errors"`
 
-	errors  map[string]struct{}
-	success uint64
+	errors    map[string]struct{}
+	success   uint64
+	corrected LatencyMetrics
+	intended  bool // whether any Result had its intended time set
+	timings   TimingMetrics
+	retries   RetryMetrics
+	stream    StreamMetrics
+	conns     ConnectionMetrics
 }
 
 // Add implements the Add method of the Report interface by adding the given
@@ -56,6 +75,26 @@ func (m *Metrics) Add(r *Result) {
 	m.BytesIn.Total += r.BytesIn
 
 	m.Latencies.Add(r.Latency)
+	m.corrected.Add(r.CorrectedLatency())
+	m.intended = m.intended || !r.Intended.IsZero()
+
+	if r.Timings != nil {
+		m.timings.Add(r.Timings)
+	}
+
+	if r.Stream != nil {
+		m.stream.Add(r.Stream)
+	}
+
+	if r.Handshake > 0 {
+		m.conns.Opened++
+		m.conns.Handshakes.Add(r.Handshake)
+	}
+
+	if r.Retries > 0 {
+		m.retries.Total += r.Retries
+		m.retries.Retried++
+	}
 
 	if m.Earliest.IsZero() || m.Earliest.After(r.Timestamp) {
 		m.Earliest = r.Timestamp
@@ -69,7 +108,10 @@ func (m *Metrics) Add(r *Result) {
 		m.End = end
 	}
 
-	if r.Code >= 200 && r.Code < 400 {
+	// Hits fail with an error on unexpected status codes, like those out of
+	// the 2xx and 3xx ranges, or the 101 of upgrades to WebSocket connections,
+	// as well as on unmet assertions.
+	if r.Error == "" {
 		m.success++
 	}
 
@@ -102,10 +144,40 @@ func (m *Metrics) Close() {
 	m.BytesIn.Mean = float64(m.BytesIn.Total) / float64(m.Requests)
 	m.BytesOut.Mean = float64(m.BytesOut.Total) / float64(m.Requests)
 	m.Success = float64(m.success) / float64(m.Requests)
-	m.Latencies.Mean = time.Duration(float64(m.Latencies.Total) / float64(m.Requests))
-	m.Latencies.P50 = m.Latencies.Quantile(0.50)
-	m.Latencies.P95 = m.Latencies.Quantile(0.95)
-	m.Latencies.P99 = m.Latencies.Quantile(0.99)
+	m.Latencies.close(m.Requests)
+
+	if m.intended {
+		m.corrected.close(m.Requests)
+		m.CorrectedLatencies = &m.corrected
+	}
+
+	if m.timings.traced > 0 {
+		m.timings.close()
+		m.Timings = &m.timings
+	}
+
+	if m.stream.streams > 0 {
+		m.stream.close()
+		m.Stream = &m.stream
+	}
+
+	if m.conns.Opened > 0 {
+		m.conns.Rate = float64(m.conns.Opened)
+		if secs := m.Duration.Seconds(); secs > 0 {
+			m.conns.Rate /= secs
+		}
+		m.conns.Handshakes.close(m.conns.Opened)
+		m.Connections = &m.conns
+	}
+
+	if m.retries.Total > 0 {
+		m.retries.Rate = float64(m.retries.Total)
+		if secs := m.Duration.Seconds(); secs > 0 {
+			m.retries.Rate /= secs
+		}
+		m.retries.Ratio = float64(m.retries.Total) / float64(m.Requests)
+		m.Retries = &m.retries
+	}
 }
 
 func (m *Metrics) init() {
@@ -149,6 +221,14 @@ func (l *LatencyMetrics) Add(latency time.Duration) {
 	l.estimator.Add(float64(latency))
 }
 
+// close computes the summary metrics of the given number of latencies.
+func (l *LatencyMetrics) close(n uint64) {
+	l.Mean = time.Duration(float64(l.Total) / float64(n))
+	l.P50 = l.Quantile(0.50)
+	l.P95 = l.Quantile(0.95)
+	l.P99 = l.Quantile(0.99)
+}
+
 // Quantile returns the nth quantile from the latency summary.
 func (l LatencyMetrics) Quantile(nth float64) time.Duration {
 	l.init()
@@ -163,6 +243,119 @@ func (l *LatencyMetrics) init() {
 	}
 }
 
+// TimingMetrics holds computed metrics of the phases of traced requests.
+// See Timings.
+type TimingMetrics struct {
+	// DNS holds computed DNS lookup duration metrics.
+	DNS LatencyMetrics `json:"dns"`
+	// Connect holds computed TCP connect duration metrics.
+	Connect LatencyMetrics `json:"connect"`
+	// TLS holds computed TLS handshake duration metrics.
+	TLS LatencyMetrics `json:"tls"`
+	// FirstByte holds computed time to first byte metrics.
+	FirstByte LatencyMetrics `json:"first_byte"`
+	// Transfer holds computed response body transfer duration metrics.
+	Transfer LatencyMetrics `json:"transfer"`
+	// Reused is the percentage of requests sent over reused connections.
+	Reused float64 `json:"reused"`
+
+	traced uint64
+	reused uint64
+}
+
+// Add adds the given Timings to the timing metrics.
+func (t *TimingMetrics) Add(tm *Timings) {
+	t.traced++
+	if tm.Reused {
+		t.reused++
+	}
+	t.DNS.Add(tm.DNS)
+	t.Connect.Add(tm.Connect)
+	t.TLS.Add(tm.TLS)
+	t.FirstByte.Add(tm.FirstByte)
+	t.Transfer.Add(tm.Transfer)
+}
+
+func (t *TimingMetrics) close() {
+	t.Reused = float64(t.reused) / float64(t.traced)
+	for _, l := range []*LatencyMetrics{&t.DNS, &t.Connect, &t.TLS, &t.FirstByte, &t.Transfer} {
+		l.close(t.traced)
+	}
+}
+
+// StreamMetrics holds computed metrics of streamed responses. See StreamStats.
+type StreamMetrics struct {
+	// FirstByte holds computed time to first byte metrics.
+	FirstByte LatencyMetrics `json:"first_byte"`
+	// FirstEvent holds computed time to first event metrics of the streams
+	// with events.
+	FirstEvent LatencyMetrics `json:"first_event"`
+	// Gaps holds computed metrics of the times between consecutive events.
+	Gaps LatencyMetrics `json:"gaps"`
+	// Events is the total number of events received.
+	Events uint64 `json:"events"`
+	// Mean is the mean number of events per stream.
+	Mean float64 `json:"mean"`
+
+	streams uint64
+	evented uint64 // streams with events
+	gaps    uint64
+}
+
+// Add adds the given StreamStats to the stream metrics.
+func (s *StreamMetrics) Add(st *StreamStats) {
+	s.streams++
+	s.Events += st.Events
+	s.FirstByte.Add(st.FirstByte)
+
+	if st.Events > 0 {
+		s.evented++
+		s.FirstEvent.Add(st.FirstEvent)
+	}
+
+	for _, gap := range st.Gaps {
+		s.gaps++
+		s.Gaps.Add(gap)
+	}
+}
+
+func (s *StreamMetrics) close() {
+	s.Mean = float64(s.Events) / float64(s.streams)
+	s.FirstByte.close(s.streams)
+	if s.evented > 0 {
+		s.FirstEvent.close(s.evented)
+	}
+	if s.gaps > 0 {
+		s.Gaps.close(s.gaps)
+	}
+}
+
+// ConnectionMetrics holds computed metrics of the connections opened by
+// requests. See Result.Handshake.
+type ConnectionMetrics struct {
+	// Opened is the number of connections opened.
+	Opened uint64 `json:"opened"`
+	// Rate is the rate of opened connections per second.
+	Rate float64 `json:"rate"`
+	// Handshakes holds computed metrics of the durations of connecting and
+	// completing the TLS handshakes of opened connections.
+	Handshakes LatencyMetrics `json:"handshakes"`
+}
+
+// RetryMetrics holds computed metrics of the retries of requests, which
+// aren't counted as requests.
+type RetryMetrics struct {
+	// Total is the total number of retries.
+	Total uint64 `json:"total"`
+	// Retried is the number of requests retried at least once.
+	Retried uint64 `json:"retried"`
+	// Rate is the rate of retries per second.
+	Rate float64 `json:"rate"`
+	// Ratio is the number of retries per request, by which retries amplify
+	// the load on targets.
+	Ratio float64 `json:"ratio"`
+}
+
 // ByteMetrics holds computed byte flow metrics.
 type ByteMetrics struct {
 	// Total is the total number of flowing bytes in an attack.
@@ -15,7 +15,7 @@ func TestMetrics_Add(t *testing.T) {
 	t.Parallel()
 
 	codes := []uint16{500, 200, 302}
-	errors := []string{"Internal server error", ""}
+	errors := []string{"Internal server error", "", ""}
 
 	var got Metrics
 	for i := 1; i <= 10000; i++ {
@@ -62,8 +62,9 @@ func TestMetrics_Add(t *testing.T) {
 		StatusCodes: map[string]int{"500": 3333, "200": 3334, "302": 3333},
 		Errors:      []string{"Internal server error"},
 
-		errors:  got.errors,
-		success: got.success,
+		errors:    got.errors,
+		success:   got.success,
+		corrected: got.corrected,
 	}
 
 	if !reflect.DeepEqual(got, want) {
@@ -71,6 +72,200 @@ func TestMetrics_Add(t *testing.T) {
 	}
 }
 
+func TestMetrics_Success(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name string
+		res  Result
+		want float64
+	}{
+		{"ok", Result{Code: 200}, 1},
+		{"failed assertion", Result{Code: 200, Error: `assertion failed: body doesn't contain "ok"`}, 0},
+		{"server error", Result{Code: 500, Error: "500 Internal Server Error"}, 0},
+		{"expected status", Result{Code: 404}, 1},
+		{"unexpected status", Result{Code: 302, Error: "302 Found"}, 0},
+	} {
+		var m Metrics
+		m.Add(&tc.res)
+		if m.Close(); m.Success != tc.want {
+			t.Errorf("%s: got success %f, want %f", tc.name, m.Success, tc.want)
+		}
+	}
+}
+
+func TestMetrics_CorrectedLatencies(t *testing.T) {
+	t.Parallel()
+
+	var m Metrics
+	m.Add(&Result{Code: 200, Timestamp: time.Unix(0, 0), Latency: time.Millisecond})
+	m.Close()
+
+	if m.CorrectedLatencies != nil {
+		t.Fatalf("got corrected latencies %+v without intended times", m.CorrectedLatencies)
+	}
+
+	began := time.Unix(10, 0)
+	m.Add(&Result{Code: 200, Intended: began, Timestamp: began.Add(time.Second), Latency: time.Millisecond})
+	m.Add(&Result{Code: 200, Intended: began, Timestamp: began, Latency: 2 * time.Millisecond})
+	m.Close()
+
+	if m.CorrectedLatencies == nil {
+		t.Fatal("got nil corrected latencies")
+	}
+
+	if got, want := m.Latencies.Max, 2*time.Millisecond; got != want {
+		t.Errorf("got max latency %s, want %s", got, want)
+	}
+
+	if got, want := m.CorrectedLatencies.Max, time.Second+time.Millisecond; got != want {
+		t.Errorf("got max corrected latency %s, want %s", got, want)
+	}
+
+	if got, want := m.CorrectedLatencies.Total, time.Second+4*time.Millisecond; got != want {
+		t.Errorf("got total corrected latency %s, want %s", got, want)
+	}
+}
+
+func TestMetrics_Timings(t *testing.T) {
+	t.Parallel()
+
+	var m Metrics
+	m.Add(&Result{Code: 200, Timestamp: time.Unix(0, 0), Latency: time.Millisecond})
+	m.Close()
+
+	if m.Timings != nil {
+		t.Fatalf("got timings %+v without traced results", m.Timings)
+	}
+
+	m.Add(&Result{
+		Code:      200,
+		Timestamp: time.Unix(1, 0),
+		Latency:   10 * time.Millisecond,
+		Timings:   &Timings{DNS: time.Millisecond, Connect: 2 * time.Millisecond, FirstByte: 8 * time.Millisecond},
+	})
+	m.Add(&Result{
+		Code:      200,
+		Timestamp: time.Unix(2, 0),
+		Latency:   4 * time.Millisecond,
+		Timings:   &Timings{FirstByte: 3 * time.Millisecond, Transfer: time.Millisecond, Reused: true},
+	})
+	m.Close()
+
+	if m.Timings == nil {
+		t.Fatal("got nil timings")
+	}
+
+	for _, tc := range []struct {
+		phase     string
+		got, want time.Duration
+	}{
+		{"dns max", m.Timings.DNS.Max, time.Millisecond},
+		{"connect total", m.Timings.Connect.Total, 2 * time.Millisecond},
+		{"first byte mean", m.Timings.FirstByte.Mean, 5500 * time.Microsecond},
+		{"transfer max", m.Timings.Transfer.Max, time.Millisecond},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("got %s %s, want %s", tc.phase, tc.got, tc.want)
+		}
+	}
+
+	if got, want := m.Timings.Reused, 0.5; got != want {
+		t.Errorf("got reused %v, want %v", got, want)
+	}
+}
+
+func TestMetrics_Retries(t *testing.T) {
+	t.Parallel()
+
+	var m Metrics
+	m.Add(&Result{Code: 200, Timestamp: time.Unix(0, 0), Latency: time.Millisecond})
+	m.Close()
+
+	if m.Retries != nil {
+		t.Fatalf("got retries %+v without retried results", m.Retries)
+	}
+
+	m.Add(&Result{Code: 200, Timestamp: time.Unix(1, 0), Retries: 3, RetryErrors: []string{"503", "503", "503"}})
+	m.Add(&Result{Code: 503, Timestamp: time.Unix(2, 0), Retries: 1, RetryErrors: []string{"503"}})
+	m.Add(&Result{Code: 200, Timestamp: time.Unix(2, 0)})
+	m.Close()
+
+	want := &RetryMetrics{Total: 4, Retried: 2, Rate: 2, Ratio: 1}
+	if !reflect.DeepEqual(m.Retries, want) {
+		t.Errorf("got retries %+v, want %+v", m.Retries, want)
+	}
+}
+
+func TestMetrics_Stream(t *testing.T) {
+	t.Parallel()
+
+	var m Metrics
+	m.Add(&Result{Code: 200, Timestamp: time.Unix(0, 0)})
+	m.Close()
+
+	if m.Stream != nil {
+		t.Fatalf("got stream metrics %+v without streamed results", m.Stream)
+	}
+
+	m.Add(&Result{Code: 200, Timestamp: time.Unix(1, 0), Stream: &StreamStats{
+		FirstByte:  time.Millisecond,
+		FirstEvent: 3 * time.Millisecond,
+		Events:     3,
+		Gaps:       []time.Duration{time.Millisecond, 3 * time.Millisecond},
+	}})
+	m.Add(&Result{Code: 200, Timestamp: time.Unix(2, 0), Stream: &StreamStats{
+		FirstByte: 3 * time.Millisecond,
+	}})
+	m.Close()
+
+	s := m.Stream
+	if s == nil {
+		t.Fatal("got no stream metrics")
+	} else if s.Events != 3 || s.Mean != 1.5 {
+		t.Errorf("got %d events and mean %v, want 3 and 1.5", s.Events, s.Mean)
+	}
+
+	for _, tc := range []struct {
+		metric    string
+		got, want time.Duration
+	}{
+		{"first byte mean", s.FirstByte.Mean, 2 * time.Millisecond},
+		{"first event mean", s.FirstEvent.Mean, 3 * time.Millisecond},
+		{"gaps mean", s.Gaps.Mean, 2 * time.Millisecond},
+		{"gaps max", s.Gaps.Max, 3 * time.Millisecond},
+	} {
+		if tc.got != tc.want {
+			t.Errorf("got %s %s, want %s", tc.metric, tc.got, tc.want)
+		}
+	}
+}
+
+func TestMetrics_Connections(t *testing.T) {
+	t.Parallel()
+
+	var m Metrics
+	m.Add(&Result{Code: 200, Timestamp: time.Unix(0, 0)})
+	m.Close()
+
+	if m.Connections != nil {
+		t.Fatalf("got connection metrics %+v without handshakes", m.Connections)
+	}
+
+	m.Add(&Result{Code: 200, Timestamp: time.Unix(1, 0), Handshake: time.Millisecond})
+	m.Add(&Result{Code: 200, Timestamp: time.Unix(2, 0), Handshake: 3 * time.Millisecond})
+	m.Close()
+
+	c := m.Connections
+	if c == nil {
+		t.Fatal("got no connection metrics")
+	} else if c.Opened != 2 || c.Rate != 1 {
+		t.Errorf("got %d opened at rate %v, want 2 at 1", c.Opened, c.Rate)
+	} else if c.Handshakes.Mean != 2*time.Millisecond || c.Handshakes.Max != 3*time.Millisecond {
+		t.Errorf("got handshakes mean %s and max %s", c.Handshakes.Mean, c.Handshakes.Max)
+	}
+}
+
 // https://github.com/ernestrc/vegeta/issues/208
 func TestMetrics_NoInfiniteRate(t *testing.T) {
 	t.Parallel()
@@ -0,0 +1,610 @@
+package vegeta
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenAPIOptions configures which operations of an OpenAPI document
+// ReadOpenAPITargets turns into Targets.
+type OpenAPIOptions struct {
+	// BaseURL is the URL the server URL of the document is resolved against,
+	// which is the first server of an OpenAPI 3 document, or the scheme, host
+	// and base path of a Swagger 2 document. The path of the server URL is
+	// appended to it, so that operation paths keep their prefix. Without it,
+	// the server URL must be absolute.
+	BaseURL string
+	// Tags is the list of operation tags to include. All operations are
+	// included when empty.
+	Tags []string
+	// PathPrefixes is the list of path prefixes of the operations to include.
+	// All operations are included when empty.
+	PathPrefixes []string
+}
+
+// ErrNoBaseURL is returned by ReadOpenAPITargets when the document doesn't
+// define an absolute server URL and no base URL is given in the options.
+var ErrNoBaseURL = errors.New("openapi: no absolute server URL, set a base URL")
+
+// openAPIMethods are the methods of a Path Item Object, in the order in which
+// their operations are turned into Targets.
+var openAPIMethods = []string{"get", "put", "post", "delete", "options", "head", "patch", "trace"}
+
+// openAPIMaxRefs limits the length of chains of references to references.
+const openAPIMaxRefs = 8
+
+// ReadOpenAPITargets returns one Target for every operation of the OpenAPI 3
+// or Swagger 2 JSON document read from the given io.Reader, sorted by path.
+//
+// Path parameters, and query and header parameters which are required or have
+// an example, are set to the example, default or first enum value of their
+// schema, or to a value synthesized from it. Request bodies are generated the
+// same way and encoded as JSON or as a form according to their media type.
+func ReadOpenAPITargets(src io.Reader, opts OpenAPIOptions) ([]Target, error) {
+	var doc map[string]interface{}
+	dec := json.NewDecoder(src)
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("bad openapi document: %s", err)
+	}
+
+	d := openAPIDoc{root: doc}
+	if _, ok := doc["swagger"]; ok {
+		d.swagger = true
+	} else if _, ok := doc["openapi"]; !ok {
+		return nil, fmt.Errorf("bad openapi document: missing openapi or swagger version")
+	}
+
+	base, server := strings.TrimSuffix(opts.BaseURL, "/"), d.serverURL()
+	if base == "" {
+		base = server
+	} else if u, err := url.Parse(server); err == nil {
+		if path := strings.Trim(u.EscapedPath(), "/"); path != "" {
+			base += "/" + path
+		}
+	}
+
+	if u, err := url.Parse(base); err != nil || !u.IsAbs() {
+		return nil, ErrNoBaseURL
+	}
+
+	paths := d.object(doc["paths"])
+	names := make([]string, 0, len(paths))
+	for path := range paths {
+		if matchPrefix(opts.PathPrefixes, path) {
+			names = append(names, path)
+		}
+	}
+	sort.Strings(names)
+
+	var tgts []Target
+	for _, path := range names {
+		item := d.object(paths[path])
+		for _, method := range openAPIMethods {
+			op := d.object(item[method])
+			if op == nil || !matchTags(opts.Tags, op["tags"]) {
+				continue
+			}
+
+			tgt, err := d.target(base, path, method, item, op)
+			if err != nil {
+				return nil, fmt.Errorf("bad openapi operation %s %s: %s", strings.ToUpper(method), path, err)
+			}
+			tgts = append(tgts, *tgt)
+		}
+	}
+
+	if len(tgts) == 0 {
+		return nil, ErrNoTargets
+	}
+
+	return tgts, nil
+}
+
+type openAPIDoc struct {
+	root    map[string]interface{}
+	swagger bool // Swagger 2 instead of OpenAPI 3
+}
+
+// serverURL returns the URL the operation paths of the document are relative
+// to, which is relative itself when the document doesn't define a host.
+func (d *openAPIDoc) serverURL() string {
+	if d.swagger {
+		basePath, _ := d.root["basePath"].(string)
+		basePath = strings.TrimSuffix(basePath, "/")
+
+		host, _ := d.root["host"].(string)
+		if host == "" {
+			return basePath
+		}
+
+		scheme := "http"
+		if schemes, _ := d.root["schemes"].([]interface{}); len(schemes) > 0 {
+			scheme, _ = schemes[0].(string)
+		}
+
+		return scheme + "://" + host + basePath
+	}
+
+	servers, _ := d.root["servers"].([]interface{})
+	if len(servers) == 0 {
+		return ""
+	}
+
+	server := d.object(servers[0])
+	u, _ := server["url"].(string)
+	for name, v := range d.object(server["variables"]) {
+		def, _ := d.object(v)["default"].(string)
+		u = strings.Replace(u, "{"+name+"}", def, -1)
+	}
+
+	return strings.TrimSuffix(u, "/")
+}
+
+// object returns v as a JSON object, following $ref pointers within the
+// document.
+func (d *openAPIDoc) object(v interface{}) map[string]interface{} {
+	obj, _ := v.(map[string]interface{})
+	for i := 0; i < openAPIMaxRefs && obj != nil; i++ {
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			return obj
+		}
+		obj, _ = d.pointer(ref).(map[string]interface{})
+	}
+	return obj
+}
+
+// pointer returns the value referenced by a local JSON pointer, like
+// #/components/schemas/Pet.
+func (d *openAPIDoc) pointer(ref string) interface{} {
+	if !strings.HasPrefix(ref, "#/") {
+		return nil
+	}
+
+	var v interface{} = d.root
+	for _, tok := range strings.Split(ref[2:], "/") {
+		tok = strings.Replace(strings.Replace(tok, "~1", "/", -1), "~0", "~", -1)
+		switch t := v.(type) {
+		case map[string]interface{}:
+			v = t[tok]
+		case []interface{}:
+			i, err := strconv.Atoi(tok)
+			if err != nil || i < 0 || i >= len(t) {
+				return nil
+			}
+			v = t[i]
+		default:
+			return nil
+		}
+	}
+
+	return v
+}
+
+func (d *openAPIDoc) target(base, path, method string, item, op map[string]interface{}) (*Target, error) {
+	tgt := Target{Method: strings.ToUpper(method), Header: http.Header{}}
+
+	// Operation parameters override the path ones with the same name and location.
+	params := map[string]map[string]interface{}{}
+	var order []string
+	for _, list := range []interface{}{item["parameters"], op["parameters"]} {
+		ps, _ := list.([]interface{})
+		for _, p := range ps {
+			param := d.object(p)
+			name, _ := param["name"].(string)
+			in, _ := param["in"].(string)
+			key := in + ":" + name
+			if _, ok := params[key]; !ok {
+				order = append(order, key)
+			}
+			params[key] = param
+		}
+	}
+
+	query, form := url.Values{}, url.Values{}
+	for _, key := range order {
+		param := params[key]
+		name, _ := param["name"].(string)
+		in, _ := param["in"].(string)
+		required, _ := param["required"].(bool)
+
+		if in == "body" { // Swagger 2
+			body, err := json.Marshal(d.sample(param["schema"], nil))
+			if err != nil {
+				return nil, err
+			}
+			tgt.Body = body
+			tgt.Header.Set("Content-Type", d.consumes(op, "application/json"))
+			continue
+		}
+
+		v, ok := d.paramExample(param)
+		if !ok && !required && in != "path" {
+			continue
+		} else if !ok {
+			v = d.paramSample(param)
+		}
+
+		values := d.paramValues(param, v)
+		switch in {
+		case "path":
+			path = strings.Replace(path, "{"+name+"}", url.PathEscape(strings.Join(values, ",")), -1)
+		case "query":
+			query[name] = append(query[name], values...)
+		case "header":
+			tgt.Header[name] = append(tgt.Header[name], strings.Join(values, ","))
+		case "formData": // Swagger 2
+			form[name] = append(form[name], values...)
+		}
+	}
+
+	if len(form) > 0 {
+		tgt.Body = []byte(form.Encode())
+		tgt.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	if rb := d.object(op["requestBody"]); rb != nil {
+		if err := d.requestBody(&tgt, rb); err != nil {
+			return nil, err
+		}
+	}
+
+	tgt.URL = base + path
+	if len(query) > 0 {
+		tgt.URL += "?" + query.Encode()
+	}
+
+	if _, err := url.ParseRequestURI(tgt.URL); err != nil {
+		return nil, fmt.Errorf("bad URL: %s", tgt.URL)
+	}
+
+	return &tgt, nil
+}
+
+// paramExample returns the example value of an OpenAPI 3 parameter, or the
+// x-example of a Swagger 2 one.
+func (d *openAPIDoc) paramExample(param map[string]interface{}) (interface{}, bool) {
+	if v, ok := param["example"]; ok {
+		return v, true
+	} else if v, ok := param["x-example"]; ok {
+		return v, true
+	}
+
+	for _, ex := range d.object(param["examples"]) {
+		if v, ok := d.object(ex)["value"]; ok {
+			return v, true
+		}
+	}
+
+	return nil, false
+}
+
+func (d *openAPIDoc) paramSample(param map[string]interface{}) interface{} {
+	if d.swagger { // Swagger 2 parameters are schemas themselves.
+		return d.sample(param, nil)
+	}
+	return d.sample(param["schema"], nil)
+}
+
+// paramValues formats a parameter value as a list of strings, one per
+// element of array values.
+func (d *openAPIDoc) paramValues(param map[string]interface{}, v interface{}) []string {
+	vs, ok := v.([]interface{})
+	if !ok {
+		return []string{openAPIString(v)}
+	}
+
+	values := make([]string, len(vs))
+	for i := range vs {
+		values[i] = openAPIString(vs[i])
+	}
+
+	// Swagger 2 defaults to comma separated values.
+	if format, _ := param["collectionFormat"].(string); d.swagger && format != "multi" {
+		return []string{strings.Join(values, ",")}
+	}
+
+	return values
+}
+
+func (d *openAPIDoc) consumes(op map[string]interface{}, def string) string {
+	for _, list := range []interface{}{op["consumes"], d.root["consumes"]} {
+		if types, _ := list.([]interface{}); len(types) > 0 {
+			if t, _ := types[0].(string); t != "" {
+				return t
+			}
+		}
+	}
+	return def
+}
+
+// requestBody sets the body of tgt according to an OpenAPI 3 Request Body
+// Object, preferring JSON over form media types.
+func (d *openAPIDoc) requestBody(tgt *Target, rb map[string]interface{}) error {
+	content := d.object(rb["content"])
+
+	types := make([]string, 0, len(content))
+	for t := range content {
+		types = append(types, t)
+	}
+	sort.Slice(types, func(i, j int) bool {
+		return mediaTypeRank(types[i]) < mediaTypeRank(types[j]) ||
+			mediaTypeRank(types[i]) == mediaTypeRank(types[j]) && types[i] < types[j]
+	})
+
+	if len(types) == 0 {
+		return nil
+	}
+
+	mt := d.object(content[types[0]])
+	v, ok := d.paramExample(mt)
+	if !ok {
+		v = d.sample(mt["schema"], nil)
+	}
+
+	switch rank := mediaTypeRank(types[0]); {
+	case rank == 0:
+		body, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		tgt.Body = body
+	case rank == 1:
+		form := url.Values{}
+		for name, fv := range d.object(v) {
+			form[name] = d.paramValues(nil, fv)
+		}
+		tgt.Body = []byte(form.Encode())
+	default:
+		tgt.Body = []byte(openAPIString(v))
+	}
+
+	ct := types[0]
+	if strings.Contains(ct, "*") {
+		ct = "application/octet-stream"
+	}
+	tgt.Header.Set("Content-Type", ct)
+
+	return nil
+}
+
+func mediaTypeRank(mt string) int {
+	switch {
+	case mt == "application/json", strings.HasSuffix(mt, "+json"):
+		return 0
+	case mt == "application/x-www-form-urlencoded":
+		return 1
+	default:
+		return 2
+	}
+}
+
+// sample returns a value which satisfies the given schema, preferring its
+// example, default and enum values. refs holds the schema references being
+// sampled, so that recursive schemas terminate.
+func (d *openAPIDoc) sample(v interface{}, refs map[string]bool) interface{} {
+	if obj, ok := v.(map[string]interface{}); ok {
+		if ref, ok := obj["$ref"].(string); ok {
+			if refs[ref] {
+				return nil
+			} else if refs == nil {
+				refs = map[string]bool{}
+			}
+			refs[ref] = true
+			defer delete(refs, ref)
+		}
+	}
+
+	schema := d.object(v)
+	if schema == nil {
+		return nil
+	}
+
+	if ex, ok := schema["example"]; ok {
+		return ex
+	} else if def, ok := schema["default"]; ok {
+		return def
+	} else if enum, _ := schema["enum"].([]interface{}); len(enum) > 0 {
+		return enum[0]
+	}
+
+	if all, _ := schema["allOf"].([]interface{}); len(all) > 0 {
+		obj := map[string]interface{}{}
+		for _, s := range all {
+			for k, v := range d.object(d.sample(s, refs)) {
+				obj[k] = v
+			}
+		}
+		return obj
+	}
+
+	for _, key := range []string{"oneOf", "anyOf"} {
+		if alts, _ := schema[key].([]interface{}); len(alts) > 0 {
+			return d.sample(alts[0], refs)
+		}
+	}
+
+	typ, _ := schema["type"].(string)
+	if types, ok := schema["type"].([]interface{}); ok && len(types) > 0 { // OpenAPI 3.1
+		typ, _ = types[0].(string)
+	}
+
+	if typ == "" {
+		if _, ok := schema["properties"]; ok {
+			typ = "object"
+		} else if _, ok := schema["items"]; ok {
+			typ = "array"
+		}
+	}
+
+	switch typ {
+	case "object":
+		required := map[string]bool{}
+		names, _ := schema["required"].([]interface{})
+		for _, name := range names {
+			if s, ok := name.(string); ok {
+				required[s] = true
+			}
+		}
+
+		obj := map[string]interface{}{}
+		for name, p := range d.object(schema["properties"]) {
+			if readOnly, _ := d.object(p)["readOnly"].(bool); readOnly {
+				continue
+			}
+			// Optional properties are left out when they can't be sampled,
+			// like recursive ones.
+			if v := d.sample(p, refs); v != nil || required[name] {
+				obj[name] = v
+			}
+		}
+		return obj
+	case "array":
+		n, ok := openAPINumber(schema["minItems"])
+		if !ok || n < 1 {
+			n = 1
+		}
+		if max, ok := openAPINumber(schema["maxItems"]); ok && max < n {
+			n = max
+		}
+		items := make([]interface{}, int(n))
+		for i := range items {
+			items[i] = d.sample(schema["items"], refs)
+		}
+		return items
+	case "integer", "number":
+		return openAPINumberSample(schema, typ == "integer")
+	case "boolean":
+		return true
+	case "string":
+		return openAPIStringSample(schema)
+	default:
+		return nil
+	}
+}
+
+func openAPINumberSample(schema map[string]interface{}, integer bool) interface{} {
+	n := 1.0
+	if min, ok := openAPINumber(schema["minimum"]); ok {
+		n = min
+		if excl, _ := schema["exclusiveMinimum"].(bool); excl {
+			n++
+		}
+	} else if min, ok := openAPINumber(schema["exclusiveMinimum"]); ok { // OpenAPI 3.1
+		n = min + 1
+	}
+
+	if max, ok := openAPINumber(schema["maximum"]); ok {
+		if excl, _ := schema["exclusiveMaximum"].(bool); excl && n >= max {
+			n = max - 1
+		} else if n > max {
+			n = max
+		}
+	} else if max, ok := openAPINumber(schema["exclusiveMaximum"]); ok && n >= max { // OpenAPI 3.1
+		n = max - 1
+	}
+
+	if integer {
+		return int64(n)
+	}
+	return n
+}
+
+var openAPIFormats = map[string]string{
+	"date":      "2006-01-02",
+	"date-time": "2006-01-02T15:04:05Z",
+	"time":      "15:04:05Z",
+	"email":     "vegeta@example.com",
+	"hostname":  "example.com",
+	"ipv4":      "127.0.0.1",
+	"ipv6":      "::1",
+	"uri":       "http://example.com",
+	"url":       "http://example.com",
+	"uuid":      "00000000-0000-4000-8000-000000000000",
+	"byte":      "dmVnZXRh",
+	"password":  "vegeta",
+}
+
+func openAPIStringSample(schema map[string]interface{}) string {
+	format, _ := schema["format"].(string)
+	s, ok := openAPIFormats[format]
+	if !ok {
+		s = "vegeta"
+	}
+
+	if min, ok := openAPINumber(schema["minLength"]); ok && len(s) < int(min) {
+		s += strings.Repeat("a", int(min)-len(s))
+	}
+
+	if max, ok := openAPINumber(schema["maxLength"]); ok && len(s) > int(max) {
+		s = s[:int(max)]
+	}
+
+	return s
+}
+
+func openAPINumber(v interface{}) (float64, bool) {
+	n, ok := v.(json.Number)
+	if !ok {
+		return 0, false
+	}
+	f, err := n.Float64()
+	return f, err == nil
+}
+
+// openAPIString formats a scalar value as a string. Other values are
+// encoded as JSON.
+func openAPIString(v interface{}) string {
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return t
+	case json.Number:
+		return t.String()
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		bs, _ := json.Marshal(t)
+		return string(bs)
+	}
+}
+
+func matchPrefix(prefixes []string, path string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+
+	for _, p := range prefixes {
+		if strings.HasPrefix(path, p) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func matchTags(tags []string, opTags interface{}) bool {
+	if len(tags) == 0 {
+		return true
+	}
+
+	ts, _ := opTags.([]interface{})
+	for _, t := range ts {
+		for _, tag := range tags {
+			if t == tag {
+				return true
+			}
+		}
+	}
+
+	return false
+}
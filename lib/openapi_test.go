@@ -0,0 +1,194 @@
+package vegeta
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+const testOpenAPI3 = `{
+  "openapi": "3.0.0",
+  "servers": [{"url": "https://{env}.goku.io/v1/", "variables": {"env": {"default": "api"}}}],
+  "paths": {
+    "/pets/{id}": {
+      "parameters": [{"name": "id", "in": "path", "required": true, "schema": {"type": "integer", "minimum": 10}}],
+      "get": {
+        "tags": ["pets"],
+        "parameters": [
+          {"name": "fields", "in": "query", "schema": {"type": "array", "items": {"type": "string"}}, "example": ["name", "age"]},
+          {"name": "limit", "in": "query", "schema": {"type": "integer", "default": 10}},
+          {"name": "X-Request-Id", "in": "header", "required": true, "schema": {"type": "string", "format": "uuid"}}
+        ]
+      },
+      "delete": {"tags": ["admin"]}
+    },
+    "/pets": {
+      "post": {
+        "tags": ["pets"],
+        "requestBody": {"$ref": "#/components/requestBodies/Pet"}
+      }
+    },
+    "/login": {
+      "post": {
+        "requestBody": {
+          "content": {
+            "application/x-www-form-urlencoded": {
+              "schema": {"type": "object", "properties": {"user": {"type": "string", "minLength": 8}}}
+            }
+          }
+        }
+      }
+    }
+  },
+  "components": {
+    "requestBodies": {
+      "Pet": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Pet"}}, "text/plain": {}}}
+    },
+    "schemas": {
+      "Pet": {
+        "type": "object",
+        "required": ["name"],
+        "properties": {
+          "id": {"type": "integer", "readOnly": true},
+          "name": {"type": "string", "maxLength": 3},
+          "kind": {"type": "string", "enum": ["dog", "cat"]},
+          "born": {"type": "string", "format": "date"},
+          "weight": {"type": "number", "minimum": 0, "exclusiveMinimum": true, "maximum": 0.5},
+          "vaccinated": {"type": "boolean"},
+          "tags": {"type": "array", "items": {"type": "string"}, "minItems": 2},
+          "parent": {"$ref": "#/components/schemas/Pet"}
+        }
+      }
+    }
+  }
+}`
+
+const testSwagger2 = `{
+  "swagger": "2.0",
+  "host": "goku.io",
+  "schemes": ["https"],
+  "basePath": "/v2",
+  "consumes": ["application/json"],
+  "paths": {
+    "/users/{name}": {
+      "put": {
+        "parameters": [
+          {"name": "name", "in": "path", "required": true, "type": "string", "x-example": "goku"},
+          {"name": "ids", "in": "query", "required": true, "type": "array", "items": {"type": "integer"}, "minItems": 2},
+          {"name": "body", "in": "body", "schema": {"$ref": "#/definitions/User"}}
+        ]
+      }
+    },
+    "/login": {
+      "post": {
+        "parameters": [
+          {"name": "user", "in": "formData", "type": "string", "required": true},
+          {"name": "remember", "in": "formData", "type": "boolean"}
+        ]
+      }
+    }
+  },
+  "definitions": {
+    "User": {"type": "object", "properties": {"power": {"type": "integer", "maximum": 9000, "minimum": 9000}}}
+  }
+}`
+
+func TestReadOpenAPITargets(t *testing.T) {
+	t.Parallel()
+
+	jsonHeader := http.Header{"Content-Type": []string{"application/json"}}
+	formHeader := http.Header{"Content-Type": []string{"application/x-www-form-urlencoded"}}
+
+	for _, tc := range []struct {
+		name string
+		doc  string
+		opts OpenAPIOptions
+		out  []Target
+		err  error
+	}{
+		{
+			name: "openapi 3",
+			doc:  testOpenAPI3,
+			out: []Target{
+				{Method: "POST", URL: "https://api.goku.io/v1/login", Body: []byte("user=vegetaaa"), Header: formHeader},
+				{Method: "POST", URL: "https://api.goku.io/v1/pets", Body: []byte(`{"born":"2006-01-02","kind":"dog","name":"veg","tags":["vegeta","vegeta"],"vaccinated":true,"weight":0.5}`), Header: jsonHeader},
+				{
+					Method: "GET",
+					URL:    "https://api.goku.io/v1/pets/10?fields=name&fields=age",
+					Header: http.Header{"X-Request-Id": []string{"00000000-0000-4000-8000-000000000000"}},
+				},
+				{Method: "DELETE", URL: "https://api.goku.io/v1/pets/10", Header: http.Header{}},
+			},
+		},
+		{
+			name: "filters",
+			doc:  testOpenAPI3,
+			opts: OpenAPIOptions{BaseURL: "http://localhost:8080/", Tags: []string{"pets", "admin"}, PathPrefixes: []string{"/pets/"}},
+			out: []Target{
+				{
+					Method: "GET",
+					URL:    "http://localhost:8080/v1/pets/10?fields=name&fields=age",
+					Header: http.Header{"X-Request-Id": []string{"00000000-0000-4000-8000-000000000000"}},
+				},
+				{Method: "DELETE", URL: "http://localhost:8080/v1/pets/10", Header: http.Header{}},
+			},
+		},
+		{
+			name: "relative server url",
+			doc:  `{"openapi": "3.0.0", "servers": [{"url": "/api/"}], "paths": {"/pets": {"get": {}}}}`,
+			opts: OpenAPIOptions{BaseURL: "http://localhost:8080/gateway"},
+			out:  []Target{{Method: "GET", URL: "http://localhost:8080/gateway/api/pets", Header: http.Header{}}},
+		},
+		{
+			name: "swagger 2 without host",
+			doc:  `{"swagger": "2.0", "basePath": "/v2", "paths": {"/pets": {"get": {}}}}`,
+			opts: OpenAPIOptions{BaseURL: "http://localhost:8080"},
+			out:  []Target{{Method: "GET", URL: "http://localhost:8080/v2/pets", Header: http.Header{}}},
+		},
+		{
+			name: "swagger 2",
+			doc:  testSwagger2,
+			out: []Target{
+				{Method: "POST", URL: "https://goku.io/v2/login", Body: []byte("user=vegeta"), Header: formHeader},
+				{Method: "PUT", URL: "https://goku.io/v2/users/goku?ids=1%2C1", Body: []byte(`{"power":9000}`), Header: jsonHeader},
+			},
+		},
+		{
+			name: "no matches",
+			doc:  testSwagger2,
+			opts: OpenAPIOptions{Tags: []string{"pets"}},
+			err:  ErrNoTargets,
+		},
+		{
+			name: "no base url",
+			doc:  `{"openapi": "3.0.0", "servers": [{"url": "/v1"}], "paths": {}}`,
+			err:  ErrNoBaseURL,
+		},
+		{
+			name: "no version",
+			doc:  `{"paths": {}}`,
+			err:  fmt.Errorf("bad openapi document: missing openapi or swagger version"),
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			tgts, err := ReadOpenAPITargets(strings.NewReader(tc.doc), tc.opts)
+			if got, want := fmt.Sprint(err), fmt.Sprint(tc.err); got != want {
+				t.Fatalf("got error %q, want %q", got, want)
+			}
+
+			if got, want := len(tgts), len(tc.out); got != want {
+				t.Fatalf("got %d targets, want %d: %+v", got, want, tgts)
+			}
+
+			for i := range tgts {
+				if got, want := tgts[i], tc.out[i]; !got.Equal(&want) {
+					t.Errorf("#%d: got: %+v\nwant: %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
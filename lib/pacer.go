@@ -14,6 +14,14 @@ type Pacer interface {
 	Pace(elapsed time.Duration, hits uint64) (wait time.Duration, stop bool)
 }
 
+// A Scheduler is a Pacer which knows when each hit is due, relative to the
+// start of the attack, so that the Results of hits sent late record the time
+// they were due at rather than the time the Pacer let them through. Due
+// returns false if the given hit has no due time, e.g. at an infinite rate.
+type Scheduler interface {
+	Due(hits uint64) (time.Duration, bool)
+}
+
 // A PacerFunc is a function adapter type that implements
 // the Pacer interface.
 type PacerFunc func(time.Duration, uint64) (time.Duration, bool)
@@ -32,8 +40,11 @@ type ConstantPacer struct {
 // Rate is a type alias for ConstantPacer for backwards-compatibility.
 type Rate = ConstantPacer
 
-// ConstantPacer satisfies the Pacer interface.
-var _ Pacer = ConstantPacer{}
+// ConstantPacer satisfies the Pacer and Scheduler interfaces.
+var (
+	_ Pacer     = ConstantPacer{}
+	_ Scheduler = ConstantPacer{}
+)
 
 // String returns a pretty-printed description of the ConstantPacer's behaviour:
 //   ConstantPacer{Freq: 1, Per: time.Second} => Constant{1 hits/1s}
@@ -65,6 +76,21 @@ func (cp ConstantPacer) Pace(elapsed time.Duration, hits uint64) (time.Duration,
 	return delta - elapsed, false
 }
 
+// Due returns the time the hit with the given sequence number is due at,
+// one interval after the previous one.
+func (cp ConstantPacer) Due(hits uint64) (time.Duration, bool) {
+	if cp.Per <= 0 || cp.Freq <= 0 {
+		return 0, false
+	}
+
+	interval := uint64(cp.Per.Nanoseconds() / int64(cp.Freq))
+	if interval == 0 || math.MaxInt64/interval < hits+1 {
+		return 0, false
+	}
+
+	return time.Duration((hits + 1) * interval), true
+}
+
 // hitsPerNs returns the attack rate this ConstantPacer represents, in
 // fractional hits per nanosecond.
 func (cp ConstantPacer) hitsPerNs() float64 {
@@ -132,8 +158,11 @@ type SinePacer struct {
 	StartAt float64
 }
 
-// SinePacer satisfies the Pacer interface.
-var _ Pacer = SinePacer{}
+// SinePacer satisfies the Pacer and Scheduler interfaces.
+var (
+	_ Pacer     = SinePacer{}
+	_ Scheduler = SinePacer{}
+)
 
 // String returns a pretty-printed description of the SinePacer's behaviour:
 //   SinePacer{
@@ -184,6 +213,28 @@ func (sp SinePacer) Pace(elapsedTime time.Duration, elapsedHits uint64) (time.Du
 	return nextHitIn, false
 }
 
+// Due returns the time the hit with the given sequence number is due at,
+// which is when the expected number of hits reaches it.
+func (sp SinePacer) Due(hits uint64) (time.Duration, bool) {
+	if sp.invalid() {
+		return 0, false
+	}
+
+	// The expected number of hits only grows, since the Amplitude is below
+	// the Mean, so Newton's method converges from the mean rate guess.
+	want := float64(hits + 1)
+	t := want / sp.Mean.hitsPerNs()
+	for i := 0; i < 10; i++ {
+		err := sp.hits(time.Duration(t)) - want
+		if math.Abs(err) < 1e-3 {
+			break
+		}
+		t -= err / sp.hitsPerNs(time.Duration(t))
+	}
+
+	return time.Duration(math.Round(t)), true
+}
+
 // ampHits returns AP/2𝛑, which is the number of hits added or subtracted
 // from the Mean due to the Amplitude over a quarter of the Period,
 // i.e. from 0 → 𝛑/2 radians
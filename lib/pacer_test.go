@@ -224,3 +224,39 @@ func TestSinePacerPace_Flat(t *testing.T) {
 		}
 	}
 }
+
+func TestPacerDue(t *testing.T) {
+	t.Parallel()
+
+	for ti, tt := range []struct {
+		pacer Scheduler
+		hits  uint64
+		due   time.Duration
+		ok    bool
+	}{
+		{ConstantPacer{Freq: 1, Per: time.Second}, 0, time.Second, true},
+		{ConstantPacer{Freq: 2, Per: time.Second}, 9, 5 * time.Second, true},
+		{ConstantPacer{Freq: 0, Per: time.Second}, 9, 0, false},
+		{ConstantPacer{Freq: 1, Per: time.Hour}, 2562048, 0, false},
+		{sineTest{1, 1, 0}.Pacer(MeanUp), 0, time.Second, true},
+		{sineTest{1, 1, 0}.Pacer(MeanUp), 9, 10 * time.Second, true},
+		{sineTest{60, 1000, 999}.Pacer(MeanUp), 0, 0, true},
+		{sineTest{0, 100, 90}.Pacer(MeanUp), 0, 0, false},
+	} {
+		due, ok := tt.pacer.Due(tt.hits)
+		if ok != tt.ok || tt.due > 0 && !durationEqual(due, tt.due) {
+			t.Errorf("%d: %+v.Due(%d) = (%s, %t), want (%s, %t)", ti, tt.pacer, tt.hits, due, ok, tt.due, tt.ok)
+		}
+	}
+
+	// Hits are due when the sine Pacer lets them through after the previous
+	// one, give or take the 1e-3 hits Pace solves them within.
+	sp := sineTest{60, 100, 90}.Pacer(Trough)
+	for hits := uint64(1); hits < 500; hits += 50 {
+		prev, _ := sp.Due(hits - 1)
+		due, _ := sp.Due(hits)
+		if wait, _ := sp.Pace(prev, hits); math.Abs(float64(prev+wait-due)) > 1e-3/sp.hitsPerNs(due) {
+			t.Errorf("%+v.Due(%d) = %s, want %s", sp, hits, due, prev+wait)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+package vegeta
+
+import (
+	"sync"
+	"time"
+)
+
+// A Replay is a TargeterProvider of recorded Targets, which are hit in the
+// order they were recorded and exactly once. Its Pacer paces an attack
+// according to the time offsets at which the Targets were recorded.
+// Targets are decoded lazily, as the attack needs them.
+type Replay struct {
+	mu      sync.Mutex
+	decode  func(*Target) (time.Duration, error)
+	queue   []Target
+	count   uint64        // number of decoded Targets
+	skipped uint64        // number of skipped bad Targets
+	offset  time.Duration // offset of the last decoded Target
+	err     error
+}
+
+// NewReplay returns a new Replay which decodes its Targets with the given
+// function. It must return the offset at which each Target was recorded
+// relative to the first one, and ErrNoTargets when there are no more.
+func NewReplay(decode func(*Target) (time.Duration, error)) *Replay {
+	return &Replay{decode: decode}
+}
+
+// skipError is returned by the decode function of a Replay for a bad
+// recorded Target which is skipped rather than ending the replay.
+type skipError struct{ error }
+
+// Skipped returns the number of bad recorded Targets skipped so far.
+func (r *Replay) Skipped() uint64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.skipped
+}
+
+// Err returns the error which ended the replay before all its Targets were
+// decoded, if any.
+func (r *Replay) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err == ErrNoTargets {
+		return nil
+	}
+	return r.err
+}
+
+// NewTargeter returns the same Replay, because it's shared by all workers.
+func (r *Replay) NewTargeter() Targeter { return r }
+
+// Next returns the next recorded Target.
+func (r *Replay) Next(tgt *Target) error {
+	if tgt == nil {
+		return ErrNilTarget
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.queue) > 0 {
+		*tgt, r.queue = r.queue[0], r.queue[1:]
+		return nil
+	}
+
+	return r.next(tgt)
+}
+
+// Result is a noop.
+func (r *Replay) Result(body []byte, code uint16, err error) {}
+
+// next decodes the next Target. It must be called with the lock held.
+func (r *Replay) next(tgt *Target) error {
+	if r.err != nil {
+		return r.err
+	}
+
+	offset, err := r.decode(tgt)
+	for {
+		if _, skip := err.(skipError); !skip {
+			break
+		}
+		r.skipped++
+		offset, err = r.decode(tgt)
+	}
+
+	if err != nil {
+		r.err = err
+		return err
+	}
+
+	r.count++
+	r.offset = offset
+	return nil
+}
+
+// Pacer returns a Pacer which sends each hit at the offset its Target was
+// recorded at, divided by the given speed factor. For instance, a speed of
+// 2 replays the Targets twice as fast as they were recorded. The attack
+// stops when there are no more Targets to replay.
+func (r *Replay) Pacer(speed float64) Pacer {
+	return PacerFunc(func(elapsed time.Duration, hits uint64) (time.Duration, bool) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		// Decode the Target of the upcoming hit, if needed, and queue
+		// it for the worker which will get the tick.
+		for r.count <= hits {
+			var tgt Target
+			if err := r.next(&tgt); err != nil {
+				return 0, true
+			}
+			r.queue = append(r.queue, tgt)
+		}
+
+		if speed <= 0 {
+			return 0, false
+		}
+
+		return time.Duration(float64(r.offset)/speed) - elapsed, false
+	})
+}
@@ -0,0 +1,65 @@
+package vegeta
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplay(t *testing.T) {
+	t.Parallel()
+
+	offsets := []time.Duration{0, time.Second, 3 * time.Second}
+	newReplay := func() *Replay {
+		i := 0
+		return NewReplay(func(tgt *Target) (time.Duration, error) {
+			if i == len(offsets) {
+				return 0, ErrNoTargets
+			}
+			*tgt = Target{Method: "GET", URL: "http://" + string('a'+rune(i))}
+			i++
+			return offsets[i-1], nil
+		})
+	}
+
+	for _, tc := range []struct {
+		speed   float64
+		elapsed time.Duration
+		hits    uint64
+		wait    time.Duration
+		stop    bool
+	}{
+		{speed: 1, elapsed: 0, hits: 0, wait: 0},
+		{speed: 1, elapsed: 500 * time.Millisecond, hits: 1, wait: 500 * time.Millisecond},
+		{speed: 1, elapsed: time.Second, hits: 2, wait: 2 * time.Second},
+		{speed: 2, elapsed: time.Second, hits: 2, wait: 500 * time.Millisecond},
+		{speed: 0, elapsed: time.Second, hits: 2, wait: 0},
+		{speed: 1, elapsed: 4 * time.Second, hits: 3, stop: true},
+	} {
+		p := newReplay().Pacer(tc.speed)
+		wait, stop := p.Pace(tc.elapsed, tc.hits)
+		if wait != tc.wait || stop != tc.stop {
+			t.Errorf("%+v: got (%s, %t), want (%s, %t)", tc, wait, stop, tc.wait, tc.stop)
+		}
+	}
+
+	// Targets decoded by the Pacer are handed out in order.
+	r := newReplay()
+	p := r.Pacer(1)
+	for hits := uint64(0); hits < 2; hits++ {
+		p.Pace(0, hits)
+	}
+
+	tr := r.NewTargeter()
+	for i, want := range []string{"http://a", "http://b", "http://c"} {
+		var got Target
+		if err := tr.Next(&got); err != nil {
+			t.Fatalf("#%d: %s", i, err)
+		} else if got.URL != want {
+			t.Errorf("#%d: got %q, want %q", i, got.URL, want)
+		}
+	}
+
+	if err := tr.Next(&Target{}); err != ErrNoTargets {
+		t.Errorf("got error %v, want %v", err, ErrNoTargets)
+	}
+}
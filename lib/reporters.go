@@ -57,8 +57,28 @@ func NewHistogramReporter(h *Histogram) Reporter {
 func NewTextReporter(m *Metrics) Reporter {
 	const fmtstr = "Requests\t[total, rate, throughput]\t%d, %.2f, %.2f\n" +
 		"Duration\t[total, attack, wait]\t%s, %s, %s\n" +
-		"Latencies\t[mean, 50, 95, 99, max]\t%s, %s, %s, %s, %s\n" +
-		"Bytes In\t[total, mean]\t%d, %.2f\n" +
+		"Latencies\t[mean, 50, 95, 99, max]\t%s, %s, %s, %s, %s\n"
+
+	const correctedfmtstr = "Corrected\t[mean, 50, 95, 99, max]\t%s, %s, %s, %s, %s\n"
+
+	const timingsfmtstr = "DNS\t[mean, 50, 95, 99, max]\t%s, %s, %s, %s, %s\n" +
+		"Connect\t[mean, 50, 95, 99, max]\t%s, %s, %s, %s, %s\n" +
+		"TLS\t[mean, 50, 95, 99, max]\t%s, %s, %s, %s, %s\n" +
+		"First Byte\t[mean, 50, 95, 99, max]\t%s, %s, %s, %s, %s\n" +
+		"Transfer\t[mean, 50, 95, 99, max]\t%s, %s, %s, %s, %s\n" +
+		"Reused\t[ratio]\t%.2f%%\n"
+
+	const streamfmtstr = "Stream TTFB\t[mean, 50, 95, 99, max]\t%s, %s, %s, %s, %s\n" +
+		"First Event\t[mean, 50, 95, 99, max]\t%s, %s, %s, %s, %s\n" +
+		"Event Gaps\t[mean, 50, 95, 99, max]\t%s, %s, %s, %s, %s\n" +
+		"Events\t[total, mean]\t%d, %.2f\n"
+
+	const connsfmtstr = "Connections\t[opened, rate]\t%d, %.2f\n" +
+		"Handshakes\t[mean, 50, 95, 99, max]\t%s, %s, %s, %s, %s\n"
+
+	const retriesfmtstr = "Retries\t[total, retried, rate, ratio]\t%d, %d, %.2f, %.2f\n"
+
+	const bytesfmtstr = "Bytes In\t[total, mean]\t%d, %.2f\n" +
 		"Bytes Out\t[total, mean]\t%d, %.2f\n" +
 		"Success\t[ratio]\t%.2f%%\n" +
 		"Status Codes\t[code:count]\t"
@@ -69,6 +89,54 @@ func NewTextReporter(m *Metrics) Reporter {
 			m.Requests, m.Rate, m.Throughput,
 			m.Duration+m.Wait, m.Duration, m.Wait,
 			m.Latencies.Mean, m.Latencies.P50, m.Latencies.P95, m.Latencies.P99, m.Latencies.Max,
+		); err != nil {
+			return err
+		}
+
+		if c := m.CorrectedLatencies; c != nil {
+			if _, err = fmt.Fprintf(tw, correctedfmtstr, c.Mean, c.P50, c.P95, c.P99, c.Max); err != nil {
+				return err
+			}
+		}
+
+		if t := m.Timings; t != nil {
+			if _, err = fmt.Fprintf(tw, timingsfmtstr,
+				t.DNS.Mean, t.DNS.P50, t.DNS.P95, t.DNS.P99, t.DNS.Max,
+				t.Connect.Mean, t.Connect.P50, t.Connect.P95, t.Connect.P99, t.Connect.Max,
+				t.TLS.Mean, t.TLS.P50, t.TLS.P95, t.TLS.P99, t.TLS.Max,
+				t.FirstByte.Mean, t.FirstByte.P50, t.FirstByte.P95, t.FirstByte.P99, t.FirstByte.Max,
+				t.Transfer.Mean, t.Transfer.P50, t.Transfer.P95, t.Transfer.P99, t.Transfer.Max,
+				t.Reused*100,
+			); err != nil {
+				return err
+			}
+		}
+
+		if s := m.Stream; s != nil {
+			if _, err = fmt.Fprintf(tw, streamfmtstr,
+				s.FirstByte.Mean, s.FirstByte.P50, s.FirstByte.P95, s.FirstByte.P99, s.FirstByte.Max,
+				s.FirstEvent.Mean, s.FirstEvent.P50, s.FirstEvent.P95, s.FirstEvent.P99, s.FirstEvent.Max,
+				s.Gaps.Mean, s.Gaps.P50, s.Gaps.P95, s.Gaps.P99, s.Gaps.Max,
+				s.Events, s.Mean,
+			); err != nil {
+				return err
+			}
+		}
+
+		if c := m.Connections; c != nil {
+			h := c.Handshakes
+			if _, err = fmt.Fprintf(tw, connsfmtstr, c.Opened, c.Rate, h.Mean, h.P50, h.P95, h.P99, h.Max); err != nil {
+				return err
+			}
+		}
+
+		if r := m.Retries; r != nil {
+			if _, err = fmt.Fprintf(tw, retriesfmtstr, r.Total, r.Retried, r.Rate, r.Ratio); err != nil {
+				return err
+			}
+		}
+
+		if _, err = fmt.Fprintf(tw, bytesfmtstr,
 			m.BytesIn.Total, m.BytesIn.Mean,
 			m.BytesOut.Total, m.BytesOut.Mean,
 			m.Success*100,
@@ -111,6 +179,46 @@ func NewJSONReporter(m *Metrics) Reporter {
 	}
 }
 
+// NewGroupedTextReporter returns a Reporter that writes out the Metrics of
+// each group of GroupedMetrics as aligned, formatted text, headed by the key
+// of the group and in the order of the keys.
+func NewGroupedTextReporter(g *GroupedMetrics) Reporter {
+	return func(w io.Writer) error {
+		keys := make([]string, 0, len(g.Groups))
+		for key := range g.Groups {
+			keys = append(keys, key)
+		}
+
+		sort.Strings(keys)
+
+		for i, key := range keys {
+			if i > 0 {
+				if _, err := fmt.Fprintln(w); err != nil {
+					return err
+				}
+			}
+
+			if _, err := fmt.Fprintf(w, "Group: %s\n", key); err != nil {
+				return err
+			}
+
+			if err := NewTextReporter(g.Groups[key]).Report(w); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// NewGroupedJSONReporter returns a Reporter that writes out the Metrics of
+// each group of GroupedMetrics as a JSON object keyed by the groups' keys.
+func NewGroupedJSONReporter(g *GroupedMetrics) Reporter {
+	return func(w io.Writer) error {
+		return json.NewEncoder(w).Encode(g.Groups)
+	}
+}
+
 var logarithmic = []float64{
 	0.00,
 	0.100,
@@ -211,7 +319,26 @@ var logarithmic = []float64{
 // NewHDRHistogramPlotReporter returns a Reporter that writes out latency metrics
 // in a format plottable by http://hdrhistogram.github.io/HdrHistogram/plotFiles.html.
 func NewHDRHistogramPlotReporter(m *Metrics) Reporter {
+	return newHDRHistogramPlotReporter(m, func() *LatencyMetrics { return &m.Latencies })
+}
+
+// NewCorrectedHDRHistogramPlotReporter returns a Reporter like the one of
+// NewHDRHistogramPlotReporter that writes out the corrected latency metrics
+// when known.
+func NewCorrectedHDRHistogramPlotReporter(m *Metrics) Reporter {
+	return newHDRHistogramPlotReporter(m, func() *LatencyMetrics {
+		if m.CorrectedLatencies != nil {
+			return m.CorrectedLatencies
+		}
+		return &m.Latencies
+	})
+}
+
+// newHDRHistogramPlotReporter writes out the latency metrics returned by
+// latencies, which are only known once m is closed.
+func newHDRHistogramPlotReporter(m *Metrics, latencies func() *LatencyMetrics) Reporter {
 	return func(w io.Writer) error {
+		l := latencies()
 		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', tabwriter.StripEscape)
 		_, err := fmt.Fprintf(tw, "Value(ms)\tPercentile\tTotalCount\t1/(1-Percentile)\n")
 		if err != nil {
@@ -220,7 +347,7 @@ func NewHDRHistogramPlotReporter(m *Metrics) Reporter {
 
 		total := float64(m.Requests)
 		for _, q := range logarithmic {
-			value := milliseconds(m.Latencies.Quantile(q))
+			value := milliseconds(l.Quantile(q))
 			oneBy := oneByQuantile(q)
 			count := int64((q * total) + 0.5) // Count at quantile
 			_, err = fmt.Fprintf(tw, "%f\t%f\t%d\t%f\n", value, q, count, oneBy)
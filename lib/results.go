@@ -6,7 +6,12 @@ import (
 	"encoding/base64"
 	"encoding/csv"
 	"encoding/gob"
+	"encoding/json"
+	"fmt"
 	"io"
+	"net/http"
+	"net/textproto"
+	"reflect"
 	"sort"
 	"strconv"
 	"time"
@@ -21,20 +26,69 @@ func init() {
 
 // Result contains the results of a single Target hit.
 type Result struct {
-	Attack    string        `json:"attack"`
-	Seq       uint64        `json:"seq"`
-	Code      uint16        `json:"code"`
+	Attack string `json:"attack"`
+	Seq    uint64 `json:"seq"`
+	Code   uint16 `json:"code"`
+	// Timestamp is the time at which the hit was sent.
 	Timestamp time.Time     `json:"timestamp"`
 	Latency   time.Duration `json:"latency"`
 	BytesOut  uint64        `json:"bytes_out"`
 	BytesIn   uint64        `json:"bytes_in"`
 	Error     string        `json:"error"`
 	Body      []byte        `json:"body"`
+	Tag       string        `json:"tag,omitempty"`
+	// Intended is the time at which the Pacer of the attack scheduled the
+	// hit, which is zero when unknown. Its difference with Timestamp is the
+	// time the hit waited for an available worker or a Pacer running behind.
+	Intended time.Time `json:"intended,omitempty"`
+	// Timings is only set when the Attacker traces its hits.
+	Timings *Timings `json:"timings,omitempty"`
+	// Stream is only set when the Attacker streams the responses of its hits.
+	Stream *StreamStats `json:"stream,omitempty"`
+	// Handshake is the time the hit took to connect and complete the TLS
+	// handshake of a new connection, when the Attacker records it, and zero
+	// over reused connections.
+	Handshake time.Duration `json:"handshake,omitempty"`
+
+	// Method, URL, Proto, RemoteAddr, LocalAddr and Header are only set when
+	// the Attacker records the metadata and response headers of its hits,
+	// though HTTP/3 hits always record their Proto and hits bound to
+	// LocalAddrs their LocalAddr.
+	Method     string      `json:"method,omitempty"`
+	URL        string      `json:"url,omitempty"`
+	Proto      string      `json:"proto,omitempty"`
+	RemoteAddr string      `json:"remote_addr,omitempty"`
+	LocalAddr  string      `json:"local_addr,omitempty"`
+	Header     http.Header `json:"header,omitempty"`
+
+	// Retries is the number of times the hit was retried, with RetryErrors
+	// holding the errors of the retried attempts.
+	Retries     uint64   `json:"retries,omitempty"`
+	RetryErrors []string `json:"retry_errors,omitempty"`
+
+	// Message is the number of the WebSocket message whose round trip the
+	// Result records, counting from one within its connection, and zero for
+	// the Results of hits.
+	Message uint64 `json:"message,omitempty"`
+
+	messages []*Result // Results of the WebSocket messages of a hit
 }
 
 // End returns the time at which a Result ended.
 func (r *Result) End() time.Time { return r.Timestamp.Add(r.Latency) }
 
+// CorrectedLatency returns the latency of a Result measured from the time at
+// which it was intended to be sent, which includes the time it waited for an
+// available worker. This corrects for coordinated omission: the requests that
+// weren't sent on time because earlier ones were slow. It's equal to Latency
+// if the intended time is unknown.
+func (r *Result) CorrectedLatency() time.Duration {
+	if r.Intended.IsZero() || r.Intended.After(r.Timestamp) {
+		return r.Latency
+	}
+	return r.End().Sub(r.Intended)
+}
+
 // Equal returns true if the given Result is equal to the receiver.
 func (r Result) Equal(other Result) bool {
 	return r.Attack == other.Attack &&
@@ -45,9 +99,46 @@ func (r Result) Equal(other Result) bool {
 		r.BytesIn == other.BytesIn &&
 		r.BytesOut == other.BytesOut &&
 		r.Error == other.Error &&
+		r.Tag == other.Tag &&
+		r.Intended.Equal(other.Intended) &&
+		r.Timings.Equal(other.Timings) &&
+		r.Stream.Equal(other.Stream) &&
+		r.Method == other.Method &&
+		r.URL == other.URL &&
+		r.Proto == other.Proto &&
+		r.RemoteAddr == other.RemoteAddr &&
+		r.LocalAddr == other.LocalAddr &&
+		headerEqual(r.Header, other.Header) &&
+		r.Retries == other.Retries &&
+		stringsEqual(r.RetryErrors, other.RetryErrors) &&
+		r.Message == other.Message &&
+		r.Handshake == other.Handshake &&
 		bytes.Equal(r.Body, other.Body)
 }
 
+// headerEqual returns true if the given headers are equal, with nil and
+// empty ones being equal.
+func headerEqual(a, b http.Header) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// stringsEqual returns true if the given slices are equal, with nil and
+// empty ones being equal.
+func stringsEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
 // Results is a slice of Result type elements.
 type Results []Result
 
@@ -134,13 +225,67 @@ func NewEncoder(r io.Writer) Encoder {
 func (enc Encoder) Encode(r *Result) error { return enc(r) }
 
 // NewCSVEncoder returns an Encoder that dumps the given *Result as a CSV
-// record. The columns are: UNIX timestamp in ns since epoch,
-// HTTP status code, request latency in ns, bytes out, bytes in,
-// response body, and lastly the error.
+// record. The columns are:
+//
+//   - UNIX timestamp in ns since epoch
+//   - HTTP status code
+//   - request latency in ns
+//   - bytes out
+//   - bytes in
+//   - error
+//   - base64 encoded response body
+//   - attack name
+//   - sequence number
+//   - tag of the hit Target
+//   - intended UNIX timestamp in ns since epoch, if known
+//   - DNS duration in ns, if traced
+//   - connect duration in ns, if traced
+//   - TLS duration in ns, if traced
+//   - first byte duration in ns, if traced
+//   - transfer duration in ns, if traced
+//   - whether the connection was reused, if traced
+//   - method of the hit Target, if recorded
+//   - URL of the hit Target, if recorded
+//   - protocol of the response, if recorded
+//   - remote address of the response, if recorded
+//   - base64 encoded response headers in wire format, if recorded
+//   - number of retries, if retried
+//   - JSON array of the errors of the retried attempts, if retried
+//   - number of the WebSocket message, if any
+//   - time to the first byte of the stream in ns, if streamed
+//   - time to the first event of the stream in ns, if streamed
+//   - number of events of the stream, if streamed
+//   - JSON array of up to 100 sampled gaps between events in ns, if streamed
+//   - handshake duration of the connection in ns, if recorded
+//   - local address of the connection, if recorded
 func NewCSVEncoder(w io.Writer) Encoder {
 	enc := csv.NewWriter(w)
 	return func(r *Result) error {
-		err := enc.Write([]string{
+		intended := ""
+		if !r.Intended.IsZero() {
+			intended = strconv.FormatInt(r.Intended.UnixNano(), 10)
+		}
+
+		timings := make([]string, 6)
+		if t := r.Timings; t != nil {
+			timings = []string{
+				strconv.FormatInt(t.DNS.Nanoseconds(), 10),
+				strconv.FormatInt(t.Connect.Nanoseconds(), 10),
+				strconv.FormatInt(t.TLS.Nanoseconds(), 10),
+				strconv.FormatInt(t.FirstByte.Nanoseconds(), 10),
+				strconv.FormatInt(t.Transfer.Nanoseconds(), 10),
+				strconv.FormatBool(t.Reused),
+			}
+		}
+
+		var header bytes.Buffer
+		if len(r.Header) > 0 {
+			if err := r.Header.Write(&header); err != nil {
+				return err
+			}
+		}
+
+		rec := append([]string{
 			strconv.FormatInt(r.Timestamp.UnixNano(), 10),
 			strconv.FormatUint(uint64(r.Code), 10),
 			strconv.FormatInt(r.Latency.Nanoseconds(), 10),
@@ -150,7 +295,57 @@ func NewCSVEncoder(w io.Writer) Encoder {
 			base64.StdEncoding.EncodeToString(r.Body),
 			r.Attack,
 			strconv.FormatUint(r.Seq, 10),
-		})
+			r.Tag,
+			intended,
+		}, timings...)
+
+		retries, retryErrors := "", ""
+		if r.Retries > 0 || len(r.RetryErrors) > 0 {
+			errs, err := json.Marshal(r.RetryErrors)
+			if err != nil {
+				return err
+			}
+			retries, retryErrors = strconv.FormatUint(r.Retries, 10), string(errs)
+		}
+
+		message := ""
+		if r.Message > 0 {
+			message = strconv.FormatUint(r.Message, 10)
+		}
+
+		stream := make([]string, 4)
+		if st := r.Stream; st != nil {
+			gaps, err := json.Marshal(st.Gaps)
+			if err != nil {
+				return err
+			}
+			stream = []string{
+				strconv.FormatInt(st.FirstByte.Nanoseconds(), 10),
+				strconv.FormatInt(st.FirstEvent.Nanoseconds(), 10),
+				strconv.FormatUint(st.Events, 10),
+				string(gaps),
+			}
+		}
+
+		rec = append(rec,
+			r.Method,
+			r.URL,
+			r.Proto,
+			r.RemoteAddr,
+			base64.StdEncoding.EncodeToString(header.Bytes()),
+			retries,
+			retryErrors,
+			message,
+		)
+		rec = append(rec, stream...)
+
+		handshake := ""
+		if r.Handshake != 0 {
+			handshake = strconv.FormatInt(r.Handshake.Nanoseconds(), 10)
+		}
+		rec = append(rec, handshake, r.LocalAddr)
+
+		err := enc.Write(rec)
 
 		if err != nil {
 			return err
@@ -165,7 +360,9 @@ func NewCSVEncoder(w io.Writer) Encoder {
 // NewCSVDecoder returns a Decoder that decodes CSV encoded Results.
 func NewCSVDecoder(rd io.Reader) Decoder {
 	dec := csv.NewReader(rd)
-	dec.FieldsPerRecord = 9
+	// Records written before the tag, intended, timings and metadata
+	// columns were added have 9 fields.
+	dec.FieldsPerRecord = -1
 	dec.TrimLeadingSpace = true
 
 	return func(r *Result) error {
@@ -174,6 +371,10 @@ func NewCSVDecoder(rd io.Reader) Decoder {
 			return err
 		}
 
+		if len(rec) < 9 {
+			return fmt.Errorf("bad csv result: %d fields, want at least 9", len(rec))
+		}
+
 		ts, err := strconv.ParseInt(rec[0], 10, 64)
 		if err != nil {
 			return err
@@ -208,10 +409,131 @@ func NewCSVDecoder(rd io.Reader) Decoder {
 			return err
 		}
 
+		if r.Tag = ""; len(rec) > 9 {
+			r.Tag = rec[9]
+		}
+
+		if r.Intended = (time.Time{}); len(rec) > 10 && rec[10] != "" {
+			intended, err := strconv.ParseInt(rec[10], 10, 64)
+			if err != nil {
+				return err
+			}
+			r.Intended = time.Unix(0, intended)
+		}
+
+		if r.Timings = nil; len(rec) > 16 && rec[11] != "" {
+			if r.Timings, err = decodeCSVTimings(rec[11:17]); err != nil {
+				return err
+			}
+		}
+
+		r.Method, r.URL, r.Proto, r.RemoteAddr, r.Header = "", "", "", "", nil
+		if len(rec) > 21 {
+			r.Method, r.URL, r.Proto, r.RemoteAddr = rec[17], rec[18], rec[19], rec[20]
+			if r.Header, err = decodeCSVHeader(rec[21]); err != nil {
+				return err
+			}
+		}
+
+		r.Retries, r.RetryErrors = 0, nil
+		if len(rec) > 23 && rec[22] != "" {
+			if r.Retries, err = strconv.ParseUint(rec[22], 10, 64); err != nil {
+				return err
+			} else if err = json.Unmarshal([]byte(rec[23]), &r.RetryErrors); err != nil {
+				return err
+			}
+		}
+
+		if r.Message = 0; len(rec) > 24 && rec[24] != "" {
+			if r.Message, err = strconv.ParseUint(rec[24], 10, 64); err != nil {
+				return err
+			}
+		}
+
+		if r.Stream = nil; len(rec) > 28 && rec[25] != "" {
+			if r.Stream, err = decodeCSVStream(rec[25:29]); err != nil {
+				return err
+			}
+		}
+
+		if r.Handshake = 0; len(rec) > 29 && rec[29] != "" {
+			ns, err := strconv.ParseInt(rec[29], 10, 64)
+			if err != nil {
+				return err
+			}
+			r.Handshake = time.Duration(ns)
+		}
+
+		if r.LocalAddr = ""; len(rec) > 30 {
+			r.LocalAddr = rec[30]
+		}
+
 		return err
 	}
 }
 
+func decodeCSVHeader(col string) (http.Header, error) {
+	if col == "" {
+		return nil, nil
+	}
+
+	wire, err := base64.StdEncoding.DecodeString(col)
+	if err != nil {
+		return nil, err
+	}
+
+	// The blank line ends the header block.
+	wire = append(wire, '\r', '\n')
+	h, err := textproto.NewReader(bufio.NewReader(bytes.NewReader(wire))).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+
+	return http.Header(h), nil
+}
+
+func decodeCSVTimings(rec []string) (*Timings, error) {
+	var t Timings
+	for i, d := range []*time.Duration{&t.DNS, &t.Connect, &t.TLS, &t.FirstByte, &t.Transfer} {
+		ns, err := strconv.ParseInt(rec[i], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		*d = time.Duration(ns)
+	}
+
+	reused, err := strconv.ParseBool(rec[5])
+	if err != nil {
+		return nil, err
+	}
+	t.Reused = reused
+
+	return &t, nil
+}
+
+func decodeCSVStream(rec []string) (*StreamStats, error) {
+	var st StreamStats
+	for i, d := range []*time.Duration{&st.FirstByte, &st.FirstEvent} {
+		ns, err := strconv.ParseInt(rec[i], 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		*d = time.Duration(ns)
+	}
+
+	events, err := strconv.ParseUint(rec[2], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	st.Events = events
+
+	if err = json.Unmarshal([]byte(rec[3]), &st.Gaps); err != nil {
+		return nil, err
+	}
+
+	return &st, nil
+}
+
 // NewJSONEncoder returns an Encoder that dumps the given *Results as a JSON
 // object.
 func NewJSONEncoder(w io.Writer) Encoder {
@@ -232,7 +554,19 @@ func NewJSONDecoder(r io.Reader) Decoder {
 	rd := bufio.NewReader(r)
 	return func(r *Result) (err error) {
 		var jl jlexer.Lexer
-		if jl.Data, err = rd.ReadSlice('\n'); err != nil {
+		jl.Data, err = rd.ReadSlice('\n')
+		if err == bufio.ErrBufferFull {
+			// Lines longer than the buffer, like those of large bodies,
+			// are read in chunks.
+			line := append([]byte(nil), jl.Data...)
+			for err == bufio.ErrBufferFull {
+				jl.Data, err = rd.ReadSlice('\n')
+				line = append(line, jl.Data...)
+			}
+			jl.Data = line
+		}
+
+		if err != nil {
 			return err
 		}
 		(*jsonResult)(r).decode(&jl)
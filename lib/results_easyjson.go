@@ -4,6 +4,7 @@
 package vegeta
 
 import (
+	"net/http"
 	"time"
 
 	"github.com/mailru/easyjson/jlexer"
@@ -41,6 +42,10 @@ func (r *jsonResult) decode(in *jlexer.Lexer) {
 			if data := in.Raw(); in.Ok() {
 				in.AddError((r.Timestamp).UnmarshalJSON(data))
 			}
+		case "intended":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((r.Intended).UnmarshalJSON(data))
+			}
 		case "latency":
 			r.Latency = time.Duration(in.Int64())
 		case "bytes_out":
@@ -49,6 +54,63 @@ func (r *jsonResult) decode(in *jlexer.Lexer) {
 			r.BytesIn = uint64(in.Uint64())
 		case "error":
 			r.Error = string(in.String())
+		case "tag":
+			r.Tag = string(in.String())
+		case "timings":
+			r.Timings = new(Timings)
+			decodeTimings(in, r.Timings)
+		case "stream":
+			r.Stream = new(StreamStats)
+			decodeStream(in, r.Stream)
+		case "method":
+			r.Method = string(in.String())
+		case "url":
+			r.URL = string(in.String())
+		case "proto":
+			r.Proto = string(in.String())
+		case "remote_addr":
+			r.RemoteAddr = string(in.String())
+		case "local_addr":
+			r.LocalAddr = string(in.String())
+		case "retries":
+			r.Retries = uint64(in.Uint64())
+		case "message":
+			r.Message = uint64(in.Uint64())
+		case "handshake":
+			r.Handshake = time.Duration(in.Int64())
+		case "retry_errors":
+			in.Delim('[')
+			r.RetryErrors = nil
+			for !in.IsDelim(']') {
+				r.RetryErrors = append(r.RetryErrors, string(in.String()))
+				in.WantComma()
+			}
+			in.Delim(']')
+		case "header":
+			in.Delim('{')
+			if !in.IsDelim('}') {
+				r.Header = make(http.Header)
+			} else {
+				r.Header = nil
+			}
+			for !in.IsDelim('}') {
+				key := string(in.String())
+				in.WantColon()
+				var v1 []string
+				if in.IsNull() {
+					in.Skip()
+				} else {
+					in.Delim('[')
+					for !in.IsDelim(']') {
+						v1 = append(v1, string(in.String()))
+						in.WantComma()
+					}
+					in.Delim(']')
+				}
+				(r.Header)[key] = v1
+				in.WantComma()
+			}
+			in.Delim('}')
 		case "body":
 			if in.IsNull() {
 				in.Skip()
@@ -161,5 +223,271 @@ func (r jsonResult) encode(out *jwriter.Writer) {
 		}
 		out.Base64Bytes(r.Body)
 	}
+	if r.Tag != "" {
+		const prefix string = ",\"tag\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(r.Tag))
+	}
+	if !r.Intended.IsZero() {
+		const prefix string = ",\"intended\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Raw((r.Intended).MarshalJSON())
+	}
+	if r.Timings != nil {
+		const prefix string = ",\"timings\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		encodeTimings(out, r.Timings)
+	}
+	if r.Stream != nil {
+		const prefix string = ",\"stream\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		encodeStream(out, r.Stream)
+	}
+	if r.Method != "" {
+		const prefix string = ",\"method\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(r.Method))
+	}
+	if r.URL != "" {
+		const prefix string = ",\"url\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(r.URL))
+	}
+	if r.Proto != "" {
+		const prefix string = ",\"proto\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(r.Proto))
+	}
+	if r.RemoteAddr != "" {
+		const prefix string = ",\"remote_addr\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(r.RemoteAddr))
+	}
+	if r.LocalAddr != "" {
+		const prefix string = ",\"local_addr\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(r.LocalAddr))
+	}
+	if len(r.Header) != 0 {
+		const prefix string = ",\"header\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.RawByte('{')
+		v1First := true
+		for v1Name, v1Value := range r.Header {
+			if v1First {
+				v1First = false
+			} else {
+				out.RawByte(',')
+			}
+			out.String(string(v1Name))
+			out.RawByte(':')
+			out.RawByte('[')
+			for v2, v3 := range v1Value {
+				if v2 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v3))
+			}
+			out.RawByte(']')
+		}
+		out.RawByte('}')
+	}
+	if r.Retries != 0 {
+		const prefix string = ",\"retries\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Uint64(uint64(r.Retries))
+	}
+	if len(r.RetryErrors) != 0 {
+		const prefix string = ",\"retry_errors\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.RawByte('[')
+		for v4, v5 := range r.RetryErrors {
+			if v4 > 0 {
+				out.RawByte(',')
+			}
+			out.String(string(v5))
+		}
+		out.RawByte(']')
+	}
+	if r.Message != 0 {
+		const prefix string = ",\"message\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Uint64(uint64(r.Message))
+	}
+	if r.Handshake != 0 {
+		const prefix string = ",\"handshake\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int64(int64(r.Handshake))
+	}
+	out.RawByte('}')
+}
+
+func decodeTimings(in *jlexer.Lexer, t *Timings) {
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "dns":
+			t.DNS = time.Duration(in.Int64())
+		case "connect":
+			t.Connect = time.Duration(in.Int64())
+		case "tls":
+			t.TLS = time.Duration(in.Int64())
+		case "first_byte":
+			t.FirstByte = time.Duration(in.Int64())
+		case "transfer":
+			t.Transfer = time.Duration(in.Int64())
+		case "reused":
+			t.Reused = bool(in.Bool())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+
+func encodeTimings(out *jwriter.Writer, t *Timings) {
+	out.RawString("{\"dns\":")
+	out.Int64(int64(t.DNS))
+	out.RawString(",\"connect\":")
+	out.Int64(int64(t.Connect))
+	out.RawString(",\"tls\":")
+	out.Int64(int64(t.TLS))
+	out.RawString(",\"first_byte\":")
+	out.Int64(int64(t.FirstByte))
+	out.RawString(",\"transfer\":")
+	out.Int64(int64(t.Transfer))
+	out.RawString(",\"reused\":")
+	out.Bool(t.Reused)
+	out.RawByte('}')
+}
+
+func decodeStream(in *jlexer.Lexer, st *StreamStats) {
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeString()
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "first_byte":
+			st.FirstByte = time.Duration(in.Int64())
+		case "first_event":
+			st.FirstEvent = time.Duration(in.Int64())
+		case "events":
+			st.Events = uint64(in.Uint64())
+		case "gaps":
+			in.Delim('[')
+			st.Gaps = nil
+			for !in.IsDelim(']') {
+				st.Gaps = append(st.Gaps, time.Duration(in.Int64()))
+				in.WantComma()
+			}
+			in.Delim(']')
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+}
+
+func encodeStream(out *jwriter.Writer, st *StreamStats) {
+	out.RawString("{\"first_byte\":")
+	out.Int64(int64(st.FirstByte))
+	out.RawString(",\"first_event\":")
+	out.Int64(int64(st.FirstEvent))
+	out.RawString(",\"events\":")
+	out.Uint64(st.Events)
+	if len(st.Gaps) != 0 {
+		out.RawString(",\"gaps\":[")
+		for i, gap := range st.Gaps {
+			if i > 0 {
+				out.RawByte(',')
+			}
+			out.Int64(int64(gap))
+		}
+		out.RawByte(']')
+	}
 	out.RawByte('}')
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"io"
 	"math/rand"
+	"net/http"
 	"reflect"
 	"testing"
 	"testing/quick"
@@ -65,7 +66,7 @@ func TestResultEncoding(t *testing.T) {
 		t.Run(tc.encoding, func(t *testing.T) {
 			t.Parallel()
 
-			err := quick.Check(func(code uint16, ts uint32, latency time.Duration, seq, bsIn, bsOut uint64, body []byte, attack, e string) bool {
+			err := quick.Check(func(code uint16, ts uint32, latency time.Duration, seq, bsIn, bsOut uint64, body []byte, attack, e, tag string, intended uint32, traced bool, timings Timings, method, url, proto, raddr, laddr string, headers bool, retries uint64, retryErrors []string, message uint64, streamed bool, stream StreamStats, handshake time.Duration) bool {
 				want := Result{
 					Attack:    attack,
 					Seq:       seq,
@@ -76,6 +77,28 @@ func TestResultEncoding(t *testing.T) {
 					BytesOut:  bsOut,
 					Error:     e,
 					Body:      body,
+					Tag:       tag,
+					Intended:  time.Unix(int64(intended), 0),
+				}
+
+				if traced {
+					want.Timings = &timings
+				}
+
+				if streamed {
+					want.Stream = &stream
+				}
+
+				want.Method, want.URL, want.Proto, want.RemoteAddr = method, url, proto, raddr
+				want.LocalAddr = laddr
+				want.Retries, want.RetryErrors = retries, retryErrors
+				want.Message = message
+				want.Handshake = handshake
+				if headers {
+					want.Header = http.Header{
+						"Content-Type": []string{"text/plain"},
+						"X-Vegeta":     []string{"1", "2"},
+					}
 				}
 
 				var buf bytes.Buffer
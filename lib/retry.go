@@ -0,0 +1,170 @@
+package vegeta
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A RetryCondition returns true if a hit attempt with the given status code,
+// which is zero without a response, and error should be retried.
+type RetryCondition func(code uint16, err error) bool
+
+// RetryOnCodes returns a RetryCondition met by responses with any of the
+// given status codes.
+func RetryOnCodes(codes ...uint16) RetryCondition {
+	return func(code uint16, err error) bool {
+		if err != nil {
+			return false
+		}
+		for _, c := range codes {
+			if code == c {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// RetryOnErrors returns a RetryCondition met by errors of any of the given
+// kinds.
+func RetryOnErrors(kinds ...ErrorKind) RetryCondition {
+	return func(_ uint16, err error) bool {
+		if err == nil {
+			return false
+		}
+		kind := classify(err)
+		for _, k := range kinds {
+			if kind == k {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// ParseRetryCondition parses a RetryCondition, which is one of:
+//
+//    <code>    a response with the given status code, e.g. 503
+//    <n>xx     a response with a status code of the given class, e.g. 5xx
+//    timeout   a timed out hit
+//    dns       a hit whose host couldn't be resolved
+//    refused   a hit whose connection was refused
+//    reset     a hit whose connection was reset or closed early
+//    tls       a hit that failed to establish a TLS session
+//    error     a hit that failed with any error
+func ParseRetryCondition(s string) (RetryCondition, error) {
+	switch s = strings.TrimSpace(s); s {
+	case "timeout":
+		return RetryOnErrors(TimeoutError), nil
+	case "dns":
+		return RetryOnErrors(DNSError), nil
+	case "refused":
+		return RetryOnErrors(ConnectionRefusedError), nil
+	case "reset":
+		return RetryOnErrors(ConnectionResetError), nil
+	case "tls":
+		return RetryOnErrors(TLSError), nil
+	case "error":
+		return func(_ uint16, err error) bool { return err != nil }, nil
+	}
+
+	if len(s) == 3 && strings.HasSuffix(s, "xx") && s[0] >= '1' && s[0] <= '5' {
+		class := uint16(s[0]-'0') * 100
+		return func(code uint16, err error) bool {
+			return err == nil && code >= class && code < class+100
+		}, nil
+	}
+
+	code, err := strconv.ParseUint(s, 10, 16)
+	if err != nil || code < 100 || code > 599 {
+		return nil, fmt.Errorf("bad retry condition: %s", s)
+	}
+
+	return RetryOnCodes(uint16(code)), nil
+}
+
+// DefaultRetryConditions retry hits with 502 and 503 responses or whose
+// connection was reset.
+var DefaultRetryConditions = []RetryCondition{
+	RetryOnCodes(502, 503),
+	RetryOnErrors(ConnectionResetError),
+}
+
+const (
+	// DefaultRetryBackoff is the default base delay of the exponential
+	// backoff between retries.
+	DefaultRetryBackoff = 100 * time.Millisecond
+	// DefaultRetryMaxBackoff is the default maximum delay between retries.
+	DefaultRetryMaxBackoff = 5 * time.Second
+)
+
+// A RetryPolicy sets how an Attacker retries failed hits.
+type RetryPolicy struct {
+	// Max is the maximum number of retries of a hit. Hits aren't retried
+	// if it's zero.
+	Max int
+	// On holds the conditions of which any must be met by an attempt for
+	// it to be retried. It defaults to DefaultRetryConditions.
+	On []RetryCondition
+	// Backoff is the base delay of the exponential backoff between
+	// retries, which doubles with each retry. It defaults to
+	// DefaultRetryBackoff.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries. It defaults to
+	// DefaultRetryMaxBackoff.
+	MaxBackoff time.Duration
+}
+
+// Retries returns a functional option which makes an Attacker retry failed
+// hits according to the given RetryPolicy. Retries wait for a random delay,
+// known as jitter, of up to the exponential backoff so that the retries of
+// hits which failed together are spread out. The Results of retried hits
+// record the errors of their retried attempts, and their latency includes
+// all attempts and delays.
+func Retries(p RetryPolicy) func(*Attacker) {
+	return func(a *Attacker) {
+		if p.On == nil {
+			p.On = DefaultRetryConditions
+		}
+		if p.Backoff <= 0 {
+			p.Backoff = DefaultRetryBackoff
+		}
+		if p.MaxBackoff <= 0 {
+			p.MaxBackoff = DefaultRetryMaxBackoff
+		}
+		a.retries = p
+	}
+}
+
+// retry returns true if an attempt with the given number of previous
+// retries, status code and error should be retried.
+func (p RetryPolicy) retry(retries int, code uint16, err error) bool {
+	var reqErr *requestError
+	if retries >= p.Max || errors.As(err, &reqErr) {
+		return false
+	}
+
+	for _, on := range p.On {
+		if on(code, err) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// backoff returns the delay before the retry following the given number of
+// previous retries.
+func (p RetryPolicy) backoff(retries int) time.Duration {
+	d := p.MaxBackoff
+	if retries < 32 {
+		if exp := p.Backoff << uint(retries); exp > 0 && exp < d {
+			d = exp
+		}
+	}
+	return time.Duration(rand.Int63n(int64(d) + 1))
+}
@@ -0,0 +1,258 @@
+package vegeta
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// ScenarioStep is a single request of a Scenario. Its URL, header values
+// and body may reference scenario variables with {{name}} placeholders.
+type ScenarioStep struct {
+	Name   string      `json:"name,omitempty"`
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Body   string      `json:"body,omitempty"`
+	Header http.Header `json:"header,omitempty"`
+	// Extract maps variable names to extraction expressions which are
+	// evaluated against the response body of the step. The supported
+	// expressions are "jsonpath:<path>" and "regex:<pattern>".
+	Extract map[string]string `json:"extract,omitempty"`
+}
+
+// Scenario is a declarative sequence of steps that each worker of an attack
+// runs in a loop. Values extracted from the response of one step become
+// variables usable by the following steps. Placeholders which don't name a
+// variable are resolved by the built-in generators documented in Templating.
+type Scenario struct {
+	Vars  map[string]string `json:"vars,omitempty"`
+	Steps []ScenarioStep    `json:"steps"`
+}
+
+// compiledStep is a ScenarioStep with its templates and extractors parsed.
+type compiledStep struct {
+	method  string
+	url     tmpl
+	body    tmpl
+	header  map[string][]tmpl
+	extract map[string]extractor
+}
+
+type scenarioTargeter struct {
+	vars  map[string]string
+	steps []compiledStep
+	body  []byte
+	hdr   http.Header
+	gen   generators
+}
+
+// NewTargeter returns a new Targeter with its own copy of the scenario
+// variables and progress, so each worker runs the scenario independently.
+func (s *scenarioTargeter) NewTargeter() Targeter {
+	return &scenarioWorker{scenarioTargeter: s, vars: s.initVars()}
+}
+
+func (s *scenarioTargeter) initVars() map[string]string {
+	vars := make(map[string]string, len(s.vars))
+	for k, v := range s.vars {
+		vars[k] = v
+	}
+	return vars
+}
+
+// scenarioWorker runs a scenario on behalf of a single worker.
+type scenarioWorker struct {
+	*scenarioTargeter
+	mu      sync.Mutex
+	vars    map[string]string
+	step    int
+	pending bool
+}
+
+func (w *scenarioWorker) Next(tgt *Target) (err error) {
+	if tgt == nil {
+		return ErrNilTarget
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// The previous step wasn't fed back, so its extractions never ran.
+	if w.pending {
+		w.restart()
+	}
+
+	step := &w.steps[w.step]
+	lookup := w.gen.lookup(w.vars)
+
+	tgt.Method = step.method
+	if tgt.URL, err = step.url.render(lookup); err != nil {
+		return err
+	}
+
+	tgt.Body = w.body
+	if len(step.body) > 0 {
+		body, err := step.body.render(lookup)
+		if err != nil {
+			return err
+		}
+		tgt.Body = []byte(body)
+	}
+
+	tgt.Header = http.Header{}
+	for k, vs := range w.hdr {
+		tgt.Header[k] = append(tgt.Header[k], vs...)
+	}
+
+	for k, ts := range step.header {
+		for _, t := range ts {
+			v, err := t.render(lookup)
+			if err != nil {
+				return err
+			}
+			tgt.Header[k] = append(tgt.Header[k], v)
+		}
+	}
+
+	w.pending = true
+	return nil
+}
+
+// Result advances the scenario to its next step when the current one
+// succeeded and all its extractions matched. Otherwise the scenario starts
+// over from its first step.
+func (w *scenarioWorker) Result(body []byte, code uint16, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.pending = false
+	if err != nil || code < 200 || code >= 400 {
+		w.restart()
+		return
+	}
+
+	for name, ex := range w.steps[w.step].extract {
+		v, err := ex.extract(body)
+		if err != nil {
+			w.restart()
+			return
+		}
+		w.vars[name] = v
+	}
+
+	if w.step++; w.step == len(w.steps) {
+		w.step = 0
+	}
+}
+
+func (w *scenarioWorker) restart() {
+	w.step, w.pending = 0, false
+	w.vars = w.initVars()
+}
+
+// NewScenarioTargeter returns a TargeterProvider that runs the JSON encoded
+// Scenario read from the given io.Reader. Every Targeter it provides runs the
+// scenario steps in order and in a loop, with its own set of variables.
+//
+//    {
+//      "vars": {"user": "goku"},
+//      "steps": [
+//        {
+//          "method": "POST",
+//          "url": "https://goku/login",
+//          "body": "{\"user\": \"{{user}}\"}",
+//          "extract": {"token": "jsonpath:$.token"}
+//        },
+//        {
+//          "method": "GET",
+//          "url": "https://goku/items",
+//          "header": {"Authorization": ["Bearer {{token}}"]},
+//          "extract": {"item": "regex:\"id\":\\s*\"(\\w+)\""}
+//        },
+//        {"method": "GET", "url": "https://goku/items/{{item}}"}
+//      ]
+//    }
+//
+// A step whose request fails, whose response isn't successful or whose
+// extractions don't match makes the scenario start over from its first step.
+//
+// body will be set as the Target's body if no body is provided in a step.
+// hdr will be merged with the each step's headers.
+func NewScenarioTargeter(src io.Reader, body []byte, hdr http.Header) (TargeterProvider, error) {
+	var sc Scenario
+	if err := json.NewDecoder(src).Decode(&sc); err != nil {
+		return nil, fmt.Errorf("bad scenario: %s", err)
+	}
+	return newScenarioTargeter(&sc, body, hdr)
+}
+
+func newScenarioTargeter(sc *Scenario, body []byte, hdr http.Header) (*scenarioTargeter, error) {
+	if len(sc.Steps) == 0 {
+		return nil, ErrNoTargets
+	}
+
+	s := &scenarioTargeter{
+		vars:  sc.Vars,
+		steps: make([]compiledStep, len(sc.Steps)),
+		body:  body,
+		hdr:   hdr,
+	}
+
+	for i, st := range sc.Steps {
+		name := st.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", i+1)
+		}
+
+		c, err := compileStep(&st)
+		if err != nil {
+			return nil, fmt.Errorf("bad scenario step %s: %s", name, err)
+		}
+		s.steps[i] = *c
+	}
+
+	return s, nil
+}
+
+func compileStep(st *ScenarioStep) (_ *compiledStep, err error) {
+	switch {
+	case st.Method == "":
+		return nil, ErrNoMethod
+	case st.URL == "":
+		return nil, ErrNoURL
+	}
+
+	c := compiledStep{
+		method:  st.Method,
+		header:  make(map[string][]tmpl, len(st.Header)),
+		extract: make(map[string]extractor, len(st.Extract)),
+	}
+
+	if c.url, err = parseTmpl(st.URL); err != nil {
+		return nil, err
+	}
+
+	if c.body, err = parseTmpl(st.Body); err != nil {
+		return nil, err
+	}
+
+	for k, vs := range st.Header {
+		for _, v := range vs {
+			t, err := parseTmpl(v)
+			if err != nil {
+				return nil, err
+			}
+			c.header[k] = append(c.header[k], t)
+		}
+	}
+
+	for name, expr := range st.Extract {
+		if c.extract[name], err = parseExtractor(expr); err != nil {
+			return nil, err
+		}
+	}
+
+	return &c, nil
+}
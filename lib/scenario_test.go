@@ -0,0 +1,156 @@
+package vegeta
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestScenarioTargeter(t *testing.T) {
+	t.Parallel()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/login":
+			body, _ := ioutil.ReadAll(r.Body)
+			if string(body) != `{"user":"goku"}` {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"token": "kamehameha"}`)
+		case "/items":
+			if r.Header.Get("Authorization") != "Bearer kamehameha" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			fmt.Fprint(w, `{"items": [{"id": 7}, {"id": 8}]}`)
+		case "/items/7":
+			fmt.Fprint(w, `<h1>Dragon Ball #7</h1>`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	src := strings.NewReader(`{
+		"vars": {"user": "goku"},
+		"steps": [
+			{
+				"method": "POST",
+				"url": "` + server.URL + `/login",
+				"body": "{\"user\":\"{{user}}\"}",
+				"extract": {"token": "jsonpath:$.token"}
+			},
+			{
+				"method": "GET",
+				"url": "` + server.URL + `/items",
+				"header": {"Authorization": ["Bearer {{token}}"]},
+				"extract": {"item": "jsonpath:$.items[0].id"}
+			},
+			{
+				"method": "GET",
+				"url": "` + server.URL + `/items/{{item}}",
+				"extract": {"title": "regex:<h1>(.+)</h1>"}
+			}
+		]
+	}`)
+
+	tr, err := NewScenarioTargeter(src, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	atk := NewAttacker()
+	workers := []Targeter{tr.NewTargeter(), tr.NewTargeter()}
+	for i := 0; i < 2; i++ {
+		for _, w := range workers {
+			for _, want := range []string{"/login", "/items", "/items/7"} {
+				res := atk.hit(w, "")
+				if res.Error != "" {
+					t.Fatalf("got error: %s", res.Error)
+				}
+				if got := w.(*scenarioWorker); got.step == 0 && want != "/items/7" {
+					t.Fatalf("scenario restarted after %s", want)
+				}
+			}
+		}
+	}
+
+	if got, want := workers[0].(*scenarioWorker).vars["title"], "Dragon Ball #7"; got != want {
+		t.Errorf("got title %q, want %q", got, want)
+	}
+}
+
+func TestScenarioTargeterRestart(t *testing.T) {
+	t.Parallel()
+
+	tr, err := NewScenarioTargeter(strings.NewReader(`{
+		"vars": {"id": "1"},
+		"steps": [
+			{"method": "GET", "url": "http://goku/{{id}}", "extract": {"id": "jsonpath:$.next"}},
+			{"method": "GET", "url": "http://goku/{{id}}"}
+		]
+	}`), []byte("default"), http.Header{"X-Saiyan": []string{"yes"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	w := tr.NewTargeter()
+	for _, tc := range []struct {
+		url  string
+		body string
+		code uint16
+		err  error
+	}{
+		{url: "http://goku/1", body: `{"next": 2}`, code: 200},
+		{url: "http://goku/2", code: 500},
+		{url: "http://goku/1", body: `{"previous": 0}`, code: 200},
+		{url: "http://goku/1", err: ErrNoTargets},
+		{url: "http://goku/1", body: `{"next": 3}`, code: 200},
+		{url: "http://goku/3", code: 200},
+		{url: "http://goku/3", body: `{"next": 4}`, code: 200},
+	} {
+		var tgt Target
+		if err := w.Next(&tgt); err != nil {
+			t.Fatal(err)
+		}
+
+		if got, want := tgt.URL, tc.url; got != want {
+			t.Fatalf("got url %q, want %q", got, want)
+		}
+
+		if got, want := string(tgt.Body), "default"; got != want {
+			t.Fatalf("got body %q, want %q", got, want)
+		}
+
+		if got, want := tgt.Header.Get("X-Saiyan"), "yes"; got != want {
+			t.Fatalf("got header %q, want %q", got, want)
+		}
+
+		w.Result([]byte(tc.body), tc.code, tc.err)
+	}
+}
+
+func TestNewScenarioTargeterErrors(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		in  string
+		err string
+	}{
+		{`{`, "bad scenario"},
+		{`{"steps": []}`, ErrNoTargets.Error()},
+		{`{"steps": [{"url": "http://goku"}]}`, "bad scenario step #1: " + ErrNoMethod.Error()},
+		{`{"steps": [{"name": "a", "method": "GET"}]}`, "bad scenario step a: " + ErrNoURL.Error()},
+		{`{"steps": [{"method": "GET", "url": "http://goku/{{id"}]}`, "bad scenario step #1: template: unclosed"},
+		{`{"steps": [{"method": "GET", "url": "http://goku", "extract": {"a": "xpath://a"}}]}`, "bad scenario step #1: bad extractor kind"},
+	} {
+		_, err := NewScenarioTargeter(strings.NewReader(tc.in), nil, nil)
+		if err == nil || !strings.HasPrefix(err.Error(), tc.err) {
+			t.Errorf("%s: got error %v, want %q", tc.in, err, tc.err)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package vegeta
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"math/rand"
+	"mime"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// StreamStats holds measures of a response body read as a stream of events
+// as they arrive, like the Server-Sent Events of text/event-stream responses
+// or the lines of newline delimited JSON ones.
+type StreamStats struct {
+	// FirstByte is the time from the start of the request to the first
+	// byte of the response.
+	FirstByte time.Duration `json:"first_byte"`
+	// FirstEvent is the time from the start of the request to the end of
+	// the first event, which is zero without events.
+	FirstEvent time.Duration `json:"first_event"`
+	// Events is the number of events received.
+	Events uint64 `json:"events"`
+	// Gaps holds the times between the ends of consecutive events, sampled
+	// uniformly down to maxStreamGaps of them in longer streams.
+	Gaps []time.Duration `json:"gaps,omitempty"`
+}
+
+// maxStreamGaps is the number of gaps StreamStats hold at most, which keeps
+// the Results of long held streams small.
+const maxStreamGaps = 100
+
+// Equal returns true if the given StreamStats are equal to the receiver.
+func (s *StreamStats) Equal(other *StreamStats) bool {
+	if s == nil || other == nil {
+		return s == other
+	}
+
+	if s.FirstByte != other.FirstByte ||
+		s.FirstEvent != other.FirstEvent ||
+		s.Events != other.Events ||
+		len(s.Gaps) != len(other.Gaps) {
+		return false
+	}
+
+	for i := range s.Gaps {
+		if s.Gaps[i] != other.Gaps[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Stream returns a functional option which makes an Attacker read response
+// bodies as streams of events and record their StreamStats. Events are
+// Server-Sent Events in text/event-stream responses and non-empty lines in
+// others.
+func Stream(stream bool) func(*Attacker) {
+	return func(a *Attacker) { a.stream = stream }
+}
+
+// StreamHold returns a functional option which makes an Attacker hold
+// streamed response bodies open for at most the given duration, after
+// which they're closed without error, instead of reading them until their
+// end. Timeouts then only apply until response headers are received.
+func StreamHold(hold time.Duration) func(*Attacker) {
+	return func(a *Attacker) { a.streamHold = hold }
+}
+
+// readStream reads the body of the given response as a stream of events
+// until its end or until it's held long enough, recording its StreamStats
+// measured from the given start of the request in res.
+func (a *Attacker) readStream(r *http.Response, start time.Time, firstByte time.Duration, res *Result) error {
+	done := make(chan struct{})
+	defer close(done)
+
+	var held int32
+	go func() {
+		var hold <-chan time.Time
+		if a.streamHold > 0 {
+			timer := time.NewTimer(a.streamHold)
+			defer timer.Stop()
+			hold = timer.C
+		}
+
+		select {
+		case <-hold:
+		case <-a.stopch:
+		case <-done:
+			return
+		}
+
+		atomic.StoreInt32(&held, 1)
+		r.Body.Close()
+	}()
+
+	var (
+		st   = StreamStats{FirstByte: firstByte}
+		sse  = isEventStream(r.Header.Get("Content-Type"))
+		br   = bufio.NewReader(r.Body)
+		last time.Time
+		data bool // whether the current Server-Sent Event has fields
+	)
+
+	event := func() {
+		now := time.Now()
+		if st.Events++; st.Events == 1 {
+			st.FirstEvent = now.Sub(start)
+		} else if gap := now.Sub(last); len(st.Gaps) < maxStreamGaps {
+			st.Gaps = append(st.Gaps, gap)
+		} else if i := rand.Int63n(int64(st.Events - 1)); i < maxStreamGaps {
+			st.Gaps[i] = gap // Reservoir sampling of all the gaps.
+		}
+		last = now
+	}
+
+	defer func() { res.Stream = &st }()
+
+	for {
+		line, err := br.ReadBytes('\n')
+
+		if n := int64(len(res.Body)); a.maxBody < 0 || n+int64(len(line)) <= a.maxBody {
+			res.Body = append(res.Body, line...)
+		} else if n < a.maxBody {
+			res.Body = append(res.Body, line[:a.maxBody-n]...)
+		}
+
+		// Events end with complete lines, or the body in other streams.
+		if text := bytes.TrimRight(line, "\r\n"); sse {
+			if len(text) == 0 && data && err == nil {
+				event()
+				data = false
+			} else if len(text) > 0 && text[0] != ':' { // Not a comment
+				data = true
+			}
+		} else if len(text) > 0 && (err == nil || err == io.EOF) {
+			event()
+		}
+
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			if atomic.LoadInt32(&held) == 1 {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// isEventStream returns true if the given content type is that of
+// Server-Sent Events.
+func isEventStream(contentType string) bool {
+	typ, _, err := mime.ParseMediaType(contentType)
+	return err == nil && typ == "text/event-stream"
+}
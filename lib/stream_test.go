@@ -0,0 +1,189 @@
+package vegeta
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// streamServer returns a server which streams the given events of the given
+// content type, flushing each one after the given delay, and then holds its
+// responses open if hold is true.
+func streamServer(contentType string, events []string, delay time.Duration, hold bool) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		for _, e := range events {
+			time.Sleep(delay)
+			fmt.Fprint(w, e)
+			w.(http.Flusher).Flush()
+		}
+
+		if hold {
+			<-r.Context().Done()
+		}
+	}))
+}
+
+func TestStream(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		name        string
+		contentType string
+		events      []string
+		want        uint64
+	}{
+		{
+			name:        "sse",
+			contentType: "text/event-stream; charset=utf-8",
+			events: []string{
+				": comment\n\n",
+				"event: tick\ndata: 1\n\n",
+				"data: 2\r\n\r\n",
+				"data: {\ndata: 3}\n\n",
+				"data: unterminated",
+			},
+			want: 3,
+		},
+		{
+			name:        "ndjson",
+			contentType: "application/x-ndjson",
+			events:      []string{`{"n":1}` + "\n", "\n", `{"n":2}` + "\n", `{"n":3}`},
+			want:        3,
+		},
+	} {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			server := streamServer(tc.contentType, tc.events, 20*time.Millisecond, false)
+			defer server.Close()
+
+			tgt := Target{Method: "GET", URL: server.URL}
+			res := NewAttacker(Stream(true)).hit(NewStaticTargeter(tgt).NewTargeter(), "")
+
+			if res.Error != "" || res.Code != 200 {
+				t.Fatalf("got code %d and error %q", res.Code, res.Error)
+			} else if got, want := string(res.Body), strings.Join(tc.events, ""); got != want {
+				t.Errorf("got body %q, want %q", got, want)
+			} else if res.BytesIn != uint64(len(res.Body)) {
+				t.Errorf("got %d bytes in, want %d", res.BytesIn, len(res.Body))
+			}
+
+			st := res.Stream
+			if st == nil {
+				t.Fatal("got no stream stats")
+			} else if st.Events != tc.want || len(st.Gaps) != int(tc.want)-1 {
+				t.Fatalf("got %d events and %d gaps, want %d events", st.Events, len(st.Gaps), tc.want)
+			} else if st.FirstByte <= 0 || st.FirstEvent < st.FirstByte || st.FirstEvent > res.Latency {
+				t.Errorf("got first byte %s and first event %s with latency %s", st.FirstByte, st.FirstEvent, res.Latency)
+			}
+
+			for i, gap := range st.Gaps {
+				if gap < 10*time.Millisecond {
+					t.Errorf("gap %d: got %s, want about 20ms", i, gap)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamGapsSampled(t *testing.T) {
+	t.Parallel()
+
+	events := make([]string, 3*maxStreamGaps)
+	for i := range events {
+		events[i] = "{}\n"
+	}
+
+	server := streamServer("application/x-ndjson", events, 0, false)
+	defer server.Close()
+
+	tgt := Target{Method: "GET", URL: server.URL}
+	res := NewAttacker(Stream(true)).hit(NewStaticTargeter(tgt).NewTargeter(), "")
+
+	if st := res.Stream; st == nil || st.Events != uint64(len(events)) {
+		t.Fatalf("got stream stats %+v, want %d events", st, len(events))
+	} else if len(st.Gaps) != maxStreamGaps {
+		t.Errorf("got %d gaps, want them sampled down to %d", len(st.Gaps), maxStreamGaps)
+	}
+}
+
+func TestStreamHold(t *testing.T) {
+	t.Parallel()
+
+	server := streamServer("text/event-stream", []string{"data: 1\n\n", "data: 2\n\n"}, 0, true)
+	defer server.Close()
+
+	tgt := Target{Method: "GET", URL: server.URL}
+	atk := NewAttacker(Stream(true), StreamHold(200*time.Millisecond), Timeout(50*time.Millisecond), MaxBody(12))
+
+	res := atk.hit(NewStaticTargeter(tgt).NewTargeter(), "")
+	if res.Error != "" {
+		t.Fatalf("got error %q, want the stream to be held past the timeout", res.Error)
+	} else if res.Latency < 200*time.Millisecond {
+		t.Errorf("got latency %s, want the stream held for 200ms", res.Latency)
+	} else if res.Stream == nil || res.Stream.Events != 2 {
+		t.Errorf("got stream stats %+v, want two events", res.Stream)
+	} else if got, want := string(res.Body), "data: 1\n\ndat"; got != want {
+		t.Errorf("got body %q, want %q", got, want)
+	}
+
+	// The timeout still applies to the response headers.
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+
+	tgt.URL = slow.URL
+	res = atk.hit(NewStaticTargeter(tgt).NewTargeter(), "")
+	if want := "stream: response headers timed out after 50ms"; res.Error != want {
+		t.Errorf("got error %q, want %q", res.Error, want)
+	}
+}
+
+func TestStreamStop(t *testing.T) {
+	t.Parallel()
+
+	server := streamServer("application/x-ndjson", []string{"{}\n"}, 0, true)
+	defer server.Close()
+
+	tgt := Target{Method: "GET", URL: server.URL}
+	atk := NewAttacker(Stream(true))
+	time.AfterFunc(100*time.Millisecond, atk.Stop)
+
+	res := atk.hit(NewStaticTargeter(tgt).NewTargeter(), "")
+	if res.Error != "" || res.Stream == nil || res.Stream.Events != 1 {
+		t.Errorf("got error %q and stream stats %+v, want a stopped stream", res.Error, res.Stream)
+	}
+}
+
+func TestWithHeaderTimeout(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest("GET", "http://goku", nil)
+	client, req, timedOut, stop := withHeaderTimeout(&http.Client{Timeout: time.Hour}, req)
+	if client.Timeout != 0 {
+		t.Errorf("got client timeout %s, want none", client.Timeout)
+	} else if timedOut() {
+		t.Error("got timed out headers, want none")
+	}
+
+	// The context of the request is released once its response is read,
+	// even though the timeout didn't fire.
+	stop()
+	select {
+	case <-req.Context().Done():
+	case <-time.After(time.Second):
+		t.Error("got a request context which outlived its response")
+	}
+}
@@ -7,12 +7,16 @@ import (
 	"fmt"
 	"io"
 	"io/ioutil"
+	"math/rand"
 	"net/http"
 	"net/url"
 	"regexp"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	jlexer "github.com/mailru/easyjson/jlexer"
 	jwriter "github.com/mailru/easyjson/jwriter"
@@ -26,6 +30,26 @@ type Target struct {
 	URL    string      `json:"url"`
 	Body   []byte      `json:"body,omitempty"`
 	Header http.Header `json:"header,omitempty"`
+	// Weight is the relative frequency with which the Target is picked
+	// by a weighted Targeter. Targets without a weight default to 1, and
+	// decoded ones must have positive weights.
+	Weight float64 `json:"weight,omitempty"`
+	// Timeout overrides the request timeout of the Attacker, in nanoseconds.
+	Timeout time.Duration `json:"timeout,omitempty"`
+	// Redirects overrides the maximum number of redirects the Attacker
+	// follows. NoFollow doesn't follow redirects but marks them successful.
+	Redirects *int `json:"redirects,omitempty"`
+	// Expect is the list of status codes of successful responses, which
+	// otherwise are 2xx and 3xx ones.
+	Expect []uint16 `json:"expect,omitempty"`
+	// Tag names the Target in the Results of its hits.
+	Tag string `json:"tag,omitempty"`
+	// Assert holds assertion expressions that responses must meet in
+	// addition to the Attacker's ones. See ParseAssertion.
+	Assert []string `json:"assert,omitempty"`
+	// Messages holds the text messages sent in order over the connections
+	// of WebSocket Targets, which have ws or wss URLs.
+	Messages []string `json:"messages,omitempty"`
 }
 
 // Request creates an *http.Request out of Target and returns it along with an
@@ -45,6 +69,22 @@ func (t *Target) Request() (*http.Request, error) {
 	return req, nil
 }
 
+// Success returns true if a response with the given status code is
+// successful according to the Target's expected status codes.
+func (t *Target) Success(code uint16) bool {
+	if len(t.Expect) == 0 {
+		return code >= 200 && code < 400
+	}
+
+	for _, c := range t.Expect {
+		if c == code {
+			return true
+		}
+	}
+
+	return false
+}
+
 // Equal returns true if the target is equal to the other given target.
 func (t *Target) Equal(other *Target) bool {
 	switch {
@@ -55,13 +95,39 @@ func (t *Target) Equal(other *Target) bool {
 	default:
 		equal := t.Method == other.Method &&
 			t.URL == other.URL &&
+			t.Weight == other.Weight &&
+			t.Timeout == other.Timeout &&
+			t.Tag == other.Tag &&
+			(t.Redirects == nil) == (other.Redirects == nil) &&
+			(t.Redirects == nil || *t.Redirects == *other.Redirects) &&
 			bytes.Equal(t.Body, other.Body) &&
+			len(t.Expect) == len(other.Expect) &&
+			len(t.Assert) == len(other.Assert) &&
+			len(t.Messages) == len(other.Messages) &&
 			len(t.Header) == len(other.Header)
 
 		if !equal {
 			return false
 		}
 
+		for i := range t.Expect {
+			if t.Expect[i] != other.Expect[i] {
+				return false
+			}
+		}
+
+		for i := range t.Assert {
+			if t.Assert[i] != other.Assert[i] {
+				return false
+			}
+		}
+
+		for i := range t.Messages {
+			if t.Messages[i] != other.Messages[i] {
+				return false
+			}
+		}
+
 		for k := range t.Header {
 			left, right := t.Header[k], other.Header[k]
 			if len(left) != len(right) {
@@ -91,7 +157,7 @@ var (
 	ErrNoURL = errors.New("target: required url is missing")
 	// TargetFormats contains the canonical list of the valid target
 	// format identifiers.
-	TargetFormats = []string{HTTPTargetFormat, JSONTargetFormat}
+	TargetFormats = []string{HTTPTargetFormat, JSONTargetFormat, ScenarioTargetFormat, HARTargetFormat, CurlTargetFormat, AccessLogTargetFormat}
 )
 
 const (
@@ -99,15 +165,34 @@ const (
 	HTTPTargetFormat = "http"
 	// JSONTargetFormat is the human readable identifier for the JSON target format.
 	JSONTargetFormat = "json"
+	// ScenarioTargetFormat is the human readable identifier for the Scenario target format.
+	ScenarioTargetFormat = "scenario"
+	// HARTargetFormat is the human readable identifier for the HAR target format.
+	HARTargetFormat = "har"
+	// CurlTargetFormat is the human readable identifier for the curl target format.
+	CurlTargetFormat = "curl"
+	// AccessLogTargetFormat is the human readable identifier for the access log target format.
+	AccessLogTargetFormat = "log"
 )
 
 // A Targeter decodes a Target or returns an error in case of failure.
 // Implementations must be safe for concurrent use.
+//
+// Every Target returned by Next is followed by exactly one call to Result
+// with the response body, status code and transport error of its hit,
+// unless the Targeter implements FeedbackTargeter.
 type Targeter interface {
 	Next(*Target) error
 	Result([]byte, uint16, error)
 }
 
+// A FeedbackTargeter is a Targeter that gets the full Feedback of the hit of
+// every Target it returns, in place of a call to Result.
+type FeedbackTargeter interface {
+	Targeter
+	Feedback(*Feedback)
+}
+
 // TargeterProvider instantiates new targeters
 type TargeterProvider interface {
 	NewTargeter() Targeter
@@ -160,8 +245,21 @@ func (d *jsonTargeter) Next(tgt *Target) (err error) {
 		return ErrNoURL
 	}
 
+	for _, expr := range t.Assert {
+		if _, err = ParseAssertion(expr); err != nil {
+			return err
+		}
+	}
+
 	tgt.Method = t.Method
 	tgt.URL = t.URL
+	tgt.Weight = t.Weight
+	tgt.Timeout = t.Timeout
+	tgt.Redirects = t.Redirects
+	tgt.Expect = t.Expect
+	tgt.Tag = t.Tag
+	tgt.Assert = t.Assert
+	tgt.Messages = t.Messages
 	if tgt.Body = d.body; len(t.Body) > 0 {
 		tgt.Body = t.Body
 	}
@@ -185,10 +283,12 @@ func (d *jsonTargeter) Next(tgt *Target) (err error) {
 // given io.Reader on every invocation. Each target is one JSON object in its own line.
 //
 // The method and url fields are required. If present, the body field must be base64 encoded.
+// The timeout field is in nanoseconds.
 // The generated [JSON Schema](lib/target.schema.json) defines the format in detail.
 //
 //    {"method":"POST", "url":"https://goku/1", "header":{"Content-Type":["text/plain"], "body": "Rk9P"}
-//    {"method":"GET",  "url":"https://goku/2"}
+//    {"method":"GET",  "url":"https://goku/2", "timeout":5000000000, "expect":[200,404], "tag":"goku"}
+//    {"method":"GET",  "url":"wss://goku/chat", "messages":["hi", "bye"]}
 //
 // body will be set as the Target's body if no body is provided in each target definiton.
 // hdr will be merged with the each Target's headers.
@@ -249,6 +349,90 @@ func NewStaticTargeter(tgts ...Target) TargeterProvider {
 	return &staticTargeter{tgts: tgts, i: -1}
 }
 
+type weightedTargeter struct {
+	tgts []Target
+	cum  []float64 // cumulative weights
+	mu   sync.Mutex
+	rng  *rand.Rand
+}
+
+func (w *weightedTargeter) Next(tgt *Target) error {
+	if tgt == nil {
+		return ErrNilTarget
+	}
+
+	w.mu.Lock()
+	x := w.rng.Float64() * w.cum[len(w.cum)-1]
+	w.mu.Unlock()
+
+	*tgt = w.tgts[sort.Search(len(w.cum), func(i int) bool { return w.cum[i] > x })]
+	return nil
+}
+
+func (w *weightedTargeter) Result(body []byte, code uint16, err error) {
+	// noop
+}
+
+// NewTargeter returns same weightedTargeter, because it's shared by all workers
+// so that the random sequence of picked Targets is deterministic.
+func (w *weightedTargeter) NewTargeter() Targeter { return w }
+
+// NewWeightedTargeter returns a Targeter which randomly picks one of the passed
+// Targets with a probability proportional to its Weight. Targets without
+// a Weight default to 1. The sequence of picked Targets is fully determined
+// by the given seed.
+func NewWeightedTargeter(seed int64, tgts ...Target) TargeterProvider {
+	w := &weightedTargeter{
+		tgts: tgts,
+		cum:  make([]float64, len(tgts)),
+		rng:  rand.New(rand.NewSource(seed)),
+	}
+
+	var sum float64
+	for i := range tgts {
+		if tgts[i].Weight > 0 {
+			sum += tgts[i].Weight
+		} else {
+			sum++
+		}
+		w.cum[i] = sum
+	}
+
+	return w
+}
+
+type sequenceTargeter struct {
+	tgts []Target
+	i    int64
+}
+
+func (s *sequenceTargeter) Next(tgt *Target) error {
+	if tgt == nil {
+		return ErrNilTarget
+	}
+
+	i := atomic.AddInt64(&s.i, 1)
+	if i >= int64(len(s.tgts)) {
+		return ErrNoTargets
+	}
+
+	*tgt = s.tgts[i]
+	return nil
+}
+
+func (s *sequenceTargeter) Result(body []byte, code uint16, err error) {
+	// noop
+}
+
+// NewTargeter returns same sequenceTargeter, because it's shared by all workers.
+func (s *sequenceTargeter) NewTargeter() Targeter { return s }
+
+// NewSequenceTargeter returns a Targeter which returns each of the passed
+// Targets exactly once, in order, and ErrNoTargets afterwards.
+func NewSequenceTargeter(tgts ...Target) TargeterProvider {
+	return &sequenceTargeter{tgts: tgts, i: -1}
+}
+
 // ReadAllTargets eagerly reads all Targets out of the provided Targeter.
 func ReadAllTargets(t TargeterProvider) (tgts []Target, err error) {
 	tr := t.NewTargeter()
@@ -310,6 +494,9 @@ func (h *httpTargeter) Next(tgt *Target) (err error) {
 		return fmt.Errorf("bad method: %s", tokens[0])
 	}
 	tgt.Method = tokens[0]
+	if tokens[1], err = parseAnnotations(tgt, tokens[1]); err != nil {
+		return err
+	}
 	if _, err = url.ParseRequestURI(tokens[1]); err != nil {
 		return fmt.Errorf("bad URL: %s", tokens[1])
 	}
@@ -318,14 +505,26 @@ func (h *httpTargeter) Next(tgt *Target) (err error) {
 	if line == "" || startsWithHTTPMethod(line) {
 		return nil
 	}
+	var fields []formField
 	for h.sc.Scan() {
 		if line = strings.TrimSpace(h.sc.Text()); line == "" {
 			break
 		} else if strings.HasPrefix(line, "@") {
+			if len(fields) > 0 {
+				return fmt.Errorf("bad body: form fields can't be followed by a body file")
+			}
 			if tgt.Body, err = ioutil.ReadFile(line[1:]); err != nil {
 				return fmt.Errorf("bad body: %s", err)
 			}
 			break
+		} else if i := strings.IndexAny(line, ":="); i != -1 && line[i] == '=' {
+			// Header names can't have an =, so this is a form field.
+			f, err := parseFormField(line)
+			if err != nil {
+				return err
+			}
+			fields = append(fields, f)
+			continue
 		}
 		tokens = strings.SplitN(line, ":", 2)
 		if len(tokens) < 2 {
@@ -344,6 +543,9 @@ func (h *httpTargeter) Next(tgt *Target) (err error) {
 	if err = h.sc.Err(); err != nil {
 		return ErrNoTargets
 	}
+	if len(fields) > 0 {
+		return setFormBody(tgt, fields)
+	}
 	return nil
 }
 
@@ -360,8 +562,23 @@ func (h *httpTargeter) NewTargeter() Targeter { return h }
 //    Header-Y: 321
 //    @/path/to/body/file
 //
-//    POST https://foo.bar/b/c/a
+//    POST https://foo.bar/b/c/a weight=5
+//    Header-X: 123
+//
+// Request lines may end with space separated key=value annotations which
+// set the Target fields of the same name: weight, timeout (e.g. 5s),
+// redirects, expect (e.g. 200,404) and tag. The assert annotation, which
+// can be repeated, adds an assertion expression without spaces.
+//
+// Instead of a body file, headers may be followed by form fields, which are
+// encoded as the Target's body according to its Content-Type header. File
+// fields are encoded as multipart/form-data, and so are all fields when the
+// Content-Type is multipart/form-data. Boundaries are generated when missing.
+//
+//    POST https://foo.bar/upload
 //    Header-X: 123
+//    name=vegeta
+//    avatar=@/path/to/avatar.png;type=image/png;filename=goku.png
 //
 // body will be set as the Target's body if no body is provided.
 // hdr will be merged with the each Target's headers.
@@ -369,6 +586,65 @@ func NewHTTPTargeter(src io.Reader, body []byte, hdr http.Header) TargeterProvid
 	return &httpTargeter{body: body, hdr: hdr, sc: peekingScanner{src: bufio.NewScanner(src)}}
 }
 
+// parseAnnotations parses the trailing key=value annotations of the given
+// request line remainder into tgt and returns what's left of it.
+func parseAnnotations(tgt *Target, line string) (string, error) {
+	tgt.Weight, tgt.Timeout, tgt.Redirects, tgt.Expect, tgt.Tag = 0, 0, nil, nil, ""
+	tgt.Assert = nil
+	for {
+		i := strings.LastIndexByte(line, ' ')
+		if i == -1 {
+			return line, nil
+		}
+
+		kv := strings.SplitN(line[i+1:], "=", 2)
+		if len(kv) != 2 {
+			return line, nil
+		}
+
+		switch kv[0] {
+		case "weight":
+			w, err := strconv.ParseFloat(kv[1], 64)
+			if err != nil || w <= 0 {
+				return "", fmt.Errorf("bad weight: %s", kv[1])
+			}
+			tgt.Weight = w
+		case "timeout":
+			d, err := time.ParseDuration(kv[1])
+			if err != nil || d < 0 {
+				return "", fmt.Errorf("bad timeout: %s", kv[1])
+			}
+			tgt.Timeout = d
+		case "redirects":
+			n, err := strconv.Atoi(kv[1])
+			if err != nil || n < NoFollow {
+				return "", fmt.Errorf("bad redirects: %s", kv[1])
+			}
+			tgt.Redirects = &n
+		case "expect":
+			for _, c := range strings.Split(kv[1], ",") {
+				code, err := strconv.ParseUint(c, 10, 16)
+				if err != nil {
+					return "", fmt.Errorf("bad expect: %s", kv[1])
+				}
+				tgt.Expect = append(tgt.Expect, uint16(code))
+			}
+		case "tag":
+			tgt.Tag = kv[1]
+		case "assert":
+			if _, err := ParseAssertion(kv[1]); err != nil {
+				return "", err
+			}
+			// Annotations are parsed from last to first.
+			tgt.Assert = append([]string{kv[1]}, tgt.Assert...)
+		default:
+			return line, nil
+		}
+
+		line = strings.TrimSpace(line[:i])
+	}
+}
+
 var httpMethodChecker = regexp.MustCompile("^[A-Z]+\\s")
 
 // A line starts with an http method when the first word is uppercase ascii
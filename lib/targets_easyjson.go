@@ -4,7 +4,9 @@
 package vegeta
 
 import (
+	fmt "fmt"
 	http "net/http"
+	time "time"
 
 	jlexer "github.com/mailru/easyjson/jlexer"
 	jwriter "github.com/mailru/easyjson/jwriter"
@@ -42,6 +44,66 @@ func (t *jsonTarget) decode(in *jlexer.Lexer) {
 			} else {
 				t.Body = in.Bytes()
 			}
+		case "weight":
+			// Unlike missing weights, which default to 1, explicit ones
+			// must be positive.
+			if t.Weight = float64(in.Float64()); t.Weight <= 0 && in.Ok() {
+				in.AddError(fmt.Errorf("bad weight: %v", t.Weight))
+			}
+		case "timeout":
+			t.Timeout = time.Duration(in.Int64())
+		case "redirects":
+			if in.IsNull() {
+				in.Skip()
+				t.Redirects = nil
+			} else {
+				n := int(in.Int())
+				if n < NoFollow && in.Ok() {
+					in.AddError(fmt.Errorf("bad redirects: %d", n))
+				}
+				t.Redirects = &n
+			}
+		case "expect":
+			if in.IsNull() {
+				in.Skip()
+				t.Expect = nil
+			} else {
+				in.Delim('[')
+				t.Expect = make([]uint16, 0, 4)
+				for !in.IsDelim(']') {
+					t.Expect = append(t.Expect, uint16(in.Uint16()))
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "tag":
+			t.Tag = string(in.String())
+		case "assert":
+			if in.IsNull() {
+				in.Skip()
+				t.Assert = nil
+			} else {
+				in.Delim('[')
+				t.Assert = make([]string, 0, 4)
+				for !in.IsDelim(']') {
+					t.Assert = append(t.Assert, string(in.String()))
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "messages":
+			if in.IsNull() {
+				in.Skip()
+				t.Messages = nil
+			} else {
+				in.Delim('[')
+				t.Messages = make([]string, 0, 4)
+				for !in.IsDelim(']') {
+					t.Messages = append(t.Messages, string(in.String()))
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
 		case "header":
 			if in.IsNull() {
 				in.Skip()
@@ -163,5 +225,96 @@ func (t jsonTarget) encode(out *jwriter.Writer) {
 			out.RawByte('}')
 		}
 	}
+	if t.Weight != 0 {
+		const prefix string = ",\"weight\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Float64(float64(t.Weight))
+	}
+	if t.Timeout != 0 {
+		const prefix string = ",\"timeout\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int64(int64(t.Timeout))
+	}
+	if t.Redirects != nil {
+		const prefix string = ",\"redirects\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(int(*t.Redirects))
+	}
+	if len(t.Expect) != 0 {
+		const prefix string = ",\"expect\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.RawByte('[')
+		for i, v := range t.Expect {
+			if i > 0 {
+				out.RawByte(',')
+			}
+			out.Uint16(uint16(v))
+		}
+		out.RawByte(']')
+	}
+	if t.Tag != "" {
+		const prefix string = ",\"tag\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(t.Tag))
+	}
+	if len(t.Assert) != 0 {
+		const prefix string = ",\"assert\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.RawByte('[')
+		for i, v := range t.Assert {
+			if i > 0 {
+				out.RawByte(',')
+			}
+			out.String(string(v))
+		}
+		out.RawByte(']')
+	}
+	if len(t.Messages) != 0 {
+		const prefix string = ",\"messages\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.RawByte('[')
+		for i, v := range t.Messages {
+			if i > 0 {
+				out.RawByte(',')
+			}
+			out.String(string(v))
+		}
+		out.RawByte(']')
+	}
 	out.RawByte('}')
 }
@@ -12,6 +12,7 @@ import (
 	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestTargetRequest(t *testing.T) {
@@ -121,6 +122,13 @@ func TestJSONTargeter(t *testing.T) {
 			out:  &Target{},
 			err:  errors.New("parse error: illegal base64 data at input byte 3 near offset 0 of ''"),
 		},
+		{
+			name: "bad redirects",
+			src:  target(`{"method": "GET", "url": "http://goku", "redirects": -2}`),
+			in:   &Target{},
+			out:  &Target{},
+			err:  errors.New("bad redirects: -2"),
+		},
 		{
 			name: "default body",
 			src:  target(`{"method": "GET", "url": "http://goku"}`),
@@ -141,6 +149,12 @@ func TestJSONTargeter(t *testing.T) {
 			in:   &Target{},
 			out:  &Target{Method: "GET", URL: "http://goku", Header: http.Header{"x": []string{"foo"}}, Body: []byte("ATTACK!")},
 		},
+		{
+			name: "websocket messages",
+			src:  target(`{"method": "GET", "url": "ws://goku", "messages": ["hi", "bye"]}`),
+			in:   &Target{},
+			out:  &Target{Method: "GET", URL: "ws://goku", Messages: []string{"hi", "bye"}},
+		},
 		{
 			name: "skips empty lines and surrounding whitespace",
 			src: strings.NewReader(`
@@ -156,7 +170,7 @@ func TestJSONTargeter(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			t.Parallel()
 
-			err := NewJSONTargeter(tc.src, tc.body, tc.hdr)(tc.in)
+			err := NewJSONTargeter(tc.src, tc.body, tc.hdr).NewTargeter().Next(tc.in)
 			if got, want := tc.in, tc.out; !got.Equal(want) {
 				t.Errorf("got Target %#v, want %#v", got, want)
 			}
@@ -191,7 +205,7 @@ func TestReadAllTargets(t *testing.T) {
 
 	for _, tc := range []struct {
 		name string
-		in   Targeter
+		in   TargeterProvider
 		out  []Target
 		err  error
 	}{
@@ -275,8 +289,8 @@ func TestNewHTTPTargeter(t *testing.T) {
 			: 1234`,
 	} {
 		src := bytes.NewBufferString(strings.TrimSpace(def))
-		read := NewHTTPTargeter(src, []byte{}, http.Header{})
-		if got := read(&Target{}); got == nil || !strings.HasPrefix(got.Error(), want.Error()) {
+		read := NewHTTPTargeter(src, []byte{}, http.Header{}).NewTargeter()
+		if got := read.Next(&Target{}); got == nil || !strings.HasPrefix(got.Error(), want.Error()) {
 			t.Errorf("got: %s, want: %s\n%s", got, want, def)
 		}
 	}
@@ -318,7 +332,7 @@ func TestNewHTTPTargeter(t *testing.T) {
 	)
 
 	src := bytes.NewBufferString(strings.TrimSpace(targets))
-	read := NewHTTPTargeter(src, []byte{}, http.Header{"Content-Type": []string{"text/plain"}})
+	read := NewHTTPTargeter(src, []byte{}, http.Header{"Content-Type": []string{"text/plain"}}).NewTargeter()
 	for _, want := range []Target{
 		{
 			Method: "GET",
@@ -376,14 +390,14 @@ func TestNewHTTPTargeter(t *testing.T) {
 		},
 	} {
 		var got Target
-		if err := read(&got); err != nil {
+		if err := read.Next(&got); err != nil {
 			t.Fatal(err)
 		} else if !reflect.DeepEqual(want, got) {
 			t.Fatalf("want: %#v, got: %#v", want, got)
 		}
 	}
 	var got Target
-	if err := read(&got); err != ErrNoTargets {
+	if err := read.Next(&got); err != ErrNoTargets {
 		t.Fatalf("got: %v, want: %v", err, ErrNoTargets)
 	} else if !reflect.DeepEqual(got, Target{}) {
 		t.Fatalf("got: %v, want: %v", got, nil)
@@ -393,12 +407,12 @@ func TestNewHTTPTargeter(t *testing.T) {
 func TestErrNilTarget(t *testing.T) {
 	t.Parallel()
 
-	for i, tr := range []Targeter{
+	for i, tr := range []TargeterProvider{
 		NewStaticTargeter(Target{Method: "GET", URL: "http://foo.bar"}),
 		NewJSONTargeter(strings.NewReader(""), nil, nil),
 		NewHTTPTargeter(strings.NewReader("GET http://foo.bar"), nil, nil),
 	} {
-		if got, want := tr(nil), ErrNilTarget; got != want {
+		if got, want := tr.NewTargeter().Next(nil), ErrNilTarget; got != want {
 			t.Errorf("test #%d: got: %v, want: %v", i, got, want)
 		}
 	}
@@ -427,10 +441,270 @@ func BenchmarkJSONTargetEncoding(b *testing.B) {
 		}
 	})
 
-	dec := NewJSONTargeter(&buf, nil, nil)
+	dec := NewJSONTargeter(&buf, nil, nil).NewTargeter()
 	b.Run("decode", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			dec.Decode(&targets[i%len(targets)])
+			dec.Next(&targets[i%len(targets)])
 		}
 	})
 }
+
+func TestWeightedTargeter(t *testing.T) {
+	t.Parallel()
+
+	tgts := []Target{
+		{Method: "GET", URL: "http://goku/search", Weight: 70},
+		{Method: "GET", URL: "http://goku/item", Weight: 25},
+		{Method: "POST", URL: "http://goku/cart", Weight: 4},
+		{Method: "GET", URL: "http://goku/"},
+	}
+
+	pick := func(seed int64, n int) []string {
+		tr := NewWeightedTargeter(seed, tgts...).NewTargeter()
+		urls := make([]string, n)
+		for i := range urls {
+			var tgt Target
+			if err := tr.Next(&tgt); err != nil {
+				t.Fatal(err)
+			}
+			urls[i] = tgt.URL
+		}
+		return urls
+	}
+
+	const n = 100000
+	counts := map[string]int{}
+	for _, u := range pick(42, n) {
+		counts[u]++
+	}
+
+	for _, tgt := range tgts {
+		w := tgt.Weight
+		if w == 0 {
+			w = 1
+		}
+		if got, want := float64(counts[tgt.URL])/n, w/100; got < want*0.9 || got > want*1.1 {
+			t.Errorf("%s: got ratio %.4f, want %.4f", tgt.URL, got, want)
+		}
+	}
+
+	if a, b := pick(7, 100), pick(7, 100); !reflect.DeepEqual(a, b) {
+		t.Errorf("same seed yielded different sequences")
+	}
+}
+
+func TestSequenceTargeter(t *testing.T) {
+	t.Parallel()
+
+	tgts := []Target{
+		{Method: "GET", URL: "http://goku/1"},
+		{Method: "GET", URL: "http://goku/2"},
+	}
+
+	tr := NewSequenceTargeter(tgts...).NewTargeter()
+	for i := range tgts {
+		var got Target
+		if err := tr.Next(&got); err != nil {
+			t.Fatal(err)
+		} else if !got.Equal(&tgts[i]) {
+			t.Fatalf("got %v, want %v", got, tgts[i])
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := tr.Next(&Target{}); err != ErrNoTargets {
+			t.Fatalf("got error %v, want %v", err, ErrNoTargets)
+		}
+	}
+}
+
+func TestTargetWeightAnnotations(t *testing.T) {
+	t.Parallel()
+
+	tr := NewHTTPTargeter(strings.NewReader(strings.Join([]string{
+		"GET http://goku/search weight=70",
+		"GET http://goku/item  weight=2.5",
+		"GET http://goku/a=b",
+		"GET http://goku/bad weight=0",
+	}, "\n")), nil, nil).NewTargeter()
+
+	for _, want := range []Target{
+		{Method: "GET", URL: "http://goku/search", Weight: 70, Header: http.Header{}},
+		{Method: "GET", URL: "http://goku/item", Weight: 2.5, Header: http.Header{}},
+		{Method: "GET", URL: "http://goku/a=b", Header: http.Header{}},
+	} {
+		var got Target
+		if err := tr.Next(&got); err != nil {
+			t.Fatal(err)
+		} else if !reflect.DeepEqual(got, want) {
+			t.Fatalf("got %#v, want %#v", got, want)
+		}
+	}
+
+	if err := tr.Next(&Target{}); err == nil || err.Error() != "bad weight: 0" {
+		t.Fatalf("got error %v, want bad weight", err)
+	}
+
+	var buf bytes.Buffer
+	want := Target{Method: "GET", URL: "http://goku/search", Weight: 70}
+	if err := NewJSONTargetEncoder(&buf).Encode(&want); err != nil {
+		t.Fatal(err)
+	}
+
+	var got Target
+	if err := NewJSONTargeter(&buf, nil, nil).NewTargeter().Next(&got); err != nil {
+		t.Fatal(err)
+	} else if !got.Equal(&want) {
+		t.Fatalf("got %#v, want %#v", got, want)
+	}
+
+	for _, w := range []string{"0", "-1"} {
+		src := strings.NewReader(`{"method": "GET", "url": "http://goku", "weight": ` + w + "}\n")
+		if err := NewJSONTargeter(src, nil, nil).NewTargeter().Next(&got); err == nil || err.Error() != "bad weight: "+w {
+			t.Errorf("got error %v, want bad weight for weight %s", err, w)
+		}
+	}
+}
+
+func TestTargetOverrideAnnotations(t *testing.T) {
+	t.Parallel()
+
+	tr := NewHTTPTargeter(strings.NewReader(strings.Join([]string{
+		"GET http://goku/a timeout=5s redirects=-1 expect=200,404 tag=a",
+		"GET http://goku/b tag=b assert=status:200 assert=header:X-Id",
+		"GET http://goku/c expect=2xx",
+	}, "\n")), nil, nil).NewTargeter()
+
+	noFollow := NoFollow
+	for _, want := range []Target{
+		{
+			Method:    "GET",
+			URL:       "http://goku/a",
+			Timeout:   5 * time.Second,
+			Redirects: &noFollow,
+			Expect:    []uint16{200, 404},
+			Tag:       "a",
+			Header:    http.Header{},
+		},
+		{
+			Method: "GET",
+			URL:    "http://goku/b",
+			Tag:    "b",
+			Assert: []string{"status:200", "header:X-Id"},
+			Header: http.Header{},
+		},
+	} {
+		var got Target
+		if err := tr.Next(&got); err != nil {
+			t.Fatal(err)
+		} else if !got.Equal(&want) {
+			t.Fatalf("got %#v, want %#v", got, want)
+		}
+
+		var buf bytes.Buffer
+		if err := NewJSONTargetEncoder(&buf).Encode(&want); err != nil {
+			t.Fatal(err)
+		}
+
+		var decoded Target
+		if err := NewJSONTargeter(&buf, nil, nil).NewTargeter().Next(&decoded); err != nil {
+			t.Fatal(err)
+		} else if !decoded.Equal(&want) {
+			t.Fatalf("got %#v, want %#v", decoded, want)
+		}
+	}
+
+	if err := tr.Next(&Target{}); err == nil || err.Error() != "bad expect: 2xx" {
+		t.Fatalf("got error %v, want bad expect", err)
+	}
+}
+
+func TestHTTPTargeterForms(t *testing.T) {
+	t.Parallel()
+
+	f, err := ioutil.TempFile("", "vegeta-")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+
+	if _, err = f.WriteString("\x89PNG"); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	tr := NewHTTPTargeter(strings.NewReader(strings.Join([]string{
+		"POST http://goku/login",
+		"user=goku",
+		"password=kaka rot&",
+		"",
+		"POST http://goku/upload",
+		"X-Power: 9000",
+		"name=goku",
+		"avatar=@" + f.Name() + ";type=image/png;filename=goku.png",
+		"",
+		"POST http://goku/profile",
+		"content-type: multipart/form-data; boundary=vegeta",
+		"name=goku",
+		"",
+		"POST http://goku/bad",
+		"Content-Type: application/json",
+		"name=goku",
+		"",
+		"POST http://goku/bad",
+		"avatar=@" + f.Name(),
+		"@" + f.Name(),
+	}, "\n")), nil, nil).NewTargeter()
+
+	var tgt Target
+	if err = tr.Next(&tgt); err != nil {
+		t.Fatal(err)
+	} else if got, want := string(tgt.Body), "password=kaka+rot%26&user=goku"; got != want {
+		t.Errorf("got body %q, want %q", got, want)
+	} else if got, want := tgt.Header.Get("Content-Type"), "application/x-www-form-urlencoded"; got != want {
+		t.Errorf("got Content-Type %q, want %q", got, want)
+	}
+
+	if err = tr.Next(&tgt); err != nil {
+		t.Fatal(err)
+	}
+
+	req, err := tgt.Request()
+	if err != nil {
+		t.Fatal(err)
+	} else if err = req.ParseMultipartForm(1 << 20); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := req.FormValue("name"), "goku"; got != want {
+		t.Errorf("got name %q, want %q", got, want)
+	} else if got, want := req.Header.Get("X-Power"), "9000"; got != want {
+		t.Errorf("got X-Power %q, want %q", got, want)
+	}
+
+	fh := req.MultipartForm.File["avatar"]
+	if len(fh) != 1 {
+		t.Fatalf("got %d avatar files, want 1", len(fh))
+	} else if got, want := fh[0].Filename, "goku.png"; got != want {
+		t.Errorf("got filename %q, want %q", got, want)
+	} else if got, want := fh[0].Header.Get("Content-Type"), "image/png"; got != want {
+		t.Errorf("got file Content-Type %q, want %q", got, want)
+	}
+
+	if err = tr.Next(&tgt); err != nil {
+		t.Fatal(err)
+	} else if got, want := tgt.Header["content-type"], []string{"multipart/form-data; boundary=vegeta"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got Content-Type %q, want %q", got, want)
+	} else if !bytes.HasPrefix(tgt.Body, []byte("--vegeta\r\n")) {
+		t.Errorf("got body %q without the given boundary", tgt.Body)
+	}
+
+	for _, want := range []string{
+		"bad form: unsupported Content-Type: application/json",
+		"bad body: form fields can't be followed by a body file",
+	} {
+		if err = tr.Next(&tgt); err == nil || err.Error() != want {
+			t.Errorf("got error %v, want %q", err, want)
+		}
+	}
+}
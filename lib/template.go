@@ -0,0 +1,319 @@
+package vegeta
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// A tmpl is a compiled string with {{name}} placeholders. Placeholders may
+// carry space separated arguments, e.g. {{name arg1 arg2}}, which are
+// passed verbatim to the lookup function on rendering.
+type tmpl []tmplNode
+
+// A tmplNode is either a literal piece of text or a placeholder.
+type tmplNode struct {
+	text string
+	name string
+	args []string
+}
+
+// parseTmpl compiles the given string into a tmpl.
+func parseTmpl(s string) (tmpl, error) {
+	var t tmpl
+	for len(s) > 0 {
+		i := strings.Index(s, "{{")
+		if i == -1 {
+			t = append(t, tmplNode{text: s})
+			break
+		}
+
+		if i > 0 {
+			t = append(t, tmplNode{text: s[:i]})
+		}
+
+		s = s[i+2:]
+		j := strings.Index(s, "}}")
+		if j == -1 {
+			return nil, fmt.Errorf("template: unclosed placeholder in %q", s)
+		}
+
+		fields := strings.Fields(s[:j])
+		if len(fields) == 0 {
+			return nil, fmt.Errorf("template: empty placeholder")
+		}
+
+		t = append(t, tmplNode{name: fields[0], args: fields[1:]})
+		s = s[j+2:]
+	}
+	return t, nil
+}
+
+// render renders the tmpl by replacing each placeholder with the value
+// returned by the given lookup function.
+func (t tmpl) render(lookup func(name string, args []string) (string, error)) (string, error) {
+	if len(t) == 1 && t[0].name == "" {
+		return t[0].text, nil
+	}
+
+	var b strings.Builder
+	for _, n := range t {
+		if n.name == "" {
+			b.WriteString(n.text)
+			continue
+		}
+
+		v, err := lookup(n.name, n.args)
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(v)
+	}
+	return b.String(), nil
+}
+
+// generators implements the built-in template functions which are
+// resolved when a placeholder doesn't name a variable:
+//
+//    {{uuid}}            a random (version 4) UUID
+//    {{randInt min max}} a random integer in the closed interval [min, max]
+//    {{seq}}             a sequence number, incremented on every use
+//    {{timestamp unit}}  the current time in s (default), ms, ns or rfc3339
+type generators struct {
+	seq uint64
+}
+
+// lookup returns a lookup function which resolves placeholders from the
+// given variables, in order, and falls back to the built-in generators.
+func (g *generators) lookup(vars ...map[string]string) func(string, []string) (string, error) {
+	return func(name string, args []string) (string, error) {
+		for _, vs := range vars {
+			if v, ok := vs[name]; ok {
+				return v, nil
+			}
+		}
+		return g.generate(name, args)
+	}
+}
+
+func (g *generators) generate(name string, args []string) (string, error) {
+	switch name {
+	case "uuid":
+		var u [16]byte
+		if _, err := rand.Read(u[:]); err != nil {
+			return "", err
+		}
+		u[6] = (u[6] & 0x0f) | 0x40 // Version 4
+		u[8] = (u[8] & 0x3f) | 0x80 // Variant RFC 4122
+		return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:]), nil
+	case "randInt":
+		if len(args) != 2 {
+			return "", fmt.Errorf("template: randInt requires min and max arguments")
+		}
+		min, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("template: bad randInt min: %s", err)
+		}
+		max, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return "", fmt.Errorf("template: bad randInt max: %s", err)
+		}
+		if max < min {
+			return "", fmt.Errorf("template: randInt max %d < min %d", max, min)
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(0).SetUint64(uint64(max-min)+1))
+		if err != nil {
+			return "", err
+		}
+		return strconv.FormatInt(min+n.Int64(), 10), nil
+	case "seq":
+		return strconv.FormatUint(atomic.AddUint64(&g.seq, 1)-1, 10), nil
+	case "timestamp":
+		now := time.Now()
+		unit := "s"
+		if len(args) > 0 {
+			unit = args[0]
+		}
+		switch unit {
+		case "s":
+			return strconv.FormatInt(now.Unix(), 10), nil
+		case "ms":
+			return strconv.FormatInt(now.UnixNano()/int64(time.Millisecond), 10), nil
+		case "ns":
+			return strconv.FormatInt(now.UnixNano(), 10), nil
+		case "rfc3339":
+			return now.Format(time.RFC3339Nano), nil
+		default:
+			return "", fmt.Errorf("template: bad timestamp unit %q", unit)
+		}
+	default:
+		return "", fmt.Errorf("template: undefined variable %q", name)
+	}
+}
+
+// Templating configures the rendering of {{name}} placeholders in the URL,
+// header values and body of Targets. Placeholders are resolved, in order,
+// from captured variables, the current Feeder row, Vars and finally the
+// built-in generators: {{uuid}}, {{randInt min max}}, {{seq}} and
+// {{timestamp [s|ms|ns|rfc3339]}}.
+type Templating struct {
+	// Vars holds the variables of every worker.
+	Vars map[string]string
+	// Feeder provides a new row of variables for every Target, if set.
+	Feeder *Feeder
+	// Captures maps variable names to extraction expressions, which are
+	// evaluated against the body of every successful response and stored
+	// as variables of the worker that sent the request. The supported
+	// expressions are "jsonpath:<path>" and "regex:<pattern>".
+	Captures map[string]string
+}
+
+type templateTargeter struct {
+	tr       TargeterProvider
+	vars     map[string]string
+	feeder   *Feeder
+	captures map[string]extractor
+	gen      generators
+	tmplmu   sync.RWMutex
+	tmpls    map[string]tmpl // parsed templates by their source
+}
+
+// maxTmpls bounds the number of parsed templates a templateTargeter caches,
+// for Targets read from unbounded sources which are rarely hit twice.
+const maxTmpls = 1 << 14
+
+// NewTemplateTargeter returns a TargeterProvider whose Targeters render the
+// {{name}} placeholders of every Target read from the given TargeterProvider,
+// as configured by the given Templating. Each Targeter holds its own set of
+// variables, so values captured from responses are local to a worker.
+func NewTemplateTargeter(tr TargeterProvider, t Templating) (TargeterProvider, error) {
+	tt := &templateTargeter{
+		tr:       tr,
+		vars:     t.Vars,
+		feeder:   t.Feeder,
+		captures: make(map[string]extractor, len(t.Captures)),
+		tmpls:    map[string]tmpl{},
+	}
+
+	for name, expr := range t.Captures {
+		ex, err := parseExtractor(expr)
+		if err != nil {
+			return nil, fmt.Errorf("bad capture %s: %s", name, err)
+		}
+		tt.captures[name] = ex
+	}
+
+	return tt, nil
+}
+
+// NewTargeter returns a new Targeter with its own set of captured variables.
+func (t *templateTargeter) NewTargeter() Targeter {
+	return &templateWorker{templateTargeter: t, tr: t.tr.NewTargeter(), captured: map[string]string{}}
+}
+
+// render renders the placeholders of s, if there are any. Templates are
+// parsed once and cached, since the same Targets are usually hit over and
+// over.
+func (t *templateTargeter) render(s string, lookup func(string, []string) (string, error)) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+
+	t.tmplmu.RLock()
+	tm, ok := t.tmpls[s]
+	t.tmplmu.RUnlock()
+
+	if !ok {
+		var err error
+		if tm, err = parseTmpl(s); err != nil {
+			return "", err
+		}
+
+		t.tmplmu.Lock()
+		if len(t.tmpls) < maxTmpls {
+			t.tmpls[s] = tm
+		}
+		t.tmplmu.Unlock()
+	}
+
+	return tm.render(lookup)
+}
+
+// templateWorker renders Targets on behalf of a single worker.
+type templateWorker struct {
+	*templateTargeter
+	tr       Targeter
+	mu       sync.Mutex
+	captured map[string]string
+}
+
+func (w *templateWorker) Next(tgt *Target) (err error) {
+	if err = w.tr.Next(tgt); err != nil {
+		return err
+	}
+
+	var row map[string]string
+	if w.feeder != nil {
+		row = w.feeder.Next()
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	lookup := w.gen.lookup(w.captured, row, w.vars)
+	if tgt.URL, err = w.render(tgt.URL, lookup); err != nil {
+		return err
+	}
+
+	if bytes.Contains(tgt.Body, []byte("{{")) {
+		body, err := w.render(string(tgt.Body), lookup)
+		if err != nil {
+			return err
+		}
+		tgt.Body = []byte(body)
+	}
+
+	// Targets may share their Header with other Targets,
+	// so we render into a copy.
+	hdr := make(http.Header, len(tgt.Header))
+	for k, vs := range tgt.Header {
+		hdr[k] = make([]string, len(vs))
+		for i, v := range vs {
+			if hdr[k][i], err = w.render(v, lookup); err != nil {
+				return err
+			}
+		}
+	}
+	tgt.Header = hdr
+
+	return nil
+}
+
+func (w *templateWorker) Result(body []byte, code uint16, err error) {
+	w.Feedback(&Feedback{Body: body, Code: code, Err: err})
+}
+
+// Feedback forwards the given Feedback to the wrapped Targeter and captures
+// variables out of successful responses.
+func (w *templateWorker) Feedback(fb *Feedback) {
+	feedback(w.tr, fb)
+	if fb.Err != nil || fb.Code < 200 || fb.Code >= 400 {
+		return
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	for name, ex := range w.captures {
+		if v, err := ex.extract(fb.Body); err == nil {
+			w.captured[name] = v
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package vegeta
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTmpl(t *testing.T) {
+	t.Parallel()
+
+	vars := map[string]string{"a": "goku", "b": "vegeta"}
+	lookup := (&generators{}).lookup(vars)
+	for _, tc := range []struct {
+		in  string
+		out string
+		err error
+	}{
+		{in: "", out: ""},
+		{in: "no placeholders", out: "no placeholders"},
+		{in: "{{a}}", out: "goku"},
+		{in: "{{ a }} vs {{b}}!", out: "goku vs vegeta!"},
+		{in: "{{seq}}-{{seq}}", out: "0-1"},
+		{in: "{{randInt 3 3}}", out: "3"},
+		{in: "{{c}}", err: fmt.Errorf(`template: undefined variable "c"`)},
+		{in: "{{}}", err: fmt.Errorf("template: empty placeholder")},
+		{in: "{{a", err: fmt.Errorf(`template: unclosed placeholder in "a"`)},
+		{in: "{{randInt 1}}", err: fmt.Errorf("template: randInt requires min and max arguments")},
+		{in: "{{randInt 2 1}}", err: fmt.Errorf("template: randInt max 1 < min 2")},
+		{in: "{{timestamp h}}", err: fmt.Errorf(`template: bad timestamp unit "h"`)},
+	} {
+		tm, err := parseTmpl(tc.in)
+		var out string
+		if err == nil {
+			out, err = tm.render(lookup)
+		}
+
+		if got, want := fmt.Sprint(err), fmt.Sprint(tc.err); got != want {
+			t.Errorf("%q: got error %q, want %q", tc.in, got, want)
+		} else if got, want := out, tc.out; got != want {
+			t.Errorf("%q: got %q, want %q", tc.in, got, want)
+		}
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	t.Parallel()
+
+	var g generators
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if id, err := g.generate("uuid", nil); err != nil || !uuid.MatchString(id) {
+		t.Errorf("got bad uuid %q (%v)", id, err)
+	}
+
+	for i := 0; i < 100; i++ {
+		s, err := g.generate("randInt", []string{"-5", "5"})
+		if n, _ := strconv.Atoi(s); err != nil || n < -5 || n > 5 {
+			t.Fatalf("got randInt %q (%v) out of range", s, err)
+		}
+	}
+
+	before := time.Now().Unix()
+	s, err := g.generate("timestamp", nil)
+	if ts, _ := strconv.ParseInt(s, 10, 64); err != nil || ts < before || ts > time.Now().Unix() {
+		t.Errorf("got bad timestamp %q (%v)", s, err)
+	}
+
+	if s, err := g.generate("timestamp", []string{"rfc3339"}); err != nil {
+		t.Error(err)
+	} else if _, err = time.Parse(time.RFC3339Nano, s); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTemplateTargeter(t *testing.T) {
+	t.Parallel()
+
+	hdr := http.Header{"Authorization": []string{"Bearer {{token}}"}}
+	tr, err := NewTemplateTargeter(
+		NewStaticTargeter(Target{
+			Method: "POST",
+			URL:    "http://goku/{{user}}?v={{version}}",
+			Body:   []byte(`{"power": {{power}}}`),
+			Header: hdr,
+		}),
+		Templating{
+			Vars:     map[string]string{"version": "1", "token": "none"},
+			Feeder:   NewFeeder(map[string]string{"user": "goku", "power": "9000"}, map[string]string{"user": "gohan", "power": "9001", "version": "2"}),
+			Captures: map[string]string{"token": "jsonpath:$.token"},
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	workers := []Targeter{tr.NewTargeter(), tr.NewTargeter()}
+	for i, want := range []Target{
+		{URL: "http://goku/goku?v=1", Body: []byte(`{"power": 9000}`), Header: http.Header{"Authorization": []string{"Bearer none"}}},
+		{URL: "http://goku/gohan?v=2", Body: []byte(`{"power": 9001}`), Header: http.Header{"Authorization": []string{"Bearer none"}}},
+		{URL: "http://goku/goku?v=1", Body: []byte(`{"power": 9000}`), Header: http.Header{"Authorization": []string{"Bearer secret"}}},
+		{URL: "http://goku/gohan?v=2", Body: []byte(`{"power": 9001}`), Header: http.Header{"Authorization": []string{"Bearer none"}}},
+	} {
+		w := workers[i%len(workers)]
+
+		var got Target
+		if err := w.Next(&got); err != nil {
+			t.Fatal(err)
+		}
+
+		want.Method = "POST"
+		if !got.Equal(&want) {
+			t.Fatalf("#%d: got: %#v, want: %#v", i, got, want)
+		}
+
+		if i == 0 {
+			w.Result([]byte(`{"token": "secret"}`), 200, nil)
+		} else {
+			w.Result([]byte(`{"token": "stolen"}`), 500, nil)
+		}
+	}
+
+	if got, want := hdr.Get("Authorization"), "Bearer {{token}}"; got != want {
+		t.Errorf("shared header modified: got %q, want %q", got, want)
+	}
+
+	// The URL, body and header templates are parsed once.
+	if n := len(tr.(*templateTargeter).tmpls); n != 3 {
+		t.Errorf("got %d parsed templates, want 3", n)
+	}
+
+	_, err = NewTemplateTargeter(NewStaticTargeter(), Templating{Captures: map[string]string{"a": "b"}})
+	if got, want := fmt.Sprint(err), "bad capture a: bad extractor: b"; !strings.HasPrefix(got, want) {
+		t.Errorf("got error %q, want %q", got, want)
+	}
+}
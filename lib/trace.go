@@ -0,0 +1,126 @@
+package vegeta
+
+import (
+	"crypto/tls"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timings holds the durations of the phases of a hit, as traced with
+// net/http/httptrace. Phases that didn't happen, like those of establishing
+// a reused connection, have zero durations.
+type Timings struct {
+	// DNS is the duration of the DNS lookup.
+	DNS time.Duration `json:"dns"`
+	// Connect is the duration of establishing the TCP connection.
+	Connect time.Duration `json:"connect"`
+	// TLS is the duration of the TLS handshake.
+	TLS time.Duration `json:"tls"`
+	// FirstByte is the time from the start of the request to the first
+	// byte of the response.
+	FirstByte time.Duration `json:"first_byte"`
+	// Transfer is the time from the first byte of the response to the end
+	// of its body.
+	Transfer time.Duration `json:"transfer"`
+	// Reused is true if the request was sent over a reused connection.
+	Reused bool `json:"reused"`
+}
+
+// Equal returns true if the given Timings are equal to the receiver.
+func (t *Timings) Equal(other *Timings) bool {
+	if t == nil || other == nil {
+		return t == other
+	}
+	return *t == *other
+}
+
+// Trace returns a functional option which makes an Attacker trace the
+// phases of every hit and record them in the Timings of its Result.
+func Trace(trace bool) func(*Attacker) {
+	return func(a *Attacker) { a.trace = trace }
+}
+
+// tracer records the Timings of a request. Its hooks may be called
+// concurrently, e.g. when dialing several addresses of a host.
+type tracer struct {
+	mu        sync.Mutex
+	t         Timings
+	start     time.Time
+	dns       time.Time
+	connect   time.Time
+	tls       time.Time
+	firstByte time.Time
+}
+
+func newTracer() *tracer {
+	return &tracer{start: time.Now()}
+}
+
+func (tr *tracer) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			tr.mu.Lock()
+			tr.t.Reused = info.Reused
+			tr.mu.Unlock()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			tr.mu.Lock()
+			tr.dns = time.Now()
+			tr.mu.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			tr.mu.Lock()
+			tr.t.DNS = time.Since(tr.dns)
+			tr.mu.Unlock()
+		},
+		ConnectStart: func(string, string) {
+			tr.mu.Lock()
+			if tr.connect.IsZero() {
+				tr.connect = time.Now()
+			}
+			tr.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			tr.mu.Lock()
+			if err == nil && tr.t.Connect == 0 {
+				tr.t.Connect = time.Since(tr.connect)
+			}
+			tr.mu.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			tr.mu.Lock()
+			tr.tls = time.Now()
+			tr.mu.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			tr.mu.Lock()
+			tr.t.TLS = time.Since(tr.tls)
+			tr.mu.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			tr.mu.Lock()
+			tr.firstByte = time.Now()
+			tr.t.FirstByte = tr.firstByte.Sub(tr.start)
+			tr.mu.Unlock()
+		},
+	}
+}
+
+// done records the end of the response body.
+func (tr *tracer) done() {
+	tr.mu.Lock()
+	if !tr.firstByte.IsZero() {
+		tr.t.Transfer = time.Since(tr.firstByte)
+	}
+	tr.mu.Unlock()
+}
+
+// timings returns a copy of the Timings recorded so far, which hooks still
+// running late, like those of abandoned dials, can't change.
+func (tr *tracer) timings() *Timings {
+	tr.mu.Lock()
+	t := tr.t
+	tr.mu.Unlock()
+	return &t
+}
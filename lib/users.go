@@ -0,0 +1,186 @@
+package vegeta
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// A UserStage is a stage of a UserSchedule during which the number of
+// virtual users ramps linearly from that of the previous stage to Users.
+type UserStage struct {
+	// Users is the number of virtual users at the end of the stage.
+	Users uint64
+	// Duration is the duration of the ramp. Stages without one start
+	// all of their users at once.
+	Duration time.Duration
+}
+
+// A UserSchedule schedules the number of virtual users of a closed-model
+// attack over time. Attacks begin without users and keep those of the last
+// stage once the schedule ends.
+type UserSchedule []UserStage
+
+// ConstantUsers returns a UserSchedule of n virtual users from the start.
+func ConstantUsers(n uint64) UserSchedule {
+	return UserSchedule{{Users: n}}
+}
+
+// Users returns the number of virtual users scheduled at the given elapsed
+// time.
+func (s UserSchedule) Users(elapsed time.Duration) uint64 {
+	var from uint64
+	for _, st := range s {
+		if elapsed < st.Duration {
+			delta := float64(st.Users) - float64(from)
+			return uint64(float64(from) + delta*float64(elapsed)/float64(st.Duration))
+		}
+		elapsed -= st.Duration
+		from = st.Users
+	}
+	return from
+}
+
+// Duration returns the total duration of the schedule's stages.
+func (s UserSchedule) Duration() (d time.Duration) {
+	for _, st := range s {
+		d += st.Duration
+	}
+	return d
+}
+
+// ThinkTime returns how long a virtual user waits between its hits.
+type ThinkTime func() time.Duration
+
+// ConstantThinkTime returns a ThinkTime that always waits d.
+func ConstantThinkTime(d time.Duration) ThinkTime {
+	return func() time.Duration { return d }
+}
+
+// UniformThinkTime returns a ThinkTime that waits a uniformly distributed
+// duration between min and max.
+func UniformThinkTime(min, max time.Duration) ThinkTime {
+	if max <= min {
+		return ConstantThinkTime(min)
+	}
+	return func() time.Duration {
+		return min + time.Duration(rand.Int63n(int64(max-min)+1))
+	}
+}
+
+// ExponentialThinkTime returns a ThinkTime that waits exponentially
+// distributed durations with the given mean, like the time between
+// independent events such as the arrival of users.
+func ExponentialThinkTime(mean time.Duration) ThinkTime {
+	return func() time.Duration {
+		return time.Duration(rand.ExpFloat64() * float64(mean))
+	}
+}
+
+// userScheduleInterval is the interval at which the number of virtual users
+// is adjusted to their schedule.
+const userScheduleInterval = 100 * time.Millisecond
+
+// AttackUsers runs a closed-model attack in which virtual users hit the
+// Targets of their own Targeter, as returned by tr, one after the other,
+// waiting for the given think time between each response and the following
+// hit. Unlike with Attack, the request rate isn't set but follows from the
+// number of virtual users, which follows the given schedule, and the latency
+// of the responses. When the duration is zero the attack runs until Stop is
+// called. Results are sent to the returned channel as soon as they arrive
+// and will have their Attack field set to the given name.
+func (a *Attacker) AttackUsers(tr TargeterProvider, s UserSchedule, think ThinkTime, du time.Duration, name string) <-chan *Result {
+	if think == nil {
+		think = ConstantThinkTime(0)
+	}
+
+	results := make(chan *Result)
+	go func() {
+		defer close(results)
+		defer a.closeQUIC()
+		defer a.closeGRPC()
+
+		var (
+			wg    sync.WaitGroup
+			users []chan struct{} // stop channels of the running users
+		)
+
+		defer wg.Wait()
+		defer func() {
+			for _, stop := range users {
+				close(stop)
+			}
+		}()
+
+		ticker := time.NewTicker(userScheduleInterval)
+		defer ticker.Stop()
+
+		began := time.Now()
+		for {
+			elapsed := time.Since(began)
+			if du > 0 && elapsed > du {
+				return
+			}
+
+			n := s.Users(elapsed)
+			if n > a.maxWorkers {
+				n = a.maxWorkers
+			}
+
+			for uint64(len(users)) < n {
+				stop := make(chan struct{})
+				users = append(users, stop)
+				wg.Add(1)
+				go a.user(tr.NewTargeter(), think, name, stop, &wg, results)
+			}
+
+			for uint64(len(users)) > n {
+				close(users[len(users)-1])
+				users = users[:len(users)-1]
+			}
+
+			select {
+			case <-ticker.C:
+			case <-a.stopch:
+				return
+			}
+		}
+	}()
+
+	return results
+}
+
+// user hits the Targets of tr in a loop, thinking between hits, until
+// stopped.
+func (a *Attacker) user(tr Targeter, think ThinkTime, name string, stop <-chan struct{}, wg *sync.WaitGroup, results chan<- *Result) {
+	defer wg.Done()
+
+	client, conns := a.workerClient()
+	defer conns.close()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-a.stopch:
+			return
+		default:
+		}
+
+		conns.churn()
+		send(results, a.hitWith(client, tr, name))
+
+		if d := think(); d > 0 {
+			timer := time.NewTimer(d)
+			select {
+			case <-timer.C:
+			case <-stop:
+				timer.Stop()
+				return
+			case <-a.stopch:
+				timer.Stop()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package vegeta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestUserSchedule(t *testing.T) {
+	t.Parallel()
+
+	s := UserSchedule{
+		{Users: 10, Duration: 10 * time.Second},
+		{Users: 50},
+		{Users: 30, Duration: 20 * time.Second},
+	}
+
+	for _, tc := range []struct {
+		elapsed time.Duration
+		users   uint64
+	}{
+		{0, 0},
+		{5 * time.Second, 5},
+		{10 * time.Second, 50},
+		{20 * time.Second, 40},
+		{30 * time.Second, 30},
+		{time.Hour, 30},
+	} {
+		if got := s.Users(tc.elapsed); got != tc.users {
+			t.Errorf("Users(%s): got %d, want %d", tc.elapsed, got, tc.users)
+		}
+	}
+
+	if got, want := s.Duration(), 30*time.Second; got != want {
+		t.Errorf("Duration(): got %s, want %s", got, want)
+	}
+
+	if got := ConstantUsers(7).Users(0); got != 7 {
+		t.Errorf("ConstantUsers(7).Users(0): got %d, want 7", got)
+	}
+}
+
+func TestThinkTime(t *testing.T) {
+	t.Parallel()
+
+	if d := ConstantThinkTime(time.Second)(); d != time.Second {
+		t.Errorf("constant: got %s, want %s", d, time.Second)
+	}
+
+	var sum time.Duration
+	for i := 0; i < 1000; i++ {
+		if d := UniformThinkTime(time.Second, 2*time.Second)(); d < time.Second || d > 2*time.Second {
+			t.Fatalf("uniform: got %s out of [1s, 2s]", d)
+		}
+
+		d := ExponentialThinkTime(time.Second)()
+		if d < 0 {
+			t.Fatalf("exponential: got negative %s", d)
+		}
+		sum += d
+	}
+
+	if mean := sum / 1000; mean < 800*time.Millisecond || mean > 1200*time.Millisecond {
+		t.Errorf("exponential: got mean %s, want about 1s", mean)
+	}
+}
+
+func TestAttackUsers(t *testing.T) {
+	t.Parallel()
+
+	var active, max int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&active, 1)
+		defer atomic.AddInt32(&active, -1)
+		for m := atomic.LoadInt32(&max); n > m && !atomic.CompareAndSwapInt32(&max, m, n); {
+			m = atomic.LoadInt32(&max)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	atk := NewAttacker()
+	tr := NewStaticTargeter(Target{Method: "GET", URL: server.URL})
+	think := ConstantThinkTime(10 * time.Millisecond)
+
+	hits := 0
+	for r := range atk.AttackUsers(tr, ConstantUsers(3), think, 300*time.Millisecond, "") {
+		if r.Error != "" {
+			t.Fatal(r.Error)
+		}
+		hits++
+	}
+
+	// Each user hits at most once every 20ms.
+	if hits == 0 || hits > 3*(300/20+1) {
+		t.Errorf("got %d hits, want between 1 and %d", hits, 3*(300/20+1))
+	}
+
+	if got := atomic.LoadInt32(&max); got != 3 {
+		t.Errorf("got %d concurrent requests, want 3", got)
+	}
+}
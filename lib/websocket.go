@@ -0,0 +1,252 @@
+package vegeta
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// WebSocket Targets have URLs with these schemes. Their connections are
+// opened with an HTTP/1.1 upgrade request, after which the Messages of the
+// Target are sent over them.
+const (
+	wsScheme    = "ws"
+	wsTLSScheme = "wss"
+)
+
+func isWebSocket(u *url.URL) bool {
+	return u.Scheme == wsScheme || u.Scheme == wsTLSScheme
+}
+
+// WebSocketConfig sets how an Attacker holds the connections of WebSocket
+// Targets.
+type WebSocketConfig struct {
+	// Hold is how long connections are held open, during which the
+	// messages of their Target are sent over and over. Without it, each
+	// message is sent once and connections are closed right after.
+	Hold time.Duration
+	// Interval is the delay between sending a message and the next one.
+	// Without it, the next message is sent as soon as the reply to the
+	// previous one arrives.
+	Interval time.Duration
+}
+
+// WebSocket returns a functional option which sets how an Attacker holds the
+// connections of WebSocket Targets. The Result of a hit of a WebSocket Target
+// records the setup of its connection, with the time until the handshake
+// completed as latency, and is followed by a Result for the round trip of
+// each sent message until its reply, which is the next message received.
+func WebSocket(c WebSocketConfig) func(*Attacker) {
+	return func(a *Attacker) { a.ws = c }
+}
+
+// wsMaxMessage is the size of the largest message a WebSocket connection reads.
+const wsMaxMessage = 1 << 30
+
+// doWebSocket opens a connection to the WebSocket Target of the given request,
+// records its setup in res and then exchanges the Target's messages over it.
+// Clients' timeouts apply to the handshake and to each message round trip.
+func (a *Attacker) doWebSocket(client *http.Client, req *http.Request, tgt *Target, res *Result) (http.Header, error) {
+	d := websocket.Dialer{
+		NetDialContext:  a.dialer.DialContext,
+		TLSClientConfig: DefaultTLSConfig,
+		Proxy:           http.ProxyFromEnvironment,
+		Jar:             client.Jar,
+	}
+	if tr, ok := client.Transport.(*http.Transport); ok {
+		d.NetDialContext, d.TLSClientConfig, d.Proxy = tr.DialContext, tr.TLSClientConfig, tr.Proxy
+	}
+
+	// Upgrades are only negotiated over HTTP/1.1.
+	if d.TLSClientConfig != nil {
+		d.TLSClientConfig = d.TLSClientConfig.Clone()
+		d.TLSClientConfig.NextProtos = nil
+	}
+
+	// Unlike the transport, the dialer doesn't report its dials to traces.
+	dial := d.NetDialContext
+	d.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		trace := httptrace.ContextClientTrace(ctx)
+		if trace != nil && trace.ConnectStart != nil {
+			trace.ConnectStart(network, addr)
+		}
+		conn, err := dial(ctx, network, addr)
+		if trace != nil && trace.ConnectDone != nil {
+			trace.ConnectDone(network, addr, err)
+		}
+		return conn, err
+	}
+
+	ctx, timeout := req.Context(), client.Timeout
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	conn, r, err := d.DialContext(ctx, req.URL.String(), req.Header)
+	if r == nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			err = &timeoutError{"websocket: handshake", timeout}
+		}
+		return nil, err
+	}
+	defer r.Body.Close()
+
+	if a.metadata {
+		res.Proto = r.Proto
+	}
+	res.Header = a.responseHeaders(r.Header)
+	res.Code = uint16(r.StatusCode)
+
+	if r.StatusCode != http.StatusSwitchingProtocols {
+		body := io.Reader(r.Body)
+		if a.maxBody >= 0 {
+			body = io.LimitReader(r.Body, a.maxBody)
+		}
+		if res.Body, err = ioutil.ReadAll(body); err != nil {
+			return r.Header, err
+		}
+		res.BytesIn = uint64(len(res.Body))
+		res.Error = r.Status
+		return r.Header, nil
+	} else if err != nil {
+		return r.Header, err
+	}
+
+	res.Latency = time.Since(res.Timestamp)
+
+	conn.SetReadLimit(wsMaxMessage)
+	a.converse(conn, tgt, res, timeout)
+
+	return r.Header, nil
+}
+
+// converse sends the messages of tgt over the given connection, recording
+// their round trips in the message Results of res, for as long as the
+// connection is held.
+func (a *Attacker) converse(conn *websocket.Conn, tgt *Target, res *Result, timeout time.Duration) {
+	defer wsClose(conn)
+
+	var hold <-chan time.Time
+	if a.ws.Hold > 0 {
+		timer := time.NewTimer(a.ws.Hold)
+		defer timer.Stop()
+		hold = timer.C
+	}
+
+	for n := 0; len(tgt.Messages) > 0 && (hold != nil || n < len(tgt.Messages)); n++ {
+		sent := time.Now()
+		m := a.message(conn, tgt.Messages[n%len(tgt.Messages)], res, uint64(n+1), timeout)
+		res.messages = append(res.messages, m)
+
+		if m.Error != "" {
+			return // The connection is likely broken.
+		}
+
+		if hold == nil && n == len(tgt.Messages)-1 {
+			return
+		}
+
+		if wait := a.ws.Interval - time.Since(sent); wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-timer.C:
+			case <-hold:
+				timer.Stop()
+				return
+			case <-a.stopch:
+				timer.Stop()
+				return
+			}
+		}
+
+		select {
+		case <-hold:
+			return
+		case <-a.stopch:
+			return
+		default:
+		}
+	}
+
+	if hold != nil {
+		select {
+		case <-hold:
+		case <-a.stopch:
+		}
+	}
+}
+
+// message sends the given message over conn and waits for its reply,
+// returning the Result of their round trip, which is the nth one of the
+// connection whose hit has the given Result.
+func (a *Attacker) message(conn *websocket.Conn, msg string, hit *Result, n uint64, timeout time.Duration) *Result {
+	m := Result{
+		Attack:  hit.Attack,
+		Tag:     hit.Tag,
+		Method:  hit.Method,
+		URL:     hit.URL,
+		Message: n,
+	}
+
+	a.seqmu.Lock()
+	m.Timestamp = a.began.Add(time.Since(a.began))
+	m.Seq = a.seq
+	a.seq++
+	a.seqmu.Unlock()
+
+	reply, err := wsRoundTrip(conn, []byte(msg), timeout)
+	m.Latency = time.Since(m.Timestamp)
+	m.BytesOut = uint64(len(msg))
+
+	if err != nil {
+		m.Error = err.Error()
+		return &m
+	}
+
+	m.Code = http.StatusSwitchingProtocols
+	m.BytesIn = uint64(len(reply))
+	if m.Body = reply; a.maxBody >= 0 && int64(len(reply)) > a.maxBody {
+		m.Body = reply[:a.maxBody]
+	}
+
+	return &m
+}
+
+// wsRoundTrip sends the given text message over conn and returns the next
+// data message received, while pings are answered. It fails if no message
+// is received within the given timeout, if any.
+func wsRoundTrip(conn *websocket.Conn, msg []byte, timeout time.Duration) ([]byte, error) {
+	if err := conn.WriteMessage(websocket.TextMessage, msg); err != nil {
+		return nil, err
+	}
+
+	var deadline time.Time
+	if timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	if err := conn.SetReadDeadline(deadline); err != nil {
+		return nil, err
+	}
+
+	_, reply, err := conn.ReadMessage()
+	if ne, ok := err.(net.Error); ok && ne.Timeout() {
+		err = &timeoutError{"websocket: reply", timeout}
+	}
+	return reply, err
+}
+
+// wsClose closes conn after sending a normal closure message.
+func wsClose(conn *websocket.Conn) error {
+	msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+	conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+	return conn.Close()
+}
@@ -0,0 +1,229 @@
+package vegeta
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsEchoServer returns a server of WebSocket connections which echo the
+// messages received over them, unless they start with "mute".
+func wsEchoServer(tls bool) *httptest.Server {
+	h := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			typ, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			} else if strings.HasPrefix(string(msg), "mute") {
+				continue
+			} else if err = conn.WriteMessage(typ, msg); err != nil {
+				return
+			}
+		}
+	})
+
+	mux := http.NewServeMux()
+	mux.Handle("/ws", h)
+	mux.HandleFunc("/plain", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no websockets here", http.StatusNotFound)
+	})
+
+	if tls {
+		return httptest.NewTLSServer(mux)
+	}
+	return httptest.NewServer(mux)
+}
+
+func TestWebSocket(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		scheme string
+		tls    bool
+	}{
+		{"ws", false},
+		{"wss", true},
+	} {
+		tc := tc
+		t.Run(tc.scheme, func(t *testing.T) {
+			t.Parallel()
+
+			server := wsEchoServer(tc.tls)
+			defer server.Close()
+
+			url := "ws" + strings.TrimPrefix(server.URL, "http")
+			if !strings.HasPrefix(url, tc.scheme+":") {
+				t.Fatalf("got url %s, want scheme %s", url, tc.scheme)
+			}
+			tgt := Target{Method: "GET", URL: url + "/ws", Messages: []string{"hello", strings.Repeat("x", 70000)}}
+
+			res := NewAttacker().hit(NewStaticTargeter(tgt).NewTargeter(), "")
+			if res.Code != http.StatusSwitchingProtocols || res.Error != "" {
+				t.Fatalf("got code %d and error %q, want a WebSocket connection", res.Code, res.Error)
+			} else if res.Latency <= 0 {
+				t.Errorf("got latency %s, want the connection setup time", res.Latency)
+			} else if got, want := len(res.messages), len(tgt.Messages); got != want {
+				t.Fatalf("got %d message results, want %d", got, want)
+			}
+
+			for i, m := range res.messages {
+				if m.Error != "" || m.Code != http.StatusSwitchingProtocols {
+					t.Errorf("message %d: got code %d and error %q", i+1, m.Code, m.Error)
+				} else if string(m.Body) != tgt.Messages[i] || m.BytesIn != m.BytesOut {
+					t.Errorf("message %d: got reply of %d bytes, want echo of %d bytes", i+1, m.BytesIn, m.BytesOut)
+				} else if m.Message != uint64(i+1) || m.Seq <= res.Seq {
+					t.Errorf("message %d: got number %d and seq %d", i+1, m.Message, m.Seq)
+				}
+			}
+
+			tgt.URL = url + "/plain"
+			res = NewAttacker().hit(NewStaticTargeter(tgt).NewTargeter(), "")
+			if res.Code != http.StatusNotFound || res.Error != "404 Not Found" || len(res.messages) != 0 {
+				t.Errorf("got code %d, error %q and %d messages, want a failed handshake", res.Code, res.Error, len(res.messages))
+			}
+		})
+	}
+}
+
+func TestWebSocketHold(t *testing.T) {
+	t.Parallel()
+
+	server := wsEchoServer(false)
+	defer server.Close()
+
+	tgt := Target{Method: "GET", URL: "ws" + strings.TrimPrefix(server.URL, "http") + "/ws", Messages: []string{"a", "b"}}
+	atk := NewAttacker(WebSocket(WebSocketConfig{Hold: 200 * time.Millisecond, Interval: 40 * time.Millisecond}))
+
+	began := time.Now()
+	res := atk.hit(NewStaticTargeter(tgt).NewTargeter(), "")
+	if held := time.Since(began); held < 200*time.Millisecond {
+		t.Errorf("got connection held for %s, want at least 200ms", held)
+	}
+
+	if n := len(res.messages); n < 3 || n > 6 {
+		t.Fatalf("got %d messages, want about one every 40ms", n)
+	}
+
+	for i, m := range res.messages {
+		if want := tgt.Messages[i%2]; string(m.Body) != want {
+			t.Errorf("message %d: got %q, want %q", i+1, m.Body, want)
+		}
+	}
+
+	// Connections without messages are held too.
+	tgt.Messages = nil
+	began = time.Now()
+	if res = atk.hit(NewStaticTargeter(tgt).NewTargeter(), ""); res.Error != "" || len(res.messages) != 0 {
+		t.Errorf("got error %q and %d messages", res.Error, len(res.messages))
+	} else if held := time.Since(began); held < 200*time.Millisecond {
+		t.Errorf("got connection held for %s, want at least 200ms", held)
+	}
+}
+
+func TestWebSocketReplyTimeout(t *testing.T) {
+	t.Parallel()
+
+	server := wsEchoServer(false)
+	defer server.Close()
+
+	tgt := Target{Method: "GET", URL: "ws" + strings.TrimPrefix(server.URL, "http") + "/ws", Messages: []string{"mute", "hello"}}
+	res := NewAttacker(Timeout(100*time.Millisecond)).hit(NewStaticTargeter(tgt).NewTargeter(), "")
+
+	if res.Error != "" {
+		t.Fatalf("got error %q, want connection to succeed", res.Error)
+	} else if len(res.messages) != 1 {
+		t.Fatalf("got %d messages, want the exchange to stop after the timeout", len(res.messages))
+	}
+
+	m := res.messages[0]
+	if m.Error != "websocket: reply timed out after 100ms" || m.Code != 0 {
+		t.Errorf("got code %d and error %q, want a timeout", m.Code, m.Error)
+	} else if kind := classify(&timeoutError{"websocket: reply", time.Second}); kind != TimeoutError {
+		t.Errorf("got error kind %v, want %v", kind, TimeoutError)
+	}
+}
+
+func TestWebSocketAttack(t *testing.T) {
+	t.Parallel()
+
+	server := wsEchoServer(false)
+	defer server.Close()
+
+	tgt := Target{Method: "GET", URL: "ws" + strings.TrimPrefix(server.URL, "http") + "/ws", Messages: []string{"a", "b"}}
+	atk := NewAttacker()
+
+	var (
+		conns, messages int
+		m               Metrics
+	)
+	for res := range atk.Attack(NewStaticTargeter(tgt), Rate{Freq: 50, Per: time.Second}, 100*time.Millisecond, "") {
+		m.Add(res)
+		if res.Error != "" {
+			t.Fatal(res.Error)
+		} else if res.Message == 0 {
+			conns++
+		} else {
+			messages++
+		}
+	}
+
+	if conns == 0 || messages != 2*conns {
+		t.Errorf("got %d connections and %d messages, want two messages per connection", conns, messages)
+	}
+
+	// The 101s of connections and messages are successful.
+	if m.Close(); m.Success != 1 {
+		t.Errorf("got success %f, want WebSocket results to be successful", m.Success)
+	}
+}
+
+func TestWebSocketPingAndClose(t *testing.T) {
+	t.Parallel()
+
+	var pongs int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := (&websocket.Upgrader{}).Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		conn.SetPongHandler(func(string) error {
+			atomic.AddInt32(&pongs, 1)
+			return nil
+		})
+
+		// A ping before the reply to the first message, and a close
+		// message instead of the reply to the second.
+		conn.ReadMessage()
+		conn.WriteControl(websocket.PingMessage, []byte("ping"), time.Now().Add(time.Second))
+		conn.WriteMessage(websocket.TextMessage, []byte("hey"))
+		conn.ReadMessage()
+		conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""))
+	}))
+	defer server.Close()
+
+	tgt := Target{Method: "GET", URL: "ws" + strings.TrimPrefix(server.URL, "http"), Messages: []string{"hi", "again"}}
+	res := NewAttacker().hit(NewStaticTargeter(tgt).NewTargeter(), "")
+
+	if res.Error != "" || len(res.messages) != 2 {
+		t.Fatalf("got error %q and %d messages, want two", res.Error, len(res.messages))
+	} else if m := res.messages[0]; m.Error != "" || string(m.Body) != "hey" {
+		t.Errorf("got reply %q and error %q, want hey", m.Body, m.Error)
+	} else if m = res.messages[1]; !strings.Contains(m.Error, "1001") {
+		t.Errorf("got error %q, want closed connection", m.Error)
+	} else if atomic.LoadInt32(&pongs) != 1 {
+		t.Errorf("got %d pongs, want 1", pongs)
+	}
+}
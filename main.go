@@ -13,11 +13,12 @@ import (
 
 func main() {
 	commands := map[string]command{
-		"attack": attackCmd(),
-		"report": reportCmd(),
-		"plot":   plotCmd(),
-		"encode": encodeCmd(),
-		"dump":   dumpCmd(),
+		"attack":  attackCmd(),
+		"report":  reportCmd(),
+		"plot":    plotCmd(),
+		"encode":  encodeCmd(),
+		"dump":    dumpCmd(),
+		"targets": targetsCmd(),
 	}
 
 	fs := flag.NewFlagSet("vegeta", flag.ExitOnError)
@@ -38,7 +39,7 @@ func main() {
 		sort.Strings(names)
 		for _, name := range names {
 			if cmd := commands[name]; cmd.fs != nil {
-				fmt.Fprintf(fs.Output(),"\n%s command:\n", name)
+				fmt.Fprintf(fs.Output(), "\n%s command:\n", name)
 				cmd.fs.SetOutput(fs.Output())
 				cmd.fs.PrintDefaults()
 			}
@@ -30,6 +30,18 @@ Options:
 
   --output  Output file [default: stdout]
 
+  --corrected  Use latencies corrected for coordinated omission, measured
+               from the times at which requests were intended to be sent,
+               in hdrplot reports. Text and JSON reports include both.
+
+  --by      Group text and JSON reports by url, method, host, tag, attack
+            or code (comma separated list).
+
+  --url-pattern  Pattern of URL path segments replaced by its name when
+                 grouping by url, as name=regexp (e.g. :slug=[a-z-]+).
+                 Repeatable. Numeric ids, UUIDs and hexadecimal hashes are
+                 always replaced by :id.
+
 Examples:
   echo "GET http://:80" | vegeta attack -rate=10/s > results.gob
   echo "GET http://:80" | vegeta attack -rate=100/s | vegeta encode > results.json
@@ -42,6 +54,10 @@ func reportCmd() command {
 	every := fs.Duration("every", 0, "Report interval")
 	output := fs.String("output", "stdout", "Output file")
 	buckets := fs.String("buckets", "", "Histogram buckets, e.g.: \"[0,1ms,10ms]\"")
+	corrected := fs.Bool("corrected", false, "Use latencies corrected for coordinated omission in hdrplot reports")
+	by := fs.String("by", "", "Group text and JSON reports by [url, method, host, tag, attack, code] (comma separated list)")
+	var urlPatterns listFlag
+	fs.Var(&urlPatterns, "url-pattern", "URL path segment pattern as name=regexp, replacing matching segments when grouping by url")
 
 	fs.Usage = func() {
 		fmt.Fprintln(os.Stderr, reportUsage)
@@ -53,15 +69,23 @@ func reportCmd() command {
 		if len(files) == 0 {
 			files = append(files, "stdin")
 		}
-		return report(files, *typ, *output, *every, *buckets)
+		return report(files, *typ, *output, *every, *buckets, *corrected, *by, urlPatterns)
 	}}
 }
 
-func report(files []string, typ, output string, every time.Duration, bucketsStr string) error {
+func report(files []string, typ, output string, every time.Duration, bucketsStr string, corrected bool, by string, urlPatterns []string) error {
 	if len(typ) < 4 {
 		return fmt.Errorf("invalid report type: %s", typ)
 	}
 
+	var grouped *vegeta.GroupedMetrics
+	if by != "" {
+		var err error
+		if grouped, err = groupedMetrics(typ, by, bucketsStr, urlPatterns); err != nil {
+			return err
+		}
+	}
+
 	dec, mc, err := decoder(files)
 	defer mc.Close()
 	if err != nil {
@@ -83,9 +107,17 @@ func report(files []string, typ, output string, every time.Duration, bucketsStr
 	case "plot":
 		return fmt.Errorf("The plot reporter has been deprecated and succeeded by the vegeta plot command")
 	case "text":
+		if grouped != nil {
+			rep, report = vegeta.NewGroupedTextReporter(grouped), grouped
+			break
+		}
 		var m vegeta.Metrics
 		rep, report = vegeta.NewTextReporter(&m), &m
 	case "json":
+		if grouped != nil {
+			rep, report = vegeta.NewGroupedJSONReporter(grouped), grouped
+			break
+		}
 		var m vegeta.Metrics
 		if bucketsStr != "" {
 			m.Histogram = &vegeta.Histogram{}
@@ -96,7 +128,9 @@ func report(files []string, typ, output string, every time.Duration, bucketsStr
 		rep, report = vegeta.NewJSONReporter(&m), &m
 	case "hdrplot":
 		var m vegeta.Metrics
-		rep, report = vegeta.NewHDRHistogramPlotReporter(&m), &m
+		if rep, report = vegeta.NewHDRHistogramPlotReporter(&m), &m; corrected {
+			rep = vegeta.NewCorrectedHDRHistogramPlotReporter(&m)
+		}
 	default:
 		switch {
 		case strings.HasPrefix(typ, "hist"):
@@ -154,6 +188,38 @@ decode:
 	return writeReport(rep, rc, out)
 }
 
+// groupedMetrics returns the GroupedMetrics of a report grouped by the given
+// comma separated list of groupings.
+func groupedMetrics(typ, by, bucketsStr string, urlPatterns []string) (*vegeta.GroupedMetrics, error) {
+	if typ != "text" && typ != "json" {
+		return nil, fmt.Errorf("-by isn't supported by %s reports", typ)
+	}
+
+	patterns := make([]vegeta.URLPattern, 0, len(urlPatterns)+len(vegeta.DefaultURLPatterns))
+	for _, s := range urlPatterns {
+		p, err := vegeta.ParseURLPattern(s)
+		if err != nil {
+			return nil, err
+		}
+		patterns = append(patterns, p)
+	}
+	patterns = append(patterns, vegeta.DefaultURLPatterns...)
+
+	groupBy, err := vegeta.ParseGroupBy(by, patterns)
+	if err != nil {
+		return nil, err
+	}
+
+	var buckets vegeta.Buckets
+	if bucketsStr != "" && typ == "json" {
+		if err := buckets.UnmarshalText([]byte(bucketsStr)); err != nil {
+			return nil, err
+		}
+	}
+
+	return vegeta.NewGroupedMetrics(groupBy, buckets), nil
+}
+
 func writeReport(r vegeta.Reporter, rc vegeta.Closer, out io.Writer) error {
 	if rc != nil {
 		rc.Close()
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	vegeta "github.com/ernestrc/vegeta/lib"
+)
+
+const targetsUsage = `Usage: vegeta targets [options] [<file>...]
+
+Converts vegeta attack targets from one format to another, or generates
+them from the operations of an OpenAPI 3 or Swagger 2 JSON document.
+
+Arguments:
+  <file>  A file with targets in the format given by --format [default: stdin]
+
+Options:
+  --format    Input targets format (http | json | curl | har | openapi)
+              [default: http]
+  --to        Output targets format (json | curl) [default: json]
+  --output    Output file [default: stdout]
+  --base-url  Base URL the server URL of openapi documents is resolved
+              against, keeping its path [default: first server]
+  --tags      Tags of the openapi operations to include (comma separated list)
+  --paths     Path prefixes of the openapi operations to include
+              (comma separated list)
+
+Examples:
+  vegeta targets -format=curl -to=json commands.sh > targets.json
+  vegeta targets -format=har -to=curl session.har
+  vegeta targets -format=openapi -base-url=http://staging:8080 -tags=pets openapi.json | vegeta attack -format=json
+`
+
+// openAPIFormat is the identifier of OpenAPI documents as an input format.
+const openAPIFormat = "openapi"
+
+func targetsCmd() command {
+	fs := flag.NewFlagSet("vegeta targets", flag.ExitOnError)
+	format := fs.String("format", vegeta.HTTPTargetFormat, "Input targets format [http, json, curl, har, openapi]")
+	to := fs.String("to", vegeta.JSONTargetFormat, "Output targets format [json, curl]")
+	output := fs.String("output", "stdout", "Output file")
+
+	var opts vegeta.OpenAPIOptions
+	fs.StringVar(&opts.BaseURL, "base-url", "", "Base URL of the openapi operations [default: first server]")
+	fs.Var((*csl)(&opts.Tags), "tags", "Tags of the openapi operations to include (comma separated list)")
+	fs.Var((*csl)(&opts.PathPrefixes), "paths", "Path prefixes of the openapi operations to include (comma separated list)")
+
+	fs.Usage = func() {
+		fmt.Fprint(os.Stderr, targetsUsage)
+	}
+
+	return command{fs, func(args []string) error {
+		fs.Parse(args)
+		files := fs.Args()
+		if len(files) == 0 {
+			files = append(files, "stdin")
+		}
+		return targets(files, *format, *to, *output, opts)
+	}}
+}
+
+func targets(files []string, format, to, output string, opts vegeta.OpenAPIOptions) error {
+	out, err := file(output, true)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	var enc vegeta.TargetEncoder
+	switch to {
+	case vegeta.JSONTargetFormat:
+		enc = vegeta.NewJSONTargetEncoder(out)
+	case vegeta.CurlTargetFormat:
+		enc = vegeta.NewCurlTargetEncoder(out)
+	default:
+		return fmt.Errorf("targets: unknown output format %q", to)
+	}
+
+	for _, name := range files {
+		if err = convertTargets(name, format, enc, opts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func convertTargets(name, format string, enc vegeta.TargetEncoder, opts vegeta.OpenAPIOptions) error {
+	f, err := file(name, false)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var tr vegeta.TargeterProvider
+	switch format {
+	case vegeta.HTTPTargetFormat:
+		tr = vegeta.NewHTTPTargeter(f, nil, nil)
+	case vegeta.JSONTargetFormat:
+		tr = vegeta.NewJSONTargeter(f, nil, nil)
+	case vegeta.CurlTargetFormat:
+		tr = vegeta.NewCurlTargeter(f, nil, nil)
+	case vegeta.HARTargetFormat:
+		if tr, err = vegeta.NewHARTargeter(f, nil, nil, vegeta.HAROptions{}); err != nil {
+			return err
+		}
+	case openAPIFormat:
+		tgts, err := vegeta.ReadOpenAPITargets(f, opts)
+		if err != nil {
+			return err
+		}
+		tr = vegeta.NewSequenceTargeter(tgts...)
+	default:
+		return fmt.Errorf("targets: unknown input format %q", format)
+	}
+
+	for t := tr.NewTargeter(); ; {
+		var tgt vegeta.Target
+		if err = t.Next(&tgt); err == vegeta.ErrNoTargets {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("targets: %s: %s", name, err)
+		} else if err = enc.Encode(&tgt); err != nil {
+			return err
+		}
+	}
+}